@@ -1,8 +1,12 @@
 package examples
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint CBMDeclaration cd1a27a57167dbd8
 
 //+++ General machine definition +++
 
@@ -10,47 +14,76 @@ import "fmt"
 type CBMState int
 
 const (
-	_          CBMState = iota
-	Closed              // Closed state
-	Exit                // Exit state
-	HalfOpened          // HalfOpened state
-	Opened              // Opened state
+	_ CBMState = iota
+	// Opened breaker rejects calls until cool down period is over.
+	Opened
+	// HalfOpened breaker tries protected function once to decide whether it recovered.
+	HalfOpened
+	// Closed breaker passes calls to protected function and counts its errors.
+	Closed
+	// Exit breaker is broken by panic of protected function.
+	Exit
 )
 
 var _CBMStateMap = map[CBMState]string{
+	Opened:     "Opened",
+	HalfOpened: "HalfOpened",
 	Closed:     "Closed",
 	Exit:       "Exit",
-	HalfOpened: "HalfOpened",
-	Opened:     "Opened",
 }
 
 var _CBMParsingStateMap = map[string]CBMState{
+	"Opened":     Opened,
+	"HalfOpened": HalfOpened,
 	"Closed":     Closed,
 	"Exit":       Exit,
-	"HalfOpened": HalfOpened,
-	"Opened":     Opened,
 }
 
 func (s CBMState) String() string {
 	return _CBMStateMap[s]
 }
 
+var _CBMLabelAttribute = map[CBMState]string{
+	Opened:     "Open",
+	HalfOpened: "Half open",
+	Closed:     "Closed",
+	Exit:       "Broken",
+}
+
+// Label returns label attribute of the state or zero value if state has no such attribute
+func (s CBMState) Label() string {
+	return _CBMLabelAttribute[s]
+}
+
+var _CBMRetryableAttribute = map[CBMState]bool{
+	Opened: true,
+}
+
+// Retryable returns retryable attribute of the state or zero value if state has no such attribute
+func (s CBMState) Retryable() bool {
+	return _CBMRetryableAttribute[s]
+}
+
 // CBMBehaviour definition
 type CBMBehaviour interface {
-	CBMClosedState
-
-	CBMHalfOpenedState
 	CBMOpenedState
+	CBMOpenedListener
+	CBMHalfOpenedState
+	CBMClosedState
+	CBMClosedGuards
+	CBMClosedEntry
 }
 
 // CBM machine type
 type CBM struct {
-	state CBMState
+	state           CBMState
+	clock           CBMClock
+	openedEnteredAt time.Time
 }
 
-// NewCBM creates machine with specified initial state
-func NewCBM(state CBMState) *CBM {
-	return &CBM{state: state}
+// NewCBM creates machine in its initial state Closed
+func NewCBM() *CBM {
+	return &CBM{state: Closed}
 }
 
 // NewCBMFromString can be used to deserialize  machine state
@@ -67,146 +100,372 @@ func (m *CBM) Current() CBMState {
 	return m.state
 }
 
-// Operate executes behaviour for the current state CBM
-func (m *CBM) Operate(operator CBMBehaviour) {
+// CBMClock provides current time for timed transitions of CBM
+type CBMClock interface {
+	Now() time.Time
+}
+
+// WithClock replaces clock used by timed transitions of CBM, so tests can control time.
+// Timers of the current states restart from the next Operate call.
+func (m *CBM) WithClock(clock CBMClock) *CBM {
+	m.clock = clock
+	m.openedEnteredAt = time.Time{}
+	return m
+}
+
+// now returns current time of CBM clock
+func (m *CBM) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
+
+var _CBMAutoStates = map[CBMState]bool{
+	HalfOpened: true,
+}
+
+// CBMGuardError reports transition of CBM refused by guard
+type CBMGuardError struct {
+	State CBMState
+	Event string
+	Guard string
+}
+
+func (e *CBMGuardError) Error() string {
+	return fmt.Sprintf("CBM transition %s from %s refused by guard %s", e.Event, e.State, e.Guard)
+}
+
+// Operate executes behaviour for the current state CBM.
+// If guard refuses transition, machine remains in current state and *CBMGuardError is returned.
+// On transition exit action of the current state is executed first,
+// then state is changed and entry action of the destination state is executed.
+// After transition to automatic state its behaviour is executed immediately, until stable state is reached.
+func (m *CBM) Operate(operator CBMBehaviour) error {
+	for {
+		previous := m.state
+		if err := m.operateCurrent(operator); err != nil {
+			return err
+		}
+		if m.state == previous || !_CBMAutoStates[m.state] {
+			return nil
+		}
+	}
+}
+
+// operateCurrent executes behaviour for the current state CBM once
+func (m *CBM) operateCurrent(operator CBMBehaviour) error {
 	switch m.state {
+	case Opened:
+		return m.handleOpenedEvent(operator, m.operateOpened(operator))
+	case HalfOpened:
+		return m.handleHalfOpenedEvent(operator, operator.OperateHalfOpened())
 	case Closed:
-		m.handleClosedEvent(operator.OperateClosed())
+		return m.handleClosedEvent(operator, operator.OperateClosed())
 	case Exit:
-		return
-	case HalfOpened:
-		m.handleHalfOpenedEvent(operator.OperateHalfOpened())
-	case Opened:
-		m.handleOpenedEvent(operator.OperateOpened())
+		return nil
+	}
+	return nil
+}
+
+// CBMEventNotAllowedError reports event fired in state of CBM that doesn't declare it
+type CBMEventNotAllowedError struct {
+	State CBMState
+	Event CBMEvent
+}
+
+func (e *CBMEventNotAllowedError) Error() string {
+	return fmt.Sprintf("CBM event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state CBM.
+// If current state doesn't declare event, machine remains in it and *CBMEventNotAllowedError is returned.
+// If guard refuses transition, *CBMGuardError is returned.
+// Fire doesn't execute state behaviour, so automatic states are passed through only by Operate.
+func (m *CBM) Fire(operator CBMBehaviour, ev CBMEvent) error {
+	switch ev.id {
+	case _CBMEventSuccess:
+		switch m.state {
+		case HalfOpened:
+			return m.handleHalfOpenedEvent(operator, HalfOpenedSuccess)
+		}
+		return &CBMEventNotAllowedError{State: m.state, Event: ev}
+	case _CBMEventFailure:
+		switch m.state {
+		case HalfOpened:
+			return m.handleHalfOpenedEvent(operator, HalfOpenedFailure(ev.failurePayload))
+		}
+		return &CBMEventNotAllowedError{State: m.state, Event: ev}
+	case _CBMEventPanic:
+		switch m.state {
+		case HalfOpened:
+			return m.handleHalfOpenedEvent(operator, HalfOpenedPanic)
+		case Closed:
+			return m.handleClosedEvent(operator, ClosedPanic)
+		}
+		return &CBMEventNotAllowedError{State: m.state, Event: ev}
+	case _CBMEventError:
+		switch m.state {
+		case Closed:
+			return m.handleClosedEvent(operator, ClosedError(ev.errorPayload))
+		}
+		return &CBMEventNotAllowedError{State: m.state, Event: ev}
 	}
+	return &CBMEventNotAllowedError{State: m.state, Event: ev}
 }
 
 // Visualize states and events for CBM in Graphviz format
 func (m *CBM) Visualize() string {
 	return `// Definition for CBM in Graphviz format 
 digraph CBM {
-	Closed -> Opened [label=Error];
-	Closed -> Exit [label=Panic];
-	Exit [shape=Msquare];
-	HalfOpened -> Opened [label=Failure];
-	HalfOpened -> Exit [label=Panic];
+	__initial [shape=point];
+	__initial -> Closed;
+	Opened [tooltip="Opened breaker rejects calls until cool down period is over."];
+	Opened -> HalfOpened [label=After100ms];
+	HalfOpened [tooltip="HalfOpened breaker tries protected function once to decide whether it recovered."];
 	HalfOpened -> Closed [label=Success];
-	Opened -> HalfOpened [label=Try];
+	HalfOpened -> Opened [label=Failure, tooltip="opens breaker again with error of the try."];
+	HalfOpened -> Exit [label=Panic];
+	Closed [tooltip="Closed breaker passes calls to protected function and counts its errors."];
+	Closed -> Opened [label="Error [ThresholdReached]", tooltip="opens breaker once errors reach threshold."];
+	Closed -> Exit [label=Panic];
+	Exit [shape=Msquare, tooltip="Exit breaker is broken by panic of protected function."];
 }
 `
 }
 
 // Handlers for state transitions
 
-func (m *CBM) handleClosedEvent(event CBMClosedEvent) {
+func (m *CBM) handleOpenedEvent(operator CBMBehaviour, event CBMOpenedEvent) error {
 	switch event {
-	case ClosedError:
-		m.state = Opened
-	case ClosedPanic:
-		m.state = Exit
-	case ClosedNoop:
+	case OpenedAfter100ms:
+		m.state = HalfOpened
+		m.openedEnteredAt = time.Time{}
+	case OpenedNoop:
 	}
+	return nil
 }
 
-func (m *CBM) handleHalfOpenedEvent(event CBMHalfOpenedEvent) {
-	switch event {
-	case HalfOpenedFailure:
+// operateOpened takes the expired timed transition or executes behaviour of Opened
+func (m *CBM) operateOpened(operator CBMBehaviour) CBMOpenedEvent {
+	now := m.now()
+	if m.openedEnteredAt.IsZero() {
+		m.openedEnteredAt = now
+	}
+	if now.Sub(m.openedEnteredAt) >= 100*time.Millisecond {
+		return OpenedAfter100ms
+	}
+	return operator.OperateOpened()
+}
+
+func (m *CBM) handleHalfOpenedEvent(operator CBMBehaviour, event CBMHalfOpenedEvent) error {
+	switch event.id {
+	case _HalfOpenedSuccess:
+		m.state = Closed
+		operator.OnClosedEnter()
+	case _HalfOpenedFailure:
 		m.state = Opened
-	case HalfOpenedPanic:
+		m.openedEnteredAt = m.now()
+		operator.OnOpenedFailure(event.failurePayload)
+	case _HalfOpenedPanic:
 		m.state = Exit
-	case HalfOpenedSuccess:
-		m.state = Closed
-	case HalfOpenedNoop:
+	case _HalfOpenedNoop:
 	}
+	return nil
 }
 
-func (m *CBM) handleOpenedEvent(event CBMOpenedEvent) {
-	switch event {
-	case OpenedTry:
-		m.state = HalfOpened
-	case OpenedNoop:
+func (m *CBM) handleClosedEvent(operator CBMBehaviour, event CBMClosedEvent) error {
+	switch event.id {
+	case _ClosedError:
+		if !operator.ThresholdReached() {
+			return &CBMGuardError{State: Closed, Event: event.String(), Guard: "ThresholdReached"}
+		}
+		m.state = Opened
+		m.openedEnteredAt = m.now()
+		operator.OnOpenedError(event.errorPayload)
+	case _ClosedPanic:
+		m.state = Exit
+	case _ClosedNoop:
 	}
+	return nil
 }
 
 //--- Here we will define all events ---
 
-//=== CBMClosedEvent definition ===
+//=== CBMEvent definition ===
 
-// CBMClosedEvent definition
-type CBMClosedEvent int
+// CBMEvent is event of any state of CBM that can be fired, some of events carry payload
+type CBMEvent struct {
+	id             int
+	failurePayload error
+	errorPayload   error
+}
 
 const (
-	_           CBMClosedEvent = iota
-	ClosedError                // ClosedError -> Opened
-	ClosedPanic                // ClosedPanic -> Exit
-	ClosedNoop                 // remain in Closed
+	_ = iota
+	_CBMEventSuccess
+	_CBMEventFailure
+	_CBMEventPanic
+	_CBMEventError
+)
+
+var (
+	CBMEventSuccess = CBMEvent{id: _CBMEventSuccess}
+	CBMEventPanic   = CBMEvent{id: _CBMEventPanic}
 )
 
-var _CBMClosedEventMap = map[CBMClosedEvent]string{
-	ClosedError: "ClosedError",
-	ClosedPanic: "ClosedPanic",
-	ClosedNoop:  "ClosedNoop",
+// CBMEventFailure creates event Failure that carries payload
+func CBMEventFailure(payload error) CBMEvent {
+	return CBMEvent{id: _CBMEventFailure, failurePayload: payload}
 }
 
-func (m CBMClosedEvent) String() string {
-	return _CBMClosedEventMap[m]
+// CBMEventError creates event Error that carries payload
+func CBMEventError(payload error) CBMEvent {
+	return CBMEvent{id: _CBMEventError, errorPayload: payload}
 }
 
-// CBMClosedState behaviour
-type CBMClosedState interface {
-	OperateClosed() CBMClosedEvent
+var _CBMEventMap = map[int]string{
+	_CBMEventSuccess: "Success",
+	_CBMEventFailure: "Failure",
+	_CBMEventPanic:   "Panic",
+	_CBMEventError:   "Error",
+}
+
+func (m CBMEvent) String() string {
+	return _CBMEventMap[m.id]
+}
+
+//=== CBMOpenedEvent definition ===
+
+// CBMOpenedEvent definition
+type CBMOpenedEvent int
+
+const (
+	_                CBMOpenedEvent = iota
+	OpenedAfter100ms                // OpenedAfter100ms -> HalfOpened
+	OpenedNoop                      // remain in Opened
+)
+
+var _CBMOpenedEventMap = map[CBMOpenedEvent]string{
+	OpenedAfter100ms: "OpenedAfter100ms",
+	OpenedNoop:       "OpenedNoop",
+}
+
+func (m CBMOpenedEvent) String() string {
+	return _CBMOpenedEventMap[m]
+}
+
+// CBMOpenedState behaviour
+type CBMOpenedState interface {
+	// Opened breaker rejects calls until cool down period is over.
+	OperateOpened() CBMOpenedEvent
 }
 
 //=== CBMHalfOpenedEvent definition ===
 
-// CBMHalfOpenedEvent definition
-type CBMHalfOpenedEvent int
+// CBMHalfOpenedEvent definition, some of events carry payload
+type CBMHalfOpenedEvent struct {
+	id             int
+	failurePayload error
+}
 
 const (
-	_                 CBMHalfOpenedEvent = iota
-	HalfOpenedFailure                    // HalfOpenedFailure -> Opened
-	HalfOpenedPanic                      // HalfOpenedPanic -> Exit
-	HalfOpenedSuccess                    // HalfOpenedSuccess -> Closed
-	HalfOpenedNoop                       // remain in HalfOpened
+	_ = iota
+	_HalfOpenedSuccess
+	_HalfOpenedFailure
+	_HalfOpenedPanic
+	_HalfOpenedNoop
 )
 
-var _CBMHalfOpenedEventMap = map[CBMHalfOpenedEvent]string{
-	HalfOpenedFailure: "HalfOpenedFailure",
-	HalfOpenedPanic:   "HalfOpenedPanic",
-	HalfOpenedSuccess: "HalfOpenedSuccess",
-	HalfOpenedNoop:    "HalfOpenedNoop",
+var (
+	HalfOpenedSuccess = CBMHalfOpenedEvent{id: _HalfOpenedSuccess} // HalfOpenedSuccess -> Closed
+	HalfOpenedPanic   = CBMHalfOpenedEvent{id: _HalfOpenedPanic}   // HalfOpenedPanic -> Exit
+	HalfOpenedNoop    = CBMHalfOpenedEvent{id: _HalfOpenedNoop}    // remain in HalfOpened
+)
+
+// HalfOpenedFailure creates event HalfOpenedFailure -> Opened that carries payload
+// HalfOpenedFailure opens breaker again with error of the try.
+func HalfOpenedFailure(payload error) CBMHalfOpenedEvent {
+	return CBMHalfOpenedEvent{id: _HalfOpenedFailure, failurePayload: payload}
+}
+
+var _CBMHalfOpenedEventMap = map[int]string{
+	_HalfOpenedSuccess: "HalfOpenedSuccess",
+	_HalfOpenedFailure: "HalfOpenedFailure",
+	_HalfOpenedPanic:   "HalfOpenedPanic",
+	_HalfOpenedNoop:    "HalfOpenedNoop",
 }
 
 func (m CBMHalfOpenedEvent) String() string {
-	return _CBMHalfOpenedEventMap[m]
+	return _CBMHalfOpenedEventMap[m.id]
 }
 
 // CBMHalfOpenedState behaviour
 type CBMHalfOpenedState interface {
+	// HalfOpened breaker tries protected function once to decide whether it recovered.
+	// HalfOpenedFailure opens breaker again with error of the try.
 	OperateHalfOpened() CBMHalfOpenedEvent
 }
 
-//=== CBMOpenedEvent definition ===
+//=== CBMClosedEvent definition ===
 
-// CBMOpenedEvent definition
-type CBMOpenedEvent int
+// CBMClosedEvent definition, some of events carry payload
+type CBMClosedEvent struct {
+	id           int
+	errorPayload error
+}
 
 const (
-	_          CBMOpenedEvent = iota
-	OpenedTry                 // OpenedTry -> HalfOpened
-	OpenedNoop                // remain in Opened
+	_ = iota
+	_ClosedError
+	_ClosedPanic
+	_ClosedNoop
 )
 
-var _CBMOpenedEventMap = map[CBMOpenedEvent]string{
-	OpenedTry:  "OpenedTry",
-	OpenedNoop: "OpenedNoop",
+var (
+	ClosedPanic = CBMClosedEvent{id: _ClosedPanic} // ClosedPanic -> Exit
+	ClosedNoop  = CBMClosedEvent{id: _ClosedNoop}  // remain in Closed
+)
+
+// ClosedError creates event ClosedError -> Opened if ThresholdReached that carries payload
+// ClosedError opens breaker once errors reach threshold.
+func ClosedError(payload error) CBMClosedEvent {
+	return CBMClosedEvent{id: _ClosedError, errorPayload: payload}
 }
 
-func (m CBMOpenedEvent) String() string {
-	return _CBMOpenedEventMap[m]
+var _CBMClosedEventMap = map[int]string{
+	_ClosedError: "ClosedError",
+	_ClosedPanic: "ClosedPanic",
+	_ClosedNoop:  "ClosedNoop",
 }
 
-// CBMOpenedState behaviour
-type CBMOpenedState interface {
-	OperateOpened() CBMOpenedEvent
+func (m CBMClosedEvent) String() string {
+	return _CBMClosedEventMap[m.id]
+}
+
+// CBMClosedState behaviour
+type CBMClosedState interface {
+	// Closed breaker passes calls to protected function and counts its errors.
+	// ClosedError opens breaker once errors reach threshold.
+	OperateClosed() CBMClosedEvent
+}
+
+// CBMClosedGuards allow or refuse transitions from Closed
+type CBMClosedGuards interface {
+	ThresholdReached() bool
+}
+
+//--- Here we will define all state actions ---
+
+// CBMClosedEntry action executed when machine enters Closed
+type CBMClosedEntry interface {
+	OnClosedEnter()
+}
+
+//--- Here we will define all payload listeners ---
+
+// CBMOpenedListener receives payloads of events that lead to Opened
+type CBMOpenedListener interface {
+	OnOpenedError(payload error)
+	OnOpenedFailure(payload error)
 }
@@ -2,20 +2,31 @@ package examples
 
 import (
 	"errors"
-	"time"
+	"fmt"
 )
 
-//go:generate ../go-fsm-generator -type CBMDeclaration -v
+//go:generate ../go-fsm-generator -v
 
 // FSMState placeholder type
 type FSMState int
 
 // CBMDeclaration of the circuit breaker state machine
+//
+//fsm:machine Name=CBM
+//fsm:assert reachable Exit
+//fsm:assert never Opened -> Closed
+//fsm:assert leaves HalfOpened
 type CBMDeclaration struct {
-	Opened     FSMState `Try:"HalfOpened"`
-	HalfOpened FSMState `Success:"Closed",Failure:"Opened",Panic:"Exit"`
-	Closed     FSMState `Error:"Opened",Panic:"Exit"`
-	Exit       FSMState
+	// Opened breaker rejects calls until cool down period is over.
+	Opened FSMState `After100ms:"HalfOpened" fsm.label:"Open" fsm.retryable:"true"`
+	// HalfOpened breaker tries protected function once to decide whether it recovered.
+	// Failure: opens breaker again with error of the try.
+	HalfOpened FSMState `Success:"Closed" Failure(error):"Opened" Panic:"Exit" fsm:"auto" fsm.label:"Half open"`
+	// Closed breaker passes calls to protected function and counts its errors.
+	// Error: opens breaker once errors reach threshold.
+	Closed FSMState `Error(error):"Opened [ThresholdReached]" Panic:"Exit" fsm:"initial,entry" fsm.label:"Closed"`
+	// Exit breaker is broken by panic of protected function.
+	Exit FSMState `fsm.label:"Broken"`
 }
 
 // CircuitBreaker type with state machine inside
@@ -24,29 +35,28 @@ type CircuitBreaker struct {
 
 	protectedFunc func() error
 	lastErr       error
+	openedBy      error
 
 	failureCount     uint
 	failureThreshold uint
-
-	openedAt       time.Time
-	coolDownPeriod time.Duration
 }
 
 // NewCircuitBreaker constructor
 func NewCircuitBreaker() *CircuitBreaker {
 	return &CircuitBreaker{
-		fsm:              NewCBM(Closed),
+		fsm:              NewCBM(),
 		failureThreshold: 3,
-		coolDownPeriod:   100 * time.Millisecond,
 	}
 }
 
 // Run executes protected func under circuit breaker
 func (m *CircuitBreaker) Run(protectedFunc func() error) error {
 	m.protectedFunc = protectedFunc
-	m.fsm.Operate(m)
-	if m.fsm.Current() == HalfOpened {
-		m.fsm.Operate(m) // Try after transition to half opened
+	if err := m.fsm.Operate(m); err != nil {
+		// guard refusal is expected here, breaker remains closed until failure threshold is reached
+		if _, ok := err.(*CBMGuardError); !ok {
+			return err
+		}
 	}
 	return m.lastErr
 }
@@ -62,14 +72,16 @@ func (m *CircuitBreaker) OperateClosed() (event CBMClosedEvent) {
 	m.lastErr = m.protectedFunc()
 	if m.lastErr != nil {
 		m.failureCount++
-		if m.failureCount >= m.failureThreshold {
-			m.openedAt = time.Now()
-			return ClosedError
-		}
+		return ClosedError(m.lastErr)
 	}
 	return ClosedNoop
 }
 
+// ThresholdReached guards transition from closed to opened state
+func (m *CircuitBreaker) ThresholdReached() bool {
+	return m.failureCount >= m.failureThreshold
+}
+
 // OperateHalfOpened state behaviour
 func (m *CircuitBreaker) OperateHalfOpened() (event CBMHalfOpenedEvent) {
 	defer func() {
@@ -81,19 +93,28 @@ func (m *CircuitBreaker) OperateHalfOpened() (event CBMHalfOpenedEvent) {
 
 	m.lastErr = m.protectedFunc()
 	if m.lastErr != nil {
-		m.openedAt = time.Now()
-		return HalfOpenedFailure
+		return HalfOpenedFailure(m.lastErr)
 	}
+	return HalfOpenedSuccess
+}
 
+// OnClosedEnter forgets failures that happened before circuit was closed
+func (m *CircuitBreaker) OnClosedEnter() {
 	m.failureCount = 0
-	return HalfOpenedSuccess
 }
 
-// OperateOpened state behaviour
+// OnOpenedError remembers error that opened closed circuit
+func (m *CircuitBreaker) OnOpenedError(err error) {
+	m.openedBy = err
+}
+
+// OnOpenedFailure remembers error that opened half opened circuit
+func (m *CircuitBreaker) OnOpenedFailure(err error) {
+	m.openedBy = err
+}
+
+// OperateOpened state behaviour, machine leaves it after cool down period
 func (m *CircuitBreaker) OperateOpened() CBMOpenedEvent {
-	if time.Since(m.openedAt) > m.coolDownPeriod {
-		return OpenedTry
-	}
-	m.lastErr = errors.New("circuit is open")
+	m.lastErr = fmt.Errorf("circuit is open: %v", m.openedBy)
 	return OpenedNoop
 }
@@ -6,8 +6,22 @@ import (
 	"time"
 )
 
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
 func TestCircuitBreaker(t *testing.T) {
+	clock := &manualClock{now: time.Unix(0, 0)}
 	cb := NewCircuitBreaker()
+	cb.fsm.WithClock(clock)
 	err := cb.Run(func() error { return nil })
 	if err != nil {
 		t.Fatal("error should be nil", err)
@@ -29,11 +43,14 @@ func TestCircuitBreaker(t *testing.T) {
 	}
 
 	err = cb.Run(func() error { return nil })
-	if err == nil || err.Error() != "circuit is open" {
+	if err == nil || err.Error() != "circuit is open: target" {
 		t.Fatal("circuit isn't open", err)
 	}
+	if state := cb.fsm.Current(); state.Label() != "Open" || !state.Retryable() {
+		t.Fatal("unexpected attributes of open state", state.Label(), state.Retryable())
+	}
 
-	time.Sleep(100 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
 
 	err = cb.Run(func() error { return targetErr })
 	if err != targetErr {
@@ -41,13 +58,30 @@ func TestCircuitBreaker(t *testing.T) {
 	}
 
 	err = cb.Run(func() error { return nil })
-	if err == nil || err.Error() != "circuit is open" {
+	if err == nil || err.Error() != "circuit is open: target" {
 		t.Fatal("circuit isn't open", err)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
 	err = cb.Run(func() error { return nil })
 	if err != nil {
 		t.Fatal("error should be nil", err)
 	}
 }
+
+func TestCircuitBreakerFire(t *testing.T) {
+	cb := NewCircuitBreaker()
+	err := cb.fsm.Fire(cb, CBMEventSuccess)
+	if _, ok := err.(*CBMEventNotAllowedError); !ok || cb.fsm.Current() != Closed {
+		t.Fatal("event should not be allowed in closed state", err)
+	}
+
+	err = cb.fsm.Fire(cb, CBMEventPanic)
+	if err != nil || cb.fsm.Current() != Exit {
+		t.Fatal("breaker should exit on panic", err)
+	}
+	err = cb.fsm.Fire(cb, CBMEventPanic)
+	if err == nil || err.Error() != "CBM event Panic not allowed in state Exit" {
+		t.Fatal("event should not be allowed in terminal state", err)
+	}
+}
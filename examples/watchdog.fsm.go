@@ -0,0 +1,338 @@
+package examples
+
+import (
+	"fmt"
+	"time"
+)
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint WatchdogDeclaration 7ad8b53f1459b8f7
+
+//+++ General machine definition +++
+
+// WatchdogState type definition
+type WatchdogState int
+
+const (
+	_ WatchdogState = iota
+	// Running job alternates between its steps until it is finished or runs out of time.
+	Running
+	// Working step does the next portion of work.
+	Working
+	// Waiting step waits for the next portion of work.
+	Waiting
+	// Finished job completed all its portions in time.
+	Finished
+	// TimedOut job was stopped by watchdog.
+	TimedOut
+)
+
+var _WatchdogStateMap = map[WatchdogState]string{
+	Running:  "Running",
+	Working:  "Working",
+	Waiting:  "Waiting",
+	Finished: "Finished",
+	TimedOut: "TimedOut",
+}
+
+var _WatchdogParsingStateMap = map[string]WatchdogState{
+	"Working":  Working,
+	"Waiting":  Waiting,
+	"Finished": Finished,
+	"TimedOut": TimedOut,
+}
+
+func (s WatchdogState) String() string {
+	return _WatchdogStateMap[s]
+}
+
+var _WatchdogParentStateMap = map[WatchdogState]WatchdogState{
+	Working: Running,
+	Waiting: Running,
+}
+
+// Parent returns composite state that contains s
+func (s WatchdogState) Parent() WatchdogState {
+	return _WatchdogParentStateMap[s]
+}
+
+// In reports whether s is the same as state or nested inside of it
+func (s WatchdogState) In(state WatchdogState) bool {
+	for ; s != 0; s = s.Parent() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchdogBehaviour definition
+type WatchdogBehaviour interface {
+	WatchdogWorkingState
+	WatchdogWaitingState
+}
+
+// Watchdog machine type
+type Watchdog struct {
+	state            WatchdogState
+	clock            WatchdogClock
+	runningEnteredAt time.Time
+}
+
+// NewWatchdog creates machine in its initial state Working
+func NewWatchdog() *Watchdog {
+	return &Watchdog{state: Working}
+}
+
+// NewWatchdogFromString can be used to deserialize  machine state
+func NewWatchdogFromString(stateStr string) (*Watchdog, error) {
+	state, ok := _WatchdogParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Watchdog: %s", stateStr)
+	}
+	return &Watchdog{state: state}, nil
+}
+
+// Current returns current state of Watchdog
+func (m *Watchdog) Current() WatchdogState {
+	return m.state
+}
+
+// WatchdogClock provides current time for timed transitions of Watchdog
+type WatchdogClock interface {
+	Now() time.Time
+}
+
+// WithClock replaces clock used by timed transitions of Watchdog, so tests can control time.
+// Timers of the current states restart from the next Operate call.
+func (m *Watchdog) WithClock(clock WatchdogClock) *Watchdog {
+	m.clock = clock
+	m.runningEnteredAt = time.Time{}
+	return m
+}
+
+// now returns current time of Watchdog clock
+func (m *Watchdog) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
+
+// Operate executes behaviour for the current state Watchdog
+func (m *Watchdog) Operate(operator WatchdogBehaviour) {
+	switch m.state {
+	case Working:
+		m.handleWorkingEvent(m.operateWorking(operator))
+	case Waiting:
+		m.handleWaitingEvent(m.operateWaiting(operator))
+	case Finished:
+		return
+	case TimedOut:
+		return
+	}
+}
+
+// WatchdogEventNotAllowedError reports event fired in state of Watchdog that doesn't declare it
+type WatchdogEventNotAllowedError struct {
+	State WatchdogState
+	Event WatchdogEvent
+}
+
+func (e *WatchdogEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Watchdog event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Watchdog.
+// If current state doesn't declare event, machine remains in it and *WatchdogEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Watchdog) Fire(ev WatchdogEvent) error {
+	switch ev {
+	case WatchdogEventWait:
+		switch m.state {
+		case Working:
+			m.handleWorkingEvent(WorkingWait)
+			return nil
+		}
+		return &WatchdogEventNotAllowedError{State: m.state, Event: ev}
+	case WatchdogEventFinish:
+		switch m.state {
+		case Working:
+			m.handleWorkingEvent(WorkingFinish)
+			return nil
+		case Waiting:
+			m.handleWaitingEvent(WaitingFinish)
+			return nil
+		}
+		return &WatchdogEventNotAllowedError{State: m.state, Event: ev}
+	case WatchdogEventResume:
+		switch m.state {
+		case Waiting:
+			m.handleWaitingEvent(WaitingResume)
+			return nil
+		}
+		return &WatchdogEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &WatchdogEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Watchdog in Graphviz format
+func (m *Watchdog) Visualize() string {
+	return `// Definition for Watchdog in Graphviz format 
+digraph Watchdog {
+	compound=true;
+	__initial [shape=point];
+	__initial -> Working [lhead=cluster_Running];
+	subgraph cluster_Running {
+		label=Running;
+		tooltip="Running job alternates between its steps until it is finished or runs out of time.";
+		Working [tooltip="Working step does the next portion of work."];
+		Waiting [tooltip="Waiting step waits for the next portion of work."];
+	}
+	Finished [shape=Msquare, tooltip="Finished job completed all its portions in time."];
+	TimedOut [shape=Msquare, tooltip="TimedOut job was stopped by watchdog."];
+	Working -> Finished [label=Finish, ltail=cluster_Running];
+	Working -> TimedOut [label=After1s, ltail=cluster_Running];
+	Working -> Waiting [label=Wait];
+	Waiting -> Working [label=Resume];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Watchdog) handleWorkingEvent(event WatchdogWorkingEvent) {
+	switch event {
+	case WorkingWait:
+		m.state = Waiting
+	case WorkingFinish:
+		m.state = Finished
+		m.runningEnteredAt = time.Time{}
+	case WorkingAfter1s:
+		m.state = TimedOut
+		m.runningEnteredAt = time.Time{}
+	case WorkingNoop:
+	}
+}
+
+// operateWorking takes the expired timed transition or executes behaviour of Working
+func (m *Watchdog) operateWorking(operator WatchdogBehaviour) WatchdogWorkingEvent {
+	now := m.now()
+	if m.runningEnteredAt.IsZero() {
+		m.runningEnteredAt = now
+	}
+	if now.Sub(m.runningEnteredAt) >= 1*time.Second {
+		return WorkingAfter1s
+	}
+	return operator.OperateWorking()
+}
+
+func (m *Watchdog) handleWaitingEvent(event WatchdogWaitingEvent) {
+	switch event {
+	case WaitingResume:
+		m.state = Working
+	case WaitingFinish:
+		m.state = Finished
+		m.runningEnteredAt = time.Time{}
+	case WaitingAfter1s:
+		m.state = TimedOut
+		m.runningEnteredAt = time.Time{}
+	case WaitingNoop:
+	}
+}
+
+// operateWaiting takes the expired timed transition or executes behaviour of Waiting
+func (m *Watchdog) operateWaiting(operator WatchdogBehaviour) WatchdogWaitingEvent {
+	now := m.now()
+	if m.runningEnteredAt.IsZero() {
+		m.runningEnteredAt = now
+	}
+	if now.Sub(m.runningEnteredAt) >= 1*time.Second {
+		return WaitingAfter1s
+	}
+	return operator.OperateWaiting()
+}
+
+//--- Here we will define all events ---
+
+//=== WatchdogEvent definition ===
+
+// WatchdogEvent is event of any state of Watchdog that can be fired
+type WatchdogEvent int
+
+const (
+	_ WatchdogEvent = iota
+	WatchdogEventWait
+	WatchdogEventFinish
+	WatchdogEventResume
+)
+
+var _WatchdogEventMap = map[WatchdogEvent]string{
+	WatchdogEventWait:   "Wait",
+	WatchdogEventFinish: "Finish",
+	WatchdogEventResume: "Resume",
+}
+
+func (m WatchdogEvent) String() string {
+	return _WatchdogEventMap[m]
+}
+
+//=== WatchdogWorkingEvent definition ===
+
+// WatchdogWorkingEvent definition
+type WatchdogWorkingEvent int
+
+const (
+	_              WatchdogWorkingEvent = iota
+	WorkingWait                         // WorkingWait -> Waiting
+	WorkingFinish                       // WorkingFinish -> Finished
+	WorkingAfter1s                      // WorkingAfter1s -> TimedOut
+	WorkingNoop                         // remain in Working
+)
+
+var _WatchdogWorkingEventMap = map[WatchdogWorkingEvent]string{
+	WorkingWait:    "WorkingWait",
+	WorkingFinish:  "WorkingFinish",
+	WorkingAfter1s: "WorkingAfter1s",
+	WorkingNoop:    "WorkingNoop",
+}
+
+func (m WatchdogWorkingEvent) String() string {
+	return _WatchdogWorkingEventMap[m]
+}
+
+// WatchdogWorkingState behaviour
+type WatchdogWorkingState interface {
+	// Working step does the next portion of work.
+	OperateWorking() WatchdogWorkingEvent
+}
+
+//=== WatchdogWaitingEvent definition ===
+
+// WatchdogWaitingEvent definition
+type WatchdogWaitingEvent int
+
+const (
+	_              WatchdogWaitingEvent = iota
+	WaitingResume                       // WaitingResume -> Working
+	WaitingFinish                       // WaitingFinish -> Finished
+	WaitingAfter1s                      // WaitingAfter1s -> TimedOut
+	WaitingNoop                         // remain in Waiting
+)
+
+var _WatchdogWaitingEventMap = map[WatchdogWaitingEvent]string{
+	WaitingResume:  "WaitingResume",
+	WaitingFinish:  "WaitingFinish",
+	WaitingAfter1s: "WaitingAfter1s",
+	WaitingNoop:    "WaitingNoop",
+}
+
+func (m WatchdogWaitingEvent) String() string {
+	return _WatchdogWaitingEventMap[m]
+}
+
+// WatchdogWaitingState behaviour
+type WatchdogWaitingState interface {
+	// Waiting step waits for the next portion of work.
+	OperateWaiting() WatchdogWaitingEvent
+}
@@ -0,0 +1,50 @@
+package examples
+
+// WatchdogDeclaration of the state machine that limits running time of a job,
+// however often the job switches between its steps
+//
+//fsm:machine Name=Watchdog
+type WatchdogDeclaration struct {
+	// Running job alternates between its steps until it is finished or runs out of time.
+	Running struct {
+		// Working step does the next portion of work.
+		Working FSMState `Wait:"Waiting" fsm:"initial"`
+		// Waiting step waits for the next portion of work.
+		Waiting FSMState `Resume:"Working"`
+	} `Finish:"Finished" After1s:"TimedOut" fsm:"initial"`
+	// Finished job completed all its portions in time.
+	Finished FSMState
+	// TimedOut job was stopped by watchdog.
+	TimedOut FSMState
+}
+
+// Job does its work in portions and waits between them under watchdog
+type Job struct {
+	fsm      *Watchdog
+	portions int
+}
+
+// NewJob constructor
+func NewJob(portions int) *Job {
+	return &Job{fsm: NewWatchdog(), portions: portions}
+}
+
+// Step executes behaviour of the current step and returns state job ended up in
+func (j *Job) Step() WatchdogState {
+	j.fsm.Operate(j)
+	return j.fsm.Current()
+}
+
+// OperateWorking does the next portion of work or finishes the job
+func (j *Job) OperateWorking() WatchdogWorkingEvent {
+	if j.portions == 0 {
+		return WorkingFinish
+	}
+	j.portions--
+	return WorkingWait
+}
+
+// OperateWaiting resumes work
+func (j *Job) OperateWaiting() WatchdogWaitingEvent {
+	return WaitingResume
+}
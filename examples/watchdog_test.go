@@ -0,0 +1,37 @@
+package examples
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdogTimesOutJobThatKeepsSwitchingSteps(t *testing.T) {
+	clock := &manualClock{now: time.Unix(0, 0)}
+	job := NewJob(100)
+	job.fsm.WithClock(clock)
+
+	for i := 0; i < 4; i++ {
+		if state := job.Step(); !state.In(Running) {
+			t.Fatal("job should be running", i, state)
+		}
+		clock.Advance(300 * time.Millisecond)
+	}
+	if state := job.Step(); state != TimedOut {
+		t.Fatal("watchdog should stop job running longer than a second", state)
+	}
+}
+
+func TestWatchdogLetsJobFinishInTime(t *testing.T) {
+	clock := &manualClock{now: time.Unix(0, 0)}
+	job := NewJob(2)
+	job.fsm.WithClock(clock)
+
+	state := job.Step()
+	for i := 0; i < 4 && state != Finished; i++ {
+		clock.Advance(200 * time.Millisecond)
+		state = job.Step()
+	}
+	if state != Finished {
+		t.Fatal("job should be finished", state)
+	}
+}
@@ -0,0 +1,192 @@
+package generator
+
+import (
+	"go/token"
+	"log"
+	"sort"
+	"strings"
+)
+
+// analyzeDefinition checks transition graph of machine for states that can't be reached from initial state
+// and for states that can't reach any terminal state. States that can't reach terminal state are reported
+// by strongly connected components, so states trapped in one cycle are reported together.
+// Machines or regions without terminal states are meant to run forever, so they have no traps.
+// Problems are warnings, unless analysis is strict.
+func analyzeDefinition(fset *token.FileSet, definition machineDefinition, strict bool) []Diagnostic {
+	graph := transitionGraph(definition)
+	severity := SeverityWarning
+	if strict {
+		severity = SeverityError
+	}
+	var result []Diagnostic
+
+	starts := initialLeaves(definition)
+	reachable := reachableStates(graph, starts)
+	for _, st := range definition.OrderedLeaves() {
+		if !reachable[st.Name] {
+			result = append(result, Diagnostic{
+				Position: fset.Position(st.Field.Pos()),
+				Severity: severity,
+				Message:  "state `" + string(st.Name) + "` can't be reached from initial state",
+			})
+		}
+	}
+
+	reverse := map[state][]state{}
+	for _, from := range definition.OrderedLeaves() {
+		for _, to := range graph[from.Name] {
+			reverse[to] = append(reverse[to], from.Name)
+		}
+	}
+	terminals := map[string][]state{}
+	for _, st := range definition.OrderedLeaves() {
+		if st.IsTerminal {
+			terminals[st.Region] = append(terminals[st.Region], st.Name)
+		}
+	}
+	finishing := map[state]bool{}
+	for _, list := range terminals {
+		for name := range reachableStates(reverse, list) {
+			finishing[name] = true
+		}
+	}
+	trapped := map[state]bool{}
+	for _, st := range definition.OrderedLeaves() {
+		trapped[st.Name] = len(terminals[st.Region]) > 0 && !finishing[st.Name] && reachable[st.Name]
+	}
+	for _, component := range stronglyConnectedComponents(definition, graph) {
+		if !trapped[component[0]] {
+			continue
+		}
+		names := make([]string, 0, len(component))
+		for _, name := range component {
+			names = append(names, "`"+string(name)+"`")
+		}
+		message := "state " + names[0] + " can't reach any terminal state"
+		if len(component) > 1 {
+			message = "states " + strings.Join(names, ", ") + " form a cycle that can't reach any terminal state"
+		}
+		result = append(result, Diagnostic{
+			Position: fset.Position(definition.States[component[0]].Field.Pos()),
+			Severity: severity,
+			Message:  message,
+		})
+	}
+	return result
+}
+
+// initialLeaves returns leaf states machine starts from, one for every region
+func initialLeaves(definition machineDefinition) []state {
+	var result []state
+	if len(definition.Regions) > 0 {
+		for _, r := range definition.Regions {
+			result = append(result, r.InitialLeaf)
+		}
+	} else {
+		result = append(result, definition.InitialLeaf())
+	}
+	return result
+}
+
+// transitionGraph returns leaf states each leaf state can move to.
+// Transition that resumes history can lead to any leaf state inside of composite state.
+func transitionGraph(definition machineDefinition) map[state][]state {
+	graph := map[state][]state{}
+	for _, st := range definition.OrderedLeaves() {
+		for _, tr := range st.OrderedTransitions() {
+			if tr.RestoredHistory != nil {
+				graph[st.Name] = append(graph[st.Name], definition.leavesOf(tr.Destination)...)
+				continue
+			}
+			graph[st.Name] = append(graph[st.Name], tr.Leaf)
+		}
+	}
+	return graph
+}
+
+func reachableStates(graph map[state][]state, starts []state) map[state]bool {
+	result := map[state]bool{}
+	queue := append([]state(nil), starts...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if result[current] {
+			continue
+		}
+		result[current] = true
+		queue = append(queue, graph[current]...)
+	}
+	return result
+}
+
+// stronglyConnectedComponents returns components of transition graph in declaration order of their first states
+func stronglyConnectedComponents(definition machineDefinition, graph map[state][]state) [][]state {
+	index := map[state]int{}
+	lowLink := map[state]int{}
+	onStack := map[state]bool{}
+	var stack []state
+	var components [][]state
+	var connect func(s state)
+	connect = func(s state) {
+		index[s] = len(index)
+		lowLink[s] = index[s]
+		stack = append(stack, s)
+		onStack[s] = true
+		for _, next := range graph[s] {
+			if _, ok := index[next]; !ok {
+				connect(next)
+				if lowLink[next] < lowLink[s] {
+					lowLink[s] = lowLink[next]
+				}
+			} else if onStack[next] && index[next] < lowLink[s] {
+				lowLink[s] = index[next]
+			}
+		}
+		if lowLink[s] != index[s] {
+			return
+		}
+		var component []state
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == s {
+				break
+			}
+		}
+		components = append(components, component)
+	}
+	order := map[state]int{}
+	for i, st := range definition.OrderedLeaves() {
+		order[st.Name] = i
+		if _, ok := index[st.Name]; !ok {
+			connect(st.Name)
+		}
+	}
+	for _, component := range components {
+		sort.Slice(component, func(i, j int) bool {
+			return order[component[i]] < order[component[j]]
+		})
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return order[components[i][0]] < order[components[j][0]]
+	})
+	return components
+}
+
+// reportDiagnostics prints problems found by analysis and fails generation if some of them are errors
+func reportDiagnostics(definition machineDefinition, diagnostics []Diagnostic) {
+	var errors []Diagnostic
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			errors = append(errors, d)
+			log.Printf("%s. %v", d.Message, d.Position)
+			continue
+		}
+		log.Printf("warning: %s. %v", d.Message, d.Position)
+	}
+	if len(errors) > 0 {
+		failf(errors[0].Position, "analysis of machine %s found %d problems", definition.MachineName, len(errors))
+	}
+}
@@ -0,0 +1,192 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// attributePrefix starts tag keys of state attributes like `fsm.label:"Half open"`
+const attributePrefix = optionsTag + "."
+
+// attributeValue is the value of state attribute declared in tag
+type attributeValue struct {
+	// Type is Go type of value, declared explicitly as `fsm.retries(int):"3"` or inferred from the value
+	Type     string
+	Explicit bool
+	// Literal is Go literal of value
+	Literal string
+}
+
+// attribute is compiled into accessor method of the state type
+type attribute struct {
+	Name   string
+	Method string
+	Type   string
+	// Values are literals of attribute for states that declare or inherit it, in generation order of states
+	Values []attributeEntry
+}
+
+// attributeEntry is the literal of attribute for the state
+type attributeEntry struct {
+	State   state
+	Literal string
+}
+
+// methodsOfStateType are generated methods attributes can't collide with
+var methodsOfStateType = []string{"String", "Parent", "In"}
+
+// parseAttribute parses tag pair of state attribute
+func parseAttribute(key string, value string) (string, attributeValue, error) {
+	name, typ := strings.TrimPrefix(key, attributePrefix), ""
+	if i := strings.Index(name, "("); i >= 0 {
+		if !strings.HasSuffix(name, ")") {
+			return "", attributeValue{}, fmt.Errorf("type of attribute `%s` should be closed with `)`", name[:i])
+		}
+		name, typ = name[:i], name[i+1:len(name)-1]
+	}
+	if !isIdentifier(name) {
+		return "", attributeValue{}, fmt.Errorf("attribute `%s` is not a valid identifier", name)
+	}
+	if typ == "" {
+		return name, inferAttributeValue(value), nil
+	}
+	literal, err := attributeLiteral(typ, value)
+	if err != nil {
+		return "", attributeValue{}, fmt.Errorf("attribute `%s`: %v", name, err)
+	}
+	return name, attributeValue{Type: typ, Explicit: true, Literal: literal}, nil
+}
+
+// inferAttributeValue detects the narrowest type value can have
+func inferAttributeValue(value string) attributeValue {
+	for _, typ := range []string{"bool", "int", "float64"} {
+		if literal, err := attributeLiteral(typ, value); err == nil {
+			return attributeValue{Type: typ, Literal: literal}
+		}
+	}
+	return attributeValue{Type: "string", Literal: strconv.Quote(value)}
+}
+
+// attributeLiteral converts value to canonical Go literal of specified type,
+// so decimal values like `08` or `010` don't turn into invalid or octal literals
+func attributeLiteral(typ string, value string) (string, error) {
+	literal := value
+	var err error
+	switch typ {
+	case "string":
+		return strconv.Quote(value), nil
+	case "bool":
+		if value != "true" && value != "false" {
+			err = strconv.ErrSyntax
+		}
+	case "int", "int64":
+		bitSize := 64
+		if typ == "int" {
+			bitSize = strconv.IntSize
+		}
+		var i int64
+		i, err = strconv.ParseInt(value, 10, bitSize)
+		literal = strconv.FormatInt(i, 10)
+	case "float64":
+		var f float64
+		f, err = strconv.ParseFloat(value, 64)
+		if err == nil && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			err = strconv.ErrSyntax
+		}
+		literal = strconv.FormatFloat(f, 'g', -1, 64)
+	default:
+		return "", fmt.Errorf("type `%s` is unknown, supported types are string, bool, int, int64 and float64", typ)
+	}
+	if err != nil {
+		return "", fmt.Errorf("value `%s` isn't %s", value, typ)
+	}
+	return literal, nil
+}
+
+// collectAttributes compiles attributes of states into accessors.
+// States inherit attributes of their parents, but own attributes of inner state take precedence.
+// Attribute should have the same type in every state that declares it,
+// and attributes can't differ only by case of the first letter, since they share accessor method.
+func collectAttributes(fset *token.FileSet, definition machineDefinition) []attribute {
+	declared := map[string]state{}
+	byName := map[string]*attribute{}
+	byMethod := map[string]string{}
+	for _, name := range sortedStates(definition.States) {
+		st := definition.States[name]
+		for _, attr := range sortedAttributeNames(st.Attributes) {
+			value := st.Attributes[attr]
+			if first, ok := declared[attr]; ok {
+				expected := definition.States[first].Attributes[attr]
+				if expected.Type != value.Type {
+					hint := ""
+					if !expected.Explicit || !value.Explicit {
+						hint = fmt.Sprintf(", declare type explicitly like `%s%s(%s)`", attributePrefix, attr, commonAttributeType(expected, value))
+					}
+					failf(
+						fset.Position(st.Field.Pos()),
+						"attribute `%s` has type %s on state `%s`, but %s on state `%s`%s",
+						attr, value.Type, name, expected.Type, first, hint,
+					)
+				}
+				continue
+			}
+			method := strings.ToUpper(attr[:1]) + attr[1:]
+			if contains(methodsOfStateType, method) {
+				failf(fset.Position(st.Field.Pos()), "attribute `%s` collides with method `%s` of state type", attr, method)
+			}
+			if other, ok := byMethod[method]; ok {
+				failf(fset.Position(st.Field.Pos()), "attributes `%s` and `%s` both generate method `%s` of state type", other, attr, method)
+			}
+			byMethod[method] = attr
+			declared[attr] = name
+			byName[attr] = &attribute{Name: attr, Method: method, Type: value.Type}
+		}
+	}
+	for _, name := range definition.StateOrder {
+		literals := map[string]string{}
+		for s := name; s != ""; s = definition.States[s].Parent {
+			for attr, value := range definition.States[s].Attributes {
+				if _, ok := literals[attr]; !ok {
+					literals[attr] = value.Literal
+				}
+			}
+		}
+		for attr, literal := range literals {
+			byName[attr].Values = append(byName[attr].Values, attributeEntry{State: name, Literal: literal})
+		}
+	}
+	var result []attribute
+	for _, attr := range byName {
+		result = append(result, *attr)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// commonAttributeType suggests type values of attribute can share
+func commonAttributeType(a attributeValue, b attributeValue) string {
+	switch {
+	case a.Explicit:
+		return a.Type
+	case b.Explicit:
+		return b.Type
+	case a.Type != "string" && a.Type != "bool" && b.Type != "string" && b.Type != "bool":
+		return "float64"
+	}
+	return "string"
+}
+
+func sortedAttributeNames(m map[string]attributeValue) []string {
+	var result []string
+	for name := range m {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
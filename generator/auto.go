@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// verifyAutoStates checks that states declared with `fsm:"auto"` option are non-terminal leaf states
+// and that automatic states don't form a cycle, so Operate always reaches a stable state.
+func verifyAutoStates(fset *token.FileSet, definition machineDefinition) {
+	for _, name := range sortedStates(definition.States) {
+		st := definition.States[name]
+		if !st.IsAuto {
+			continue
+		}
+		if st.IsComposite {
+			failf(fset.Position(st.Field.Pos()), "composite state `%s` can't be automatic, declare its sub-states automatic instead", name)
+		}
+		if st.IsTerminal {
+			failf(fset.Position(st.Field.Pos()), "terminal state `%s` can't be automatic", name)
+		}
+	}
+
+	visited := map[state]bool{}
+	for _, name := range sortedStates(definition.States) {
+		if definition.States[name].IsAuto && !visited[name] {
+			findAutoCycle(fset, definition, name, visited, nil)
+		}
+	}
+}
+
+// findAutoCycle walks transitions between automatic states depth-first and fails on the first cycle
+func findAutoCycle(fset *token.FileSet, definition machineDefinition, name state, visited map[state]bool, path []state) {
+	for i, st := range path {
+		if st == name {
+			cycle := make([]string, 0, len(path)-i+1)
+			for _, s := range append(path[i:], name) {
+				cycle = append(cycle, string(s))
+			}
+			failf(
+				fset.Position(definition.States[name].Field.Pos()),
+				"automatic states form a cycle %s, machine would never stop operating",
+				strings.Join(cycle, " -> "),
+			)
+		}
+	}
+	if visited[name] {
+		return
+	}
+	st := definition.States[name]
+	path = append(path, name)
+	for _, ev := range sortedEvents(st.Transitions) {
+		for _, next := range destinationLeaves(st.Transitions[ev]) {
+			if definition.States[next].IsAuto {
+				findAutoCycle(fset, definition, next, visited, path)
+			}
+		}
+	}
+	visited[name] = true
+}
+
+// destinationLeaves returns leaf states machine can enter by transition
+func destinationLeaves(tr transition) []state {
+	if tr.RestoredHistory == nil {
+		return []state{tr.Leaf}
+	}
+	var result []state
+	for leaf := range tr.RestoredHistory.Values {
+		result = append(result, leaf)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
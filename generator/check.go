@@ -0,0 +1,142 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+const (
+	// SeverityError marks problem that fails generation
+	SeverityError = "error"
+	// SeverityWarning marks problem that generator only warns about
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem of machine declaration found by verification or analysis
+type Diagnostic struct {
+	Position token.Position
+	Severity string
+	Message  string
+}
+
+// String prints diagnostic like `etalon.go:12:2: error: message`
+func (d Diagnostic) String() string {
+	if d.Position.Filename == "" && !d.Position.IsValid() {
+		return d.Severity + ": " + d.Message
+	}
+	return d.Position.String() + ": " + d.Severity + ": " + d.Message
+}
+
+// MarshalJSON encodes diagnostic with file, line and column of its position
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+		Severity string `json:"severity"`
+		Message  string `json:"message"`
+	}{d.Position.Filename, d.Position.Line, d.Position.Column, d.Severity, d.Message})
+}
+
+// declarationError is a problem of machine declaration that stops its generation
+type declarationError struct {
+	Position token.Position
+	Message  string
+}
+
+// Error prints problem like `message. etalon.go:12:2`
+func (e *declarationError) Error() string {
+	if e.Position.Filename == "" && !e.Position.IsValid() {
+		return e.Message
+	}
+	return e.Message + ". " + e.Position.String()
+}
+
+// declarationErrors are problems found by independent verification passes of machine declaration
+type declarationErrors []*declarationError
+
+// Error prints every problem on its own line
+func (errs declarationErrors) Error() string {
+	var lines []string
+	for _, e := range errs {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// verify runs verification pass and records problem it was stopped with by failf, so next passes still run
+func (errs *declarationErrors) verify(pass func()) {
+	*errs = append(*errs, catchDeclarationError(pass)...)
+}
+
+// fail stops generation of machine with recorded problems, if there are any
+func (errs declarationErrors) fail() {
+	if len(errs) > 0 {
+		panic(errs)
+	}
+}
+
+// failf stops generation of machine with problem found at position of its declaration.
+// Problem is returned by catchDeclarationError that wraps generation of machine.
+func failf(position token.Position, format string, args ...interface{}) {
+	panic(&declarationError{Position: position, Message: fmt.Sprintf(format, args...)})
+}
+
+// catchDeclarationError runs generation step and returns problems it was stopped with by failf
+func catchDeclarationError(step func()) (errs declarationErrors) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch e := r.(type) {
+			case *declarationError:
+				errs = declarationErrors{e}
+			case declarationErrors:
+				errs = e
+			default:
+				panic(r)
+			}
+		}
+	}()
+	step()
+	return nil
+}
+
+// CheckDeclarations parses and verifies machines like RunGenerator does, but doesn't write any files.
+// Instead of stopping on the first problem it checks every machine and returns problems of all of them,
+// including warnings of analysis. Every verification pass of machine reports its problem,
+// but machine with invalid declaration isn't analyzed.
+func CheckDeclarations(dirName string, types []string, options Options) []Diagnostic {
+	var result []Diagnostic
+	report := func(errs declarationErrors) {
+		for _, err := range errs {
+			result = append(result, Diagnostic{Position: err.Position, Severity: SeverityError, Message: err.Message})
+		}
+	}
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dirName, nil, parser.SpuriousErrors|parser.ParseComments)
+	if list, ok := err.(scanner.ErrorList); ok {
+		for _, e := range list {
+			result = append(result, Diagnostic{Position: e.Pos, Severity: SeverityError, Message: e.Msg})
+		}
+		return result
+	}
+	if err != nil {
+		return []Diagnostic{{Severity: SeverityError, Message: fmt.Sprintf("can't parse destination dir: %v", err)}}
+	}
+	report(catchDeclarationError(func() {
+		forEachMachine(fset, pkgs, types, func(ns *namespace, machineName string, pkg *ast.Package, obj *ast.Object) {
+			report(catchDeclarationError(func() {
+				definition := parseDefinition(options, machineName, dirName, pkg, fset, obj)
+				result = append(result, analyzeDefinition(fset, definition, options.Strict)...)
+				result = append(result, checkProperties(fset, definition)...)
+				lock, locked := readLockFile(lockFilePath(definition), false)
+				generateSource(options, ns, pkg, fset, &definition, lock, locked)
+			}))
+		})
+	}))
+	return result
+}
@@ -0,0 +1,303 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// additiveChange keeps code and persisted data of previous version of machine working
+	additiveChange = "additive"
+	// breakingChange breaks code or persisted data of previous version of machine
+	breakingChange = "breaking"
+)
+
+// change is a difference between two versions of machine
+type change struct {
+	Kind    string
+	Message string
+}
+
+// DiffDeclarations compares machines declared at oldSpec and newSpec, writes their changes to w
+// and reports whether some of them are breaking. Spec is a directory or a file with declarations,
+// or `<revision>:<path>` of git repository, like `HEAD~1:examples/circuitbreaker.go`.
+// Spec of file selects machines marked in it, unless types are specified, while the whole package is parsed.
+// Constants are compared as generator numbers them, taking lock files into account.
+func DiffDeclarations(oldSpec string, newSpec string, types []string, options Options, w io.Writer) (bool, error) {
+	previous, err := loadMachines(oldSpec, types, options)
+	if err != nil {
+		return false, err
+	}
+	current, err := loadMachines(newSpec, types, options)
+	if err != nil {
+		return false, err
+	}
+	var names []string
+	for name := range previous {
+		names = append(names, name)
+	}
+	for name := range current {
+		if _, ok := previous[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	breaking := false
+	for _, name := range names {
+		before, wasDeclared := previous[name]
+		after, isDeclared := current[name]
+		var changes []change
+		switch {
+		case !isDeclared:
+			changes = []change{{breakingChange, "machine removed"}}
+		case !wasDeclared:
+			changes = []change{{additiveChange, "machine added"}}
+		default:
+			changes = compareMachines(before, after)
+		}
+		for _, c := range changes {
+			fmt.Fprintf(w, "%s: %s: %s\n", name, c.Kind, c.Message)
+			breaking = breaking || c.Kind == breakingChange
+		}
+	}
+	return breaking, nil
+}
+
+// compareMachines classifies changes between two versions of machine. Removed states and events,
+// transitions that lead to other states, renumbered constants and states that can't be reached anymore are breaking.
+func compareMachines(previous machineDefinition, current machineDefinition) []change {
+	var result []change
+	breaking := func(format string, args ...interface{}) {
+		result = append(result, change{breakingChange, fmt.Sprintf(format, args...)})
+	}
+	additive := func(format string, args ...interface{}) {
+		result = append(result, change{additiveChange, fmt.Sprintf(format, args...)})
+	}
+
+	for _, name := range sortedStates(previous.States) {
+		after, ok := current.States[name]
+		if !ok {
+			breaking("state `%s` removed", name)
+			continue
+		}
+		if before := previous.States[name]; before.Value != after.Value {
+			breaking("state `%s` renumbered from %d to %d", name, before.Value, after.Value)
+		}
+	}
+	for _, name := range sortedStates(current.States) {
+		if _, ok := previous.States[name]; !ok {
+			additive("state `%s` added", name)
+		}
+	}
+
+	previousLeaves, currentLeaves := previous.Leaves(), current.Leaves()
+	for _, name := range sortedStates(previousLeaves) {
+		before := previousLeaves[name]
+		after, ok := currentLeaves[name]
+		if !ok {
+			continue
+		}
+		for _, ev := range sortedEvents(before.Transitions) {
+			tr := before.Transitions[ev]
+			next, ok := after.Transitions[ev]
+			switch {
+			case !ok:
+				breaking("event `%s` removed from state `%s`", ev, name)
+			case destinationOf(tr) != destinationOf(next):
+				breaking("event `%s` of state `%s` retargeted from `%s` to `%s`", ev, name, destinationOf(tr), destinationOf(next))
+			case tr.Value != next.Value:
+				breaking("event `%s` of state `%s` renumbered from %d to %d", ev, name, tr.Value, next.Value)
+			}
+		}
+		for _, ev := range sortedEvents(after.Transitions) {
+			if _, ok := before.Transitions[ev]; !ok {
+				additive("event `%s` added to state `%s`", ev, name)
+			}
+		}
+		if !before.IsTerminal && !after.IsTerminal && before.NoopValue != after.NoopValue {
+			breaking("event `%s` of state `%s` renumbered from %d to %d", noopEvent, name, before.NoopValue, after.NoopValue)
+		}
+	}
+
+	machineEvents := map[event]int{}
+	for _, ev := range previous.Events {
+		machineEvents[ev.Name] = ev.Value
+	}
+	for _, ev := range current.Events {
+		if value, ok := machineEvents[ev.Name]; ok && value != ev.Value {
+			breaking("machine event `%s` renumbered from %d to %d", ev.Name, value, ev.Value)
+		}
+	}
+
+	wasReachable := reachableStates(transitionGraph(previous), initialLeaves(previous))
+	isReachable := reachableStates(transitionGraph(current), initialLeaves(current))
+	for _, name := range sortedStates(previousLeaves) {
+		if _, ok := currentLeaves[name]; ok && wasReachable[name] && !isReachable[name] {
+			breaking("state `%s` can't be reached from initial state anymore", name)
+		}
+	}
+	return result
+}
+
+// destinationOf returns destination of transition together with history it resumes, like `Operating.H`
+func destinationOf(tr transition) string {
+	if tr.History != "" {
+		return string(tr.Destination) + "." + tr.History
+	}
+	return string(tr.Destination)
+}
+
+// declarations are parsed package with declarations of machines
+type declarations struct {
+	DirName string
+	// FileName is the file spec refers to, if it doesn't refer to directory
+	FileName string
+	Fset     *token.FileSet
+	Pkgs     map[string]*ast.Package
+	// Cleanup removes temporary directory with files of git revision
+	Cleanup func()
+}
+
+// loadMachines parses machines declared at spec and numbers their states and events
+func loadMachines(spec string, types []string, options Options) (map[string]machineDefinition, error) {
+	d, err := loadDeclarations(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Cleanup()
+	result := map[string]machineDefinition{}
+	if err := catchDeclarationError(func() {
+		if d.FileName != "" && len(types) == 0 {
+			types = markedTypesOfFile(d.Fset, d.Pkgs, filepath.Join(d.DirName, d.FileName))
+			if len(types) == 0 {
+				return
+			}
+		}
+		forEachMachine(d.Fset, d.Pkgs, types, func(ns *namespace, machineName string, pkg *ast.Package, obj *ast.Object) {
+			definition := parseDefinition(options, machineName, d.DirName, pkg, d.Fset, obj)
+			lock, _ := readLockFile(lockFilePath(definition), true)
+			assignStateAndEventValues(d.Fset, &definition, lock, true)
+			result[machineName] = definition
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// markedTypesOfFile returns types marked with `//fsm:machine` comment that are declared in file
+func markedTypesOfFile(fset *token.FileSet, pkgs map[string]*ast.Package, path string) []string {
+	var result []string
+	for typeName := range collectMarkers(fset, pkgs) {
+		for _, pkg := range pkgs {
+			if file, ok := pkg.Files[path]; ok && file.Scope.Lookup(typeName) != nil {
+				result = append(result, typeName)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// loadDeclarations parses package of declarations at local path or at `<revision>:<path>` of git repository.
+// Files of git revision are written to temporary directory together with lock files,
+// which is removed by Cleanup function of result.
+func loadDeclarations(spec string) (declarations, error) {
+	if info, err := os.Stat(spec); err == nil {
+		result := declarations{DirName: spec, Cleanup: func() {}}
+		if !info.IsDir() {
+			result.DirName, result.FileName = filepath.Dir(spec), filepath.Base(spec)
+		}
+		return result, result.parse()
+	}
+	colon := strings.Index(spec, ":")
+	if colon <= 0 {
+		return declarations{}, fmt.Errorf("`%s` is neither a path nor `<revision>:<path>` of git repository", spec)
+	}
+	revision, path := spec[:colon], spec[colon+1:]
+	objectType, err := git("cat-file", "-t", spec)
+	if err != nil {
+		return declarations{}, err
+	}
+	dirPath, fileName := path, ""
+	if strings.TrimSpace(objectType) == "blob" {
+		dirPath, fileName = "", path
+		if slash := strings.LastIndex(path, "/"); slash >= 0 {
+			dirPath, fileName = path[:slash], path[slash+1:]
+		}
+	}
+	names, err := git("ls-tree", "--full-tree", "--name-only", revision+":"+dirPath)
+	if err != nil {
+		return declarations{}, err
+	}
+	dirName, err := ioutil.TempDir("", "fsm-diff")
+	if err != nil {
+		return declarations{}, fmt.Errorf("can't create temporary dir: %v", err)
+	}
+	result := declarations{DirName: dirName, FileName: fileName, Cleanup: func() {
+		os.RemoveAll(dirName)
+	}}
+	if err := result.writeRevision(revision, dirPath, names); err != nil {
+		result.Cleanup()
+		return declarations{}, err
+	}
+	if err := result.parse(); err != nil {
+		result.Cleanup()
+		return declarations{}, err
+	}
+	return result, nil
+}
+
+// writeRevision writes Go and lock files of revision directory to DirName
+func (d *declarations) writeRevision(revision string, dirPath string, names string) error {
+	for _, name := range strings.Split(names, "\n") {
+		if !strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, lockFileSuffix) {
+			continue
+		}
+		revisionPath := name
+		if dirPath != "" {
+			revisionPath = strings.TrimSuffix(dirPath, "/") + "/" + name
+		}
+		content, err := git("show", revision+":"+revisionPath)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(d.DirName, name), []byte(content), 0664); err != nil {
+			return fmt.Errorf("can't write file of revision to disk: %v", err)
+		}
+	}
+	return nil
+}
+
+// parse parses the whole package in DirName, even if spec refers to one file of it
+func (d *declarations) parse() error {
+	d.Fset = token.NewFileSet()
+	pkgs, err := parser.ParseDir(d.Fset, d.DirName, nil, parser.SpuriousErrors|parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("can't parse declarations: %v", err)
+	}
+	d.Pkgs = pkgs
+	return nil
+}
+
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// machineMarker is the comment that marks struct type as declaration of machine, like `//fsm:machine Name=CBM`
+const machineMarker = "//fsm:machine"
+
+const markerNameKey = "Name"
+
+// collectMarkers returns names of machines declared by types marked with machineMarker comment.
+// Machine name defaults to type name without `Declaration` suffix.
+func collectMarkers(fset *token.FileSet, packages map[string]*ast.Package) map[string]string {
+	markers := map[string]string{}
+	for _, pkg := range packages {
+		for _, fileName := range sortedFileNames(pkg) {
+			for _, decl := range pkg.Files[fileName].Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					doc := typeSpec.Doc
+					if doc == nil && len(genDecl.Specs) == 1 {
+						doc = genDecl.Doc
+					}
+					name, ok := parseMarker(fset, typeSpec, doc)
+					if ok {
+						markers[typeSpec.Name.Name] = name
+					}
+				}
+			}
+		}
+	}
+	return markers
+}
+
+func parseMarker(fset *token.FileSet, typeSpec *ast.TypeSpec, doc *ast.CommentGroup) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, comment := range doc.List {
+		if comment.Text != machineMarker && !strings.HasPrefix(comment.Text, machineMarker+" ") {
+			continue
+		}
+		name := strings.TrimSuffix(typeSpec.Name.Name, declarationTag)
+		for _, arg := range strings.Fields(strings.TrimPrefix(comment.Text, machineMarker)) {
+			parts := strings.SplitN(arg, "=", 2)
+			if len(parts) != 2 || parts[0] != markerNameKey {
+				failf(fset.Position(comment.Pos()), "unsupported argument `%s` of `%s` comment, expected `%s=<machine name>`", arg, machineMarker, markerNameKey)
+			}
+			if !isIdentifier(parts[1]) {
+				failf(fset.Position(comment.Pos()), "machine name `%s` is not a valid identifier", parts[1])
+			}
+			name = parts[1]
+		}
+		if name == "" || name == typeSpec.Name.Name {
+			failf(fset.Position(comment.Pos()), "machine name of `%s` should differ from type name, set it with `%s %s=<machine name>`", typeSpec.Name.Name, machineMarker, markerNameKey)
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// machineTypes returns types to generate machines for.
+// If no types are specified, all types marked with machineMarker comment are used.
+// Types that aren't marked should have `Declaration` suffix.
+func machineTypes(types []string, markers map[string]string) []string {
+	if len(types) == 0 {
+		for t := range markers {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		if len(types) == 0 {
+			failf(token.Position{}, "no types specified and no types marked with `%s` comment found", machineMarker)
+		}
+		return types
+	}
+	var unmarked []string
+	for _, t := range types {
+		if _, ok := markers[t]; !ok {
+			unmarked = append(unmarked, t)
+		}
+	}
+	verificationError := verifySpecifiedTypes(unmarked)
+	if verificationError != nil {
+		failf(token.Position{}, "%v", verificationError)
+	}
+	return types
+}
+
+// machineName returns name of machine declared by type
+func machineName(typeName string, markers map[string]string) string {
+	if name, ok := markers[typeName]; ok {
+		return name
+	}
+	return strings.TrimSuffix(typeName, declarationTag)
+}
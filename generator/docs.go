@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// parseDoc splits doc and line comments of state field into description of state
+// and descriptions of its events, written as `Event: description` lines
+func parseDoc(st *stateDefinition) {
+	for _, group := range []*ast.CommentGroup{st.Field.Doc, st.Field.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, line := range strings.Split(group.Text(), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if colon := strings.Index(line, ":"); colon > 0 {
+				ev := event(line[:colon])
+				if tr, ok := st.Events[ev]; ok {
+					if text := strings.TrimSpace(line[colon+1:]); text != "" {
+						tr.Doc = append(tr.Doc, text)
+						st.Events[ev] = tr
+					}
+					continue
+				}
+			}
+			st.Doc = append(st.Doc, line)
+		}
+	}
+}
+
+// tooltipEscaper escapes doc for Graphviz string. Backticks are replaced,
+// because description is embedded into generated code as raw string literal.
+var tooltipEscaper = strings.NewReplacer("`", "'", `\`, `\\`, `"`, `\"`)
+
+// tooltip returns doc as value of Graphviz tooltip attribute
+func tooltip(doc []string) string {
+	return `"` + tooltipEscaper.Replace(strings.Join(doc, " ")) + `"`
+}
+
+// describeNode declares node of leaf state, terminal states are drawn as squares
+func describeNode(builder *strings.Builder, indent string, st stateDefinition) {
+	var attributes []string
+	if st.IsTerminal {
+		attributes = append(attributes, "shape=Msquare")
+	}
+	if len(st.Doc) > 0 {
+		attributes = append(attributes, "tooltip="+tooltip(st.Doc))
+	}
+	builder.WriteString(indent)
+	builder.WriteString(string(st.Name))
+	if len(attributes) > 0 {
+		builder.WriteString(" [")
+		builder.WriteString(strings.Join(attributes, ", "))
+		builder.WriteString("]")
+	}
+	builder.WriteString(";\n")
+}
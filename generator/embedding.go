@@ -0,0 +1,257 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// prefixOption prefixes names of states spliced from embedded declaration like `fsm:"prefix=Upload"`
+const prefixOption = "prefix="
+
+// parseEmbedding splices states of machine declaration embedded into struct as composite state
+// named after embedded machine. Events in tag of embedded field wire terminal states of embedded machine
+// to states of outer machine, like `Delivered:"Uploaded"`, so transitions to wired terminal state lead
+// to outer state instead. Spliced states reuse event types and behaviour interfaces generated for embedded machine,
+// so it has to be generated in the same package.
+func parseEmbedding(
+	fset *token.FileSet, pkg *ast.Package, field *ast.Field, parent state,
+	states map[state]stateDefinition, wildcards map[state]stateDefinition, visiting map[*ast.StructType]bool,
+) stateDefinition {
+	ident, ok := field.Type.(*ast.Ident)
+	if !ok {
+		failf(fset.Position(field.Pos()), "embedded declaration should be declared in the same package")
+	}
+	markers := collectMarkers(fset, map[string]*ast.Package{pkg.Name: pkg})
+	if _, ok := markers[ident.Name]; !ok {
+		if err := verifySpecifiedTypes([]string{ident.Name}); err != nil {
+			failf(fset.Position(field.Pos()), "embedded type `%s` is not a machine declaration: %v", ident.Name, err)
+		}
+	}
+	nested := nestedDeclaration(pkg, field)
+	if nested == nil {
+		failf(fset.Position(field.Pos()), "embedded declaration `%s` should be struct type with states", ident.Name)
+	}
+	if visiting[nested] {
+		failf(fset.Position(field.Pos()), "embedded declaration `%s` recursively contains itself", ident.Name)
+	}
+	embedded := machineName(ident.Name, markers)
+
+	var pairs []tagPair
+	if field.Tag != nil {
+		var err error
+		pairs, err = parseTag(field.Tag.Value)
+		if err != nil {
+			failf(fset.Position(field.Tag.Pos()), "unsupported tag format on embedded `%s`: %v", ident.Name, err)
+		}
+	}
+	var prefix string
+	var options []string
+	var wiring []tagPair
+	for _, pair := range pairs {
+		switch {
+		case pair.Key == optionsTag:
+			for _, option := range strings.Split(pair.Value, ",") {
+				option = strings.TrimSpace(option)
+				if strings.HasPrefix(option, prefixOption) {
+					prefix = strings.TrimPrefix(option, prefixOption)
+					continue
+				}
+				options = append(options, option)
+			}
+		case strings.HasPrefix(pair.Key, attributePrefix):
+			failf(fset.Position(field.Tag.Pos()), "embedded `%s` can't have attributes", ident.Name)
+		default:
+			wiring = append(wiring, pair)
+		}
+	}
+	if prefix != "" && !isIdentifier(prefix) {
+		failf(fset.Position(field.Tag.Pos()), "prefix `%s` of embedded `%s` is not a valid identifier", prefix, ident.Name)
+	}
+	st := stateDefinition{
+		Name:        state(prefix + embedded),
+		Origin:      state(prefix + embedded),
+		StateField:  "state",
+		Field:       field,
+		Parent:      parent,
+		IsComposite: true,
+	}
+	if len(options) > 0 {
+		parseStateOptions(&st, fset, field.Tag, strings.Join(options, ","))
+	}
+	parseDoc(&st)
+	if st.IsRegion || st.IsAuto {
+		failf(fset.Position(field.Pos()), "embedded `%s` can't be region or automatic state", ident.Name)
+	}
+
+	inner := map[state]stateDefinition{}
+	innerWildcards := map[state]stateDefinition{}
+	children, initial := parseDeclaration(fset, pkg, nested, "", inner, innerWildcards, visiting)
+	if initial == "" {
+		failf(fset.Position(field.Pos()), "embedded machine %s should have initial state", embedded)
+	}
+	rename := func(s state) state {
+		if _, ok := inner[s]; ok {
+			return state(prefix) + s
+		}
+		return s
+	}
+	targets := map[state]state{}
+	for _, pair := range wiring {
+		terminal, ok := inner[state(pair.Key)]
+		if !ok || !terminal.IsTerminal || terminal.IsComposite {
+			failf(fset.Position(field.Tag.Pos()), "`%s` is not a terminal state of embedded machine %s", pair.Key, embedded)
+		}
+		if terminal.IsInitial {
+			failf(fset.Position(field.Tag.Pos()), "initial state `%s` of embedded machine %s can't be wired", pair.Key, embedded)
+		}
+		if !isIdentifier(pair.Value) {
+			failf(fset.Position(field.Tag.Pos()), "terminal state `%s` of embedded machine %s should be wired to state, got `%s`", pair.Key, embedded, pair.Value)
+		}
+		targets[terminal.Name] = state(pair.Value)
+	}
+	redirect := func(s state) state {
+		if target, ok := targets[s]; ok {
+			return target
+		}
+		return rename(s)
+	}
+	splice := func(list []state) []state {
+		var result []state
+		for _, s := range list {
+			if _, ok := targets[s]; !ok {
+				result = append(result, rename(s))
+			}
+		}
+		return result
+	}
+	redirectEvents := func(spliced *stateDefinition) {
+		events := map[event]transition{}
+		destinations := map[state][]event{}
+		for ev, tr := range spliced.Events {
+			tr.Destination = redirect(tr.Destination)
+			events[ev] = tr
+			destinations[tr.Destination] = append(destinations[tr.Destination], ev)
+		}
+		spliced.Events, spliced.Destinations = events, destinations
+	}
+
+	for _, name := range sortedStates(inner) {
+		if _, ok := targets[name]; ok {
+			continue
+		}
+		spliced := inner[name]
+		if spliced.IsRegion {
+			failf(fset.Position(field.Pos()), "embedded machine %s can't have regions", embedded)
+		}
+		if spliced.Embedded == "" {
+			spliced.Embedded = embedded
+		}
+		spliced.Name = rename(name)
+		spliced.Parent = rename(spliced.Parent)
+		if spliced.Parent == "" {
+			spliced.Parent = st.Name
+		}
+		spliced.Children = splice(spliced.Children)
+		spliced.InitialChild = rename(spliced.InitialChild)
+		redirectEvents(&spliced)
+		if _, ok := states[spliced.Name]; ok {
+			failf(
+				fset.Position(field.Pos()),
+				"state `%s` of embedded machine %s is already declared, use `%s` option to rename spliced states",
+				spliced.Name, embedded, prefixOption,
+			)
+		}
+		states[spliced.Name] = spliced
+	}
+	for _, scope := range sortedStates(innerWildcards) {
+		wildcard := innerWildcards[scope]
+		if wildcard.Embedded == "" {
+			wildcard.Embedded = embedded
+		}
+		redirectEvents(&wildcard)
+		if scope == "" {
+			wildcards[st.Name] = wildcard
+			continue
+		}
+		wildcards[rename(scope)] = wildcard
+	}
+	st.Children = splice(children)
+	st.InitialChild = rename(initial)
+	return st
+}
+
+// verifyEmbeddings checks that states spliced from embedded machine don't inherit events of outer machine,
+// because their event types are generated for embedded machine
+func verifyEmbeddings(fset *token.FileSet, definition machineDefinition) {
+	for _, name := range sortedStates(definition.States) {
+		st := definition.States[name]
+		if st.Embedded == "" || st.IsComposite {
+			continue
+		}
+		for s := st.Parent; s != ""; s = definition.States[s].Parent {
+			parent := definition.States[s]
+			if parent.Embedded != st.Embedded && len(parent.Events) > 0 {
+				failf(
+					fset.Position(parent.Field.Pos()),
+					"states of embedded machine %s can't inherit events of `%s`, because they reuse event types of %s",
+					st.Embedded, parent.Name, st.Embedded,
+				)
+			}
+		}
+	}
+}
+
+// verifyEmbeddedMachines checks that machines embedded into definition are generated in the same run
+// or were already generated into the package, since spliced states refer to their event types and constants
+func verifyEmbeddedMachines(fset *token.FileSet, pkg *ast.Package, definition machineDefinition, ns *namespace) {
+	files := map[string]bool{}
+	for fileName := range pkg.Files {
+		files[filepath.Base(fileName)] = true
+	}
+	for _, name := range sortedStates(definition.States) {
+		st := definition.States[name]
+		parent := definition.States[st.Parent]
+		if st.Embedded == "" || parent.Embedded == st.Embedded || ns.machines[st.Embedded] || files[outputFileName(st.Embedded)] {
+			continue
+		}
+		failf(
+			fset.Position(parent.Field.Pos()),
+			"embedded machine %s isn't generated in package of %s, generate it together with %s",
+			st.Embedded, definition.MachineName, definition.MachineName,
+		)
+	}
+}
+
+// embeddedPrefixes returns prefixes of constants generated for embedded machines, so spliced states refer to them
+func embeddedPrefixes(fset *token.FileSet, definition machineDefinition, prefix string) map[string]string {
+	result := map[string]string{}
+	for _, st := range definition.States {
+		if _, ok := result[st.Embedded]; st.Embedded == "" || ok {
+			continue
+		}
+		embedded := machineDefinition{
+			DirName:     definition.DirName,
+			PkgName:     definition.PkgName,
+			MachineName: st.Embedded,
+			Struct:      definition.Struct,
+		}
+		result[st.Embedded] = machinePrefix(fset, embedded, prefix)
+	}
+	return result
+}
+
+// InterfacePrefix returns name that per-state event type and behaviour interfaces of state start with.
+// States spliced from embedded machine reuse the ones generated for that machine.
+func (s stateDefinition) InterfacePrefix(machineName string) string {
+	if s.Embedded != "" {
+		return s.Embedded + string(s.Origin)
+	}
+	return machineName + string(s.Name)
+}
+
+// OriginOf returns the name behaviour methods and event constants of state are generated with
+func (m machineDefinition) OriginOf(s state) state {
+	return m.States[s].Origin
+}
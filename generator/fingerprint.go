@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"strings"
+)
+
+// fingerprintMarker is the comment of generated file that records declaration it was generated from,
+// like `//fsm:fingerprint CBMDeclaration 9f86d081884c7d65`
+const fingerprintMarker = "//fsm:fingerprint"
+
+// declarationFingerprint returns hash of declaration type of machine together with its comments
+// and declarations of composite and embedded states it refers to
+func declarationFingerprint(fset *token.FileSet, pkg *ast.Package, definition machineDefinition) string {
+	structs := map[*ast.StructType]bool{definition.Struct: true}
+	for _, st := range definition.States {
+		if !st.IsComposite {
+			continue
+		}
+		if nested := nestedDeclaration(pkg, st.Field); nested != nil {
+			structs[nested] = true
+		}
+	}
+	hash := sha256.New()
+	for _, fileName := range sortedFileNames(pkg) {
+		file := pkg.Files[fileName]
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE || !declaresStruct(genDecl, structs) {
+				continue
+			}
+			err := printer.Fprint(hash, fset, &printer.CommentedNode{Node: genDecl, Comments: file.Comments})
+			if err != nil {
+				failf(fset.Position(genDecl.Pos()), "can't print declaration: %v", err)
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+func declaresStruct(genDecl *ast.GenDecl, structs map[*ast.StructType]bool) bool {
+	for _, spec := range genDecl.Specs {
+		if structType, ok := spec.(*ast.TypeSpec).Type.(*ast.StructType); ok && structs[structType] {
+			return true
+		}
+	}
+	return false
+}
+
+// readFingerprint returns declaration type and its fingerprint recorded in generated file
+func readFingerprint(content []byte) (string, string, bool) {
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, fingerprintMarker+" ") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, fingerprintMarker))
+		if len(fields) != 2 {
+			return "", "", false
+		}
+		return fields[0], fields[1], true
+	}
+	return "", "", false
+}
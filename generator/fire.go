@@ -0,0 +1,36 @@
+package generator
+
+import "sort"
+
+// machineEvent is event of any state of the machine that can be fired from outside
+type machineEvent struct {
+	Name         event
+	Payload      string
+	PayloadField string
+	// StateField is the name of machine field that holds current state of the region event belongs to
+	StateField string
+	// Value is numeric value of event constant, when machine has explicit values
+	Value int
+}
+
+// collectEvents returns events of all leaf states in order of their first appearance or ordered by name.
+// Timed events are taken only by Operate, so they can't be fired.
+func collectEvents(definition machineDefinition, alphabetical bool) []machineEvent {
+	var result []machineEvent
+	seen := map[event]bool{}
+	for _, st := range definition.OrderedLeaves() {
+		for _, tr := range st.OrderedTransitions() {
+			if seen[tr.Event] || tr.After > 0 {
+				continue
+			}
+			seen[tr.Event] = true
+			result = append(result, machineEvent{Name: tr.Event, Payload: tr.Payload, PayloadField: tr.PayloadField, StateField: st.StateField})
+		}
+	}
+	if alphabetical {
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].Name < result[j].Name
+		})
+	}
+	return result
+}
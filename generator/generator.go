@@ -11,20 +11,104 @@ import (
 	"log"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const declarationTag = "Declaration"
 
+// optionsTag is the field tag key reserved for state options like `fsm:"initial"`
+const optionsTag = "fsm"
+
+const (
+	initialOption = "initial"
+	entryOption   = "entry"
+	exitOption    = "exit"
+	regionOption  = "region"
+	autoOption    = "auto"
+)
+
 type event string
 type state string
 
+type transition struct {
+	Event       event
+	Destination state
+	Guard       string
+	// Pos is the position of event inside of declaring tag, it orders transitions as they are declared
+	Pos token.Pos
+	// Value is numeric value of event constant, when machine has explicit values
+	Value int
+	// Command is the output transition produces for the caller
+	Command string
+	// History is the history marker of composite Destination, if transition resumes it
+	History string
+	// After is the delay of timed transition, that is taken automatically
+	After time.Duration
+	// Payload is Go type of data event carries and PayloadField is the event field that holds it
+	Payload      string
+	PayloadField string
+	// Doc describes transition, it comes from `Event: description` line of state comment
+	Doc []string
+
+	// Leaf is the state machine ends up in, when Destination is composite
+	Leaf state
+	// ExitActions and EntryActions are states which actions are executed during transition
+	ExitActions  []state
+	EntryActions []state
+	// HistoryRecords remember sub-states of composite states left during transition
+	HistoryRecords []historyRecord
+	// StartedTimers and StoppedTimers are fields with entry time of states with timers
+	// entered and left during transition
+	StartedTimers []string
+	StoppedTimers []string
+	// RestoredHistory is the history transition resumes
+	RestoredHistory *history
+}
+
 type stateDefinition struct {
 	Name         state
-	Events       map[event]state
+	Events       map[event]transition
 	Destinations map[state][]event
+	// Transitions are own and inherited from parent states events of leaf state
+	Transitions  map[event]transition
+	Guards       []string
+	Timers       []timer
+	HasPayloads  bool
+	Listeners    []listener
 	IsTerminal   bool
-	Field        *ast.Field
+	IsInitial    bool
+	HasEntry     bool
+	HasExit      bool
+	IsAuto       bool
+	Attributes   map[string]attributeValue
+	Parent       state
+	IsComposite  bool
+	Children     []state
+	InitialChild state
+	IsRegion     bool
+	// Region is the name of orthogonal region state belongs to
+	Region string
+	// StateField is the name of machine field that holds current state of the region
+	StateField string
+	Field      *ast.Field
+	// Value is numeric value of state constant pinned in declaration or assigned by generator
+	// and NoopValue is value of its Noop event, when machine has explicit values
+	Value     int
+	NoopValue int
+	// EventValues are values of events pinned in declaration of the state, including Noop.
+	// Sub-states inherit them unless they pin their own.
+	EventValues map[event]int
+	// EventOrder and TransitionOrder are events and transitions in generation order
+	EventOrder      []event
+	TransitionOrder []event
+	// Origin is the name state has in declaration of the machine it is spliced from and Embedded is that machine.
+	// States of embedded machine reuse its event types and behaviour interfaces.
+	Origin   state
+	Embedded string
+	// Doc is the comment of state field without descriptions of events
+	Doc []string
 }
 
 type machineDefinition struct {
@@ -32,25 +116,187 @@ type machineDefinition struct {
 	PkgName     string
 	MachineName string
 	States      map[state]stateDefinition
+	TopLevel    []state
+	Regions     []region
+	Histories   []history
+	Events      []machineEvent
+	Initial     state
+	HasGuards   bool
+	HasActions  bool
+	HasTimers   bool
+	HasAuto     bool
+	HasPayloads bool
+	Commands    []string
+	Attributes  []attribute
+	// PayloadImports are import specs of packages payload types refer to
+	PayloadImports []string
+	// HasValues reports whether constants of states and events have explicit values instead of iota
+	HasValues   bool
 	Description string
 	Struct      *ast.StructType
+	// Declaration is the name of declaration type and Fingerprint is the hash of declaration
+	// recorded in generated file, so stale file tells which declaration changed
+	Declaration string
+	Fingerprint string
+	// StateOrder is all states in generation order
+	StateOrder []state
+	// Prefix namespaces generated state and event constants
+	Prefix string
+	// EmbeddedPrefixes are prefixes of event constants generated for embedded machines
+	EmbeddedPrefixes map[string]string
+	// Properties are asserted by `//fsm:assert` comments of declaration type
+	Properties []property
+}
+
+// NeedsOperator reports whether transition handlers call behaviour
+func (m machineDefinition) NeedsOperator() bool {
+	return m.HasGuards || m.HasActions || m.HasPayloads
+}
+
+// HasCommands reports whether transitions of machine produce commands
+func (m machineDefinition) HasCommands() bool {
+	return len(m.Commands) > 0
+}
+
+// HandlerResults returns results of generated transition handlers
+func (m machineDefinition) HandlerResults() string {
+	switch {
+	case m.HasCommands() && m.HasGuards:
+		return " (" + m.MachineName + "Command, error)"
+	case m.HasCommands():
+		return " " + m.MachineName + "Command"
+	case m.HasGuards:
+		return " error"
+	}
+	return ""
+}
+
+// OperateResults returns results of generated Operate method
+func (m machineDefinition) OperateResults() string {
+	switch {
+	case m.HasCommands() && m.HasGuards:
+		return " ([]" + m.MachineName + "Command, error)"
+	case m.HasCommands():
+		return " []" + m.MachineName + "Command"
+	case m.HasGuards:
+		return " error"
+	}
+	return ""
+}
+
+// ZeroResults returns zero values of generated transition handler results
+func (m machineDefinition) ZeroResults() string {
+	switch {
+	case m.HasCommands() && m.HasGuards:
+		return " 0, nil"
+	case m.HasCommands():
+		return " 0"
+	case m.HasGuards:
+		return " nil"
+	}
+	return ""
+}
+
+// HasHierarchy reports whether machine has composite states
+func (m machineDefinition) HasHierarchy() bool {
+	for _, st := range m.States {
+		if st.IsComposite {
+			return true
+		}
+	}
+	return false
+}
+
+// Imports returns import specs of packages used by generated code
+func (m machineDefinition) Imports() []string {
+	imports := []string{`"fmt"`}
+	if len(m.Regions) > 0 || len(m.Histories) > 0 {
+		imports = append(imports, `"strings"`)
+	}
+	if m.HasTimers {
+		imports = append(imports, `"time"`)
+	}
+	for _, spec := range m.PayloadImports {
+		if !contains(imports, spec) {
+			imports = append(imports, spec)
+		}
+	}
+	return imports
+}
+
+// Leaves returns states machine can actually be in
+func (m machineDefinition) Leaves() map[state]stateDefinition {
+	result := map[state]stateDefinition{}
+	for name, st := range m.States {
+		if !st.IsComposite {
+			result[name] = st
+		}
+	}
+	return result
+}
+
+// InitialLeaf returns state machine starts from
+func (m machineDefinition) InitialLeaf() state {
+	return m.leafOf(m.Initial)
+}
+
+// Options configure generation
+type Options struct {
+	// Verbose prints description of generated machines
+	Verbose bool
+	// Alphabetical orders states and events in generated code by name instead of declaration order
+	Alphabetical bool
+	// Prefix is template of prefix for generated state and event constants, like `{{.MachineName}}`.
+	// It lets machines with the same states live in one package.
+	Prefix string
+	// Lock records numeric values of states and events in `<machine>.fsm.lock` file next to generated one,
+	// so they are never renumbered. Machines that already have lock file use it even without this option.
+	Lock bool
+	// Strict turns problems found by analysis of transition graph, like unreachable states, into errors
+	Strict bool
 }
 
 func RunGeneratorForTypes(dirName string, types []string, verbose bool) {
-	verificationError := verifySpecifiedTypes(types)
-	if verificationError != nil {
-		log.Fatal(verificationError.Error())
+	RunGenerator(dirName, types, Options{Verbose: verbose})
+}
+
+// RunGenerator generates state machines for specified declaration types found in dirName.
+// If no types are specified, machines are generated for all types marked with `//fsm:machine` comment.
+func RunGenerator(dirName string, types []string, options Options) {
+	fset, pkgs := parseDir(dirName)
+	err := catchDeclarationError(func() {
+		forEachMachine(fset, pkgs, types, func(ns *namespace, machineName string, pkg *ast.Package, obj *ast.Object) {
+			generateStm(options, ns, machineName, dirName, pkg, fset, obj)
+		})
+	})
+	if err != nil {
+		log.Fatal(err.Error())
 	}
+}
 
+func parseDir(dirName string) (*token.FileSet, map[string]*ast.Package) {
 	fset := token.NewFileSet()
-	pkgs, err := parser.ParseDir(fset, dirName, nil, parser.SpuriousErrors)
+	pkgs, err := parser.ParseDir(fset, dirName, nil, parser.SpuriousErrors|parser.ParseComments)
 	if err != nil {
 		log.Fatal("can't parse destination dir ", err)
 	}
-	scan(pkgs, types, func(pkg *ast.Package, foundType string, obj *ast.Object) {
+	return fset, pkgs
+}
 
-		machineName := strings.TrimSuffix(foundType, declarationTag)
-		generateStm(verbose, machineName, dirName, pkg.Name, fset, obj)
+// forEachMachine applies function to every machine declared by specified types or marked with `//fsm:machine` comment
+func forEachMachine(
+	fset *token.FileSet, pkgs map[string]*ast.Package, types []string,
+	apply func(ns *namespace, machineName string, pkg *ast.Package, obj *ast.Object),
+) {
+	markers := collectMarkers(fset, pkgs)
+	types = machineTypes(types, markers)
+	var machines []string
+	for _, t := range types {
+		machines = append(machines, machineName(t, markers))
+	}
+	ns := newNamespace(machines)
+	scan(pkgs, types, func(pkg *ast.Package, foundType string, obj *ast.Object) {
+		apply(ns, machineName(foundType, markers), pkg, obj)
 	})
 }
 
@@ -63,45 +309,98 @@ func verifySpecifiedTypes(types []string) error {
 	return nil
 }
 
-func generateStm(verbose bool, machineName string, dirName string, pkgName string, fset *token.FileSet, obj *ast.Object) {
+func generateStm(options Options, ns *namespace, machineName string, dirName string, pkg *ast.Package, fset *token.FileSet, obj *ast.Object) {
+	definition := parseDefinition(options, machineName, dirName, pkg, fset, obj)
+	reportDiagnostics(definition, analyzeDefinition(fset, definition, options.Strict))
+	reportDiagnostics(definition, checkProperties(fset, definition))
+	lockPath := lockFilePath(definition)
+	lock, locked := readLockFile(lockPath, options.Lock)
+	src := generateSource(options, ns, pkg, fset, &definition, lock, locked)
+	writeToFile(definition, src)
+	if locked {
+		writeLockFile(lockPath, definition, lock)
+	}
+	if options.Verbose {
+		fmt.Println(strip(definition.Description))
+	}
+}
+
+// generateSource assigns values to states and events of parsed machine and generates its source
+// with identifiers verified against the package
+func generateSource(options Options, ns *namespace, pkg *ast.Package, fset *token.FileSet, definition *machineDefinition, lock lockFile, locked bool) []byte {
+	assignStateAndEventValues(fset, definition, lock, locked)
+	definition.Prefix = machinePrefix(fset, *definition, options.Prefix)
+	definition.EmbeddedPrefixes = embeddedPrefixes(fset, *definition, options.Prefix)
+	verifyEmbeddedMachines(fset, pkg, *definition, ns)
+	definition.Description = describeGeneratedMachine(*definition)
+	definition.Fingerprint = declarationFingerprint(fset, pkg, *definition)
+	src := generateFromTemplate(fset, *definition)
+	verifyIdentifiers(fset, pkg, *definition, src, ns)
+	return src
+}
+
+// parseDefinition parses and verifies declaration of machine
+func parseDefinition(options Options, machineName string, dirName string, pkg *ast.Package, fset *token.FileSet, obj *ast.Object) machineDefinition {
 	structType := extractStructTypeFromDefinition(fset, obj)
 	states := map[state]stateDefinition{}
-	for _, field := range structType.Fields.List {
-		verifyField(fset, field)
-		st := stateDefinition{
-			Name:       state(field.Names[0].Name),
-			IsTerminal: field.Tag == nil || field.Tag.Value == "",
-			Field:      field,
-		}
-		st.Events, st.Destinations = parseStateMachineEventsAndDestinations(st, fset, field.Tag)
-		states[st.Name] = st
-	}
+	wildcards := map[state]stateDefinition{}
+	topLevel, initial := parseDeclaration(fset, pkg, structType, "", states, wildcards, map[*ast.StructType]bool{})
 	definition := machineDefinition{
 		DirName:     dirName,
-		PkgName:     pkgName,
+		PkgName:     pkg.Name,
 		MachineName: machineName,
+		Declaration: obj.Name,
 		States:      states,
+		TopLevel:    topLevel,
+		Initial:     initial,
 		Struct:      structType,
 	}
-	verifyDefinition(fset, definition)
-	definition.Description = describeGeneratedMachine(definition)
-	generateFromTemplateAndWriteToFile(definition)
-	if verbose {
-		fmt.Println(strip(definition.Description))
+	definition.Regions, definition.TopLevel = collectRegions(fset, definition)
+	var errs declarationErrors
+	errs.verify(func() { verifyDefinition(fset, definition) })
+	errs.verify(func() { verifyEmbeddings(fset, definition) })
+	errs.verify(func() { definition.PayloadImports = verifyPayloads(fset, pkg, definition, wildcards) })
+	errs.fail()
+	resolveTransitions(definition)
+	expandWildcards(fset, definition, wildcards)
+	definition.Histories = collectHistories(fset, definition)
+	resolveTransitions(definition)
+	errs.verify(func() { verifyTerminalStates(fset, definition) })
+	errs.verify(func() { verifyAutoStates(fset, definition) })
+	collectListeners(definition)
+	errs.verify(func() { verifyGuards(fset, definition) })
+	errs.verify(func() { verifyEventValues(fset, definition) })
+	errs.fail()
+	orderDefinition(&definition, options.Alphabetical)
+	definition.Events = collectEvents(definition, options.Alphabetical)
+	definition.Commands = collectCommands(definition, options.Alphabetical)
+	definition.Attributes = collectAttributes(fset, definition)
+	for _, st := range states {
+		definition.HasGuards = definition.HasGuards || len(st.Guards) > 0
+		definition.HasActions = definition.HasActions || st.HasEntry || st.HasExit
+		definition.HasTimers = definition.HasTimers || len(st.Timers) > 0
+		definition.HasAuto = definition.HasAuto || st.IsAuto
+		definition.HasPayloads = definition.HasPayloads || st.HasPayloads
 	}
+	definition.Properties = parseProperties(fset, pkg, definition)
+	return definition
 }
 
-func generateFromTemplateAndWriteToFile(definition machineDefinition) {
+func generateFromTemplate(fset *token.FileSet, definition machineDefinition) []byte {
 	var b bytes.Buffer
 	err := embeddedTemplate.Execute(&b, definition)
 	if err != nil {
-		log.Fatal("can't execute template ", err)
+		failf(fset.Position(definition.Struct.Pos()), "can't execute template for machine %s: %v", definition.MachineName, err)
 	}
 	src, err := format.Source(b.Bytes())
 	if err != nil {
-		log.Fatal("can't format generated template ", err)
+		failf(fset.Position(definition.Struct.Pos()), "can't format source generated for machine %s: %v", definition.MachineName, err)
 	}
-	output := strings.ToLower(definition.MachineName + ".fsm.go")
+	return src
+}
+
+func writeToFile(definition machineDefinition, src []byte) {
+	output := outputFileName(definition.MachineName)
 	absPath, err := filepath.Abs(definition.DirName)
 	if err != nil {
 		log.Fatal("can't calculate abs path for: "+definition.DirName, err)
@@ -123,25 +422,43 @@ func describeGeneratedMachine(definition machineDefinition) string {
 	builder.WriteString("digraph ")
 	builder.WriteString(definition.MachineName)
 	builder.WriteString(" {\n")
+	if definition.HasHierarchy() && len(definition.Regions) == 0 {
+		builder.WriteString("	compound=true;\n")
+	}
+
+	if len(definition.Regions) > 0 {
+		builder.WriteString("	compound=true;\n")
+		describeRegions(builder, definition)
+	}
+
+	if definition.Initial != "" {
+		builder.WriteString("	__initial [shape=point];\n")
+		builder.WriteString("	__initial -> ")
+		builder.WriteString(string(definition.InitialLeaf()))
+		if definition.States[definition.Initial].IsComposite {
+			builder.WriteString(" [lhead=cluster_")
+			builder.WriteString(string(definition.Initial))
+			builder.WriteString("]")
+		}
+		builder.WriteString(";\n")
+	}
+
+	if definition.HasHierarchy() && len(definition.Regions) == 0 {
+		describeClusters(builder, definition, definition.TopLevel, "	")
+	}
 
-	for _, state := range sortedStates(definition.States) {
+	for _, state := range definition.StateOrder {
 		stateDef := definition.States[state]
+		flat := !definition.HasHierarchy() && len(definition.Regions) == 0
+		if flat && (stateDef.IsTerminal || len(stateDef.Doc) > 0) {
+			describeNode(builder, "	", stateDef)
+		}
 		if stateDef.IsTerminal {
-			builder.WriteString("	")
-			builder.WriteString(string(state))
-			builder.WriteString(" [shape=Msquare];\n")
 			continue
 		}
 
-		for _, ev := range sortedEvents(stateDef.Events) {
-			dst := stateDef.Events[ev]
-			builder.WriteString("	")
-			builder.WriteString(string(state))
-			builder.WriteString(" -> ")
-			builder.WriteString(string(dst))
-			builder.WriteString(" [label=")
-			builder.WriteString(string(ev))
-			builder.WriteString("];\n")
+		for _, ev := range stateDef.EventOrder {
+			describeTransition(builder, definition, state, stateDef.Events[ev])
 		}
 	}
 	builder.WriteString("}\n`")
@@ -149,42 +466,335 @@ func describeGeneratedMachine(definition machineDefinition) string {
 	return builder.String()
 }
 
-func parseStateMachineEventsAndDestinations(st stateDefinition, fset *token.FileSet, tag *ast.BasicLit) (map[event]state, map[state][]event) {
+// describeClusters declares nodes of composite states inside of their clusters
+func describeClusters(builder *strings.Builder, definition machineDefinition, states []state, indent string) {
+	for _, st := range states {
+		stateDef := definition.States[st]
+		if !stateDef.IsComposite {
+			describeNode(builder, indent, stateDef)
+			continue
+		}
+		builder.WriteString(indent)
+		builder.WriteString("subgraph cluster_")
+		builder.WriteString(string(st))
+		builder.WriteString(" {\n")
+		builder.WriteString(indent)
+		builder.WriteString("	label=")
+		builder.WriteString(string(st))
+		builder.WriteString(";\n")
+		if len(stateDef.Doc) > 0 {
+			builder.WriteString(indent)
+			builder.WriteString("	tooltip=")
+			builder.WriteString(tooltip(stateDef.Doc))
+			builder.WriteString(";\n")
+		}
+		for _, h := range definition.Histories {
+			if h.Composite == st {
+				builder.WriteString(indent)
+				builder.WriteString("	")
+				builder.WriteString(historyNode(h.Composite, h.Deep))
+				builder.WriteString(` [shape=circle, label="`)
+				builder.WriteString(strings.TrimPrefix(h.Name, string(st)+"."))
+				builder.WriteString(`"];`)
+				builder.WriteString("\n")
+			}
+		}
+		describeClusters(builder, definition, stateDef.Children, indent+"	")
+		builder.WriteString(indent)
+		builder.WriteString("}\n")
+	}
+}
+
+func describeTransition(builder *strings.Builder, definition machineDefinition, from state, tr transition) {
+	builder.WriteString("	")
+	builder.WriteString(string(definition.leafOf(from)))
+	builder.WriteString(" -> ")
+	if tr.History != "" {
+		builder.WriteString(historyNode(tr.Destination, tr.History == deepHistory))
+	} else {
+		builder.WriteString(string(definition.leafOf(tr.Destination)))
+	}
+	builder.WriteString(" [label=")
+	if tr.Guard != "" || tr.Command != "" {
+		builder.WriteString(`"`)
+		builder.WriteString(string(tr.Event))
+		if tr.Guard != "" {
+			builder.WriteString(" [")
+			builder.WriteString(tr.Guard)
+			builder.WriteString("]")
+		}
+		if tr.Command != "" {
+			builder.WriteString(" / ")
+			builder.WriteString(tr.Command)
+		}
+		builder.WriteString(`"`)
+	} else {
+		builder.WriteString(string(tr.Event))
+	}
+	if definition.States[from].IsComposite {
+		builder.WriteString(", ltail=cluster_")
+		builder.WriteString(string(from))
+	}
+	if definition.States[tr.Destination].IsComposite && tr.History == "" {
+		builder.WriteString(", lhead=cluster_")
+		builder.WriteString(string(tr.Destination))
+	}
+	if len(tr.Doc) > 0 {
+		builder.WriteString(", tooltip=")
+		builder.WriteString(tooltip(tr.Doc))
+	}
+	builder.WriteString("];\n")
+}
+
+func parseStateDefinition(fset *token.FileSet, field *ast.Field) stateDefinition {
+	st := stateDefinition{
+		Name:       state(field.Names[0].Name),
+		Origin:     state(field.Names[0].Name),
+		StateField: "state",
+		Field:      field,
+	}
+	var pairs []tagPair
+	if field.Tag != nil {
+		var err error
+		pairs, err = parseTag(field.Tag.Value)
+		if err != nil {
+			failf(fset.Position(field.Tag.Pos()), "unsupported tag format on state `%s`: %v", st.Name, err)
+		}
+	}
+	var eventPairs []tagPair
+	for _, pair := range pairs {
+		if pair.Key == optionsTag {
+			parseStateOptions(&st, fset, field.Tag, pair.Value)
+			continue
+		}
+		if strings.HasPrefix(pair.Key, attributePrefix) {
+			name, value, err := parseAttribute(pair.Key, pair.Value)
+			if err != nil {
+				failf(fset.Position(field.Tag.Pos()), "unsupported tag format on state `%s`: %v", st.Name, err)
+			}
+			if _, ok := st.Attributes[name]; ok {
+				failf(fset.Position(field.Tag.Pos()), "attribute `%s` duplicate on state `%s`", name, st.Name)
+			}
+			if st.Attributes == nil {
+				st.Attributes = map[string]attributeValue{}
+			}
+			st.Attributes[name] = value
+			continue
+		}
+		eventPairs = append(eventPairs, pair)
+	}
+	st.IsTerminal = len(eventPairs) == 0
+	st.Events, st.Destinations = parseStateMachineEventsAndDestinations(st, fset, field.Tag, eventPairs)
+	parseDoc(&st)
+	return st
+}
+
+func parseStateOptions(st *stateDefinition, fset *token.FileSet, tag *ast.BasicLit, options string) {
+	for _, option := range strings.Split(options, ",") {
+		option = strings.TrimSpace(option)
+		if strings.HasPrefix(option, eventValueOption) {
+			parts := strings.SplitN(strings.TrimPrefix(option, eventValueOption), "=", 2)
+			if !isIdentifier(parts[0]) {
+				failf(fset.Position(tag.Pos()), "event of pinned value on state `%s` is not a valid identifier, got `%s`", st.Name, option)
+			}
+			value := 0
+			if len(parts) == 2 {
+				value, _ = strconv.Atoi(parts[1])
+			}
+			if value <= 0 {
+				failf(fset.Position(tag.Pos()), "value of event `%s` on state `%s` should be positive integer, got `%s`", parts[0], st.Name, option)
+			}
+			if _, ok := st.EventValues[event(parts[0])]; ok {
+				failf(fset.Position(tag.Pos()), "value of event `%s` pinned twice on state `%s`", parts[0], st.Name)
+			}
+			if st.EventValues == nil {
+				st.EventValues = map[event]int{}
+			}
+			st.EventValues[event(parts[0])] = value
+			continue
+		}
+		if strings.HasPrefix(option, valueOption) {
+			value, err := strconv.Atoi(strings.TrimPrefix(option, valueOption))
+			if err != nil || value <= 0 {
+				failf(fset.Position(tag.Pos()), "value of state `%s` should be positive integer, got `%s`", st.Name, option)
+			}
+			st.Value = value
+			continue
+		}
+		switch option {
+		case initialOption:
+			st.IsInitial = true
+		case entryOption:
+			st.HasEntry = true
+		case exitOption:
+			st.HasExit = true
+		case regionOption:
+			st.IsRegion = true
+		case autoOption:
+			st.IsAuto = true
+		default:
+			failf(fset.Position(tag.Pos()), "unsupported option `%s` on state `%s`", option, st.Name)
+		}
+	}
+}
+
+func parseStateMachineEventsAndDestinations(st stateDefinition, fset *token.FileSet, tag *ast.BasicLit, pairs []tagPair) (map[event]transition, map[state][]event) {
 	if st.IsTerminal {
 		return nil, nil
 	}
-	events := map[event]state{}
+	events := map[event]transition{}
 	destinations := map[state][]event{}
-	eventsDeclarations := strings.Split(strip(tag.Value), ",")
-	for _, eventDeclaration := range eventsDeclarations {
-		eventStr := strings.Split(eventDeclaration, ":")
-		if len(eventStr) != 2 || len(eventStr[0]) < 1 || len(eventStr[1]) < 3 {
-			log.Fatalf("unsuported tag format %+v. %v", eventDeclaration, fset.Position(tag.Pos()))
+	for _, pair := range pairs {
+		ev, payload, err := parseEventKey(pair.Key)
+		if err != nil {
+			failf(fset.Position(tag.Pos()), "unsupported tag format on state `%s`: %v", st.Name, err)
+		}
+		tr, err := parseTransition(ev, pair.Value)
+		if err != nil {
+			failf(fset.Position(tag.Pos()), "unsupported tag format on state `%s`: %v", st.Name, err)
+		}
+		if payload != "" && tr.After > 0 {
+			failf(fset.Position(tag.Pos()), "timed event `%s` on state `%s` can't carry payload", ev, st.Name)
 		}
-		ev := event(eventStr[0])
-		dst := state(strip(eventStr[1]))
+		tr.Pos = tag.Pos() + token.Pos(pair.Offset)
+		tr.Payload = payload
+		if payload != "" {
+			tr.PayloadField = strings.ToLower(string(ev[:1])) + string(ev[1:]) + "Payload"
+		}
+		dst := tr.Destination
 
 		if ev == "Noop" {
-			log.Fatalf("event `Noop` is reserved by system %+v", fset.Position(tag.Pos()))
+			failf(fset.Position(tag.Pos()), "event `Noop` is reserved by system")
 		}
 
 		if _, ok := events[ev]; ok {
-			log.Fatalf("event `%s` duplicate on state `%s`. %v", ev, st.Name, fset.Position(tag.Pos()))
+			failf(fset.Position(tag.Pos()), "event `%s` duplicate on state `%s`", ev, st.Name)
 		}
-		events[ev] = dst
+		events[ev] = tr
 		destinations[dst] = append(destinations[dst], ev)
 	}
 	return events, destinations
 }
 
+func collectCommands(definition machineDefinition, alphabetical bool) []string {
+	var result []string
+	for _, st := range definition.OrderedLeaves() {
+		for _, tr := range st.OrderedTransitions() {
+			if tr.Command != "" && !contains(result, tr.Command) {
+				result = append(result, tr.Command)
+			}
+		}
+	}
+	if alphabetical {
+		sort.Strings(result)
+	}
+	return result
+}
+
+func collectGuards(events map[event]transition) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, ev := range sortedEvents(events) {
+		guard := events[ev].Guard
+		if guard == "" || seen[guard] {
+			continue
+		}
+		seen[guard] = true
+		result = append(result, guard)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// verifyDefinition checks that transitions lead to declared states and that machine,
+// every region and composite state have exactly one initial state.
 func verifyDefinition(fset *token.FileSet, definition machineDefinition) {
 	for _, st := range definition.States {
 		for dst, events := range st.Destinations {
 			_, ok := definition.States[dst]
 			if !ok {
-				log.Fatalf(
-					"You've defined (%v) -%v-> (%v). But there is no such destination state as `%v`. %v",
-					st.Name, events, dst, dst, fset.Position(st.Field.Pos()),
+				failf(
+					fset.Position(st.Field.Pos()),
+					"You've defined (%v) -%v-> (%v). But there is no such destination state as `%v`",
+					st.Name, events, dst, dst,
+				)
+			}
+		}
+	}
+	if len(definition.Regions) == 0 && definition.Initial == "" {
+		failf(fset.Position(definition.Struct.Pos()), "machine %s should have initial state", definition.MachineName)
+	}
+	scopes := [][]state{definition.TopLevel}
+	if len(definition.Regions) > 0 {
+		scopes = nil
+		for _, r := range definition.Regions {
+			scopes = append(scopes, r.States)
+		}
+	}
+	for _, name := range sortedStates(definition.States) {
+		if st := definition.States[name]; st.IsComposite {
+			scopes = append(scopes, st.Children)
+		}
+	}
+	for _, scope := range scopes {
+		var initial []stateDefinition
+		for _, name := range scope {
+			if st := definition.States[name]; st.IsInitial {
+				initial = append(initial, st)
+			}
+		}
+		sort.Slice(initial, func(i, j int) bool {
+			return initial[i].Field.Pos() < initial[j].Field.Pos()
+		})
+		if len(initial) > 1 {
+			failf(
+				fset.Position(initial[1].Field.Pos()),
+				"only one initial state allowed, but both `%s` and `%s` are initial",
+				initial[0].Name, initial[1].Name,
+			)
+		}
+	}
+}
+
+func verifyTerminalStates(fset *token.FileSet, definition machineDefinition) {
+	if definition.Initial != "" && definition.States[definition.InitialLeaf()].IsTerminal {
+		st := definition.States[definition.Initial]
+		failf(fset.Position(st.Field.Pos()), "initial state `%s` can't be terminal", st.Name)
+	}
+	for _, st := range definition.States {
+		if st.IsTerminal && st.HasExit {
+			failf(fset.Position(st.Field.Pos()), "terminal state `%s` can't have exit action", st.Name)
+		}
+	}
+}
+
+// verifyGuards checks that guards don't clash with `Operate*` and `On*` methods generated for states,
+// because guards are methods of the same behaviour
+func verifyGuards(fset *token.FileSet, definition machineDefinition) {
+	methods := map[string]bool{}
+	for _, st := range definition.States {
+		if !st.IsComposite && !st.IsTerminal {
+			methods["Operate"+string(st.Origin)] = true
+		}
+		if st.HasEntry {
+			methods["On"+string(st.Origin)+"Enter"] = true
+		}
+		if st.HasExit {
+			methods["On"+string(st.Origin)+"Exit"] = true
+		}
+		for _, l := range st.Listeners {
+			methods["On"+string(st.Origin)+string(l.Event)] = true
+		}
+	}
+	for _, name := range sortedStates(definition.Leaves()) {
+		st := definition.States[name]
+		for _, ev := range sortedEvents(st.Transitions) {
+			tr := st.Transitions[ev]
+			if tr.Guard != "" && methods[tr.Guard] {
+				failf(
+					fset.Position(tr.Pos),
+					"guard `%s` of event `%s` on state `%s` clashes with generated behaviour method `%s`",
+					tr.Guard, ev, name, tr.Guard,
 				)
 			}
 		}
@@ -193,24 +803,24 @@ func verifyDefinition(fset *token.FileSet, definition machineDefinition) {
 
 func verifyField(fset *token.FileSet, field *ast.Field) {
 	if len(field.Names) != 1 {
-		log.Fatalf("target field names have unexpected len: %+v. %v", field.Names, fset.Position(field.Pos()))
+		failf(fset.Position(field.Pos()), "target field names have unexpected len: %+v", field.Names)
 	}
 }
 
 func extractStructTypeFromDefinition(fset *token.FileSet, obj *ast.Object) *ast.StructType {
 	if obj.Kind != ast.Typ {
-		log.Fatalf("target type kind unsuported %+v. %v", obj, fset.Position(obj.Pos()))
+		failf(fset.Position(obj.Pos()), "target type kind unsuported %+v", obj)
 	}
 	typeSpec, ok := obj.Decl.(*ast.TypeSpec)
 	if !ok {
-		log.Fatalf("target type declaration unsuported %+v. %v", obj.Decl, fset.Position(obj.Pos()))
+		failf(fset.Position(obj.Pos()), "target type declaration unsuported %+v", obj.Decl)
 	}
 	structType, ok := typeSpec.Type.(*ast.StructType)
 	if !ok {
-		log.Fatalf("type specification is not struct type %+v. %v", typeSpec, fset.Position(typeSpec.Pos()))
+		failf(fset.Position(typeSpec.Pos()), "type specification is not struct type %+v", typeSpec)
 	}
 	if structType.Incomplete || structType.Fields == nil || len(structType.Fields.List) == 0 {
-		log.Fatalf("target struct is incoplete or has zero fields %+v. %v", typeSpec, fset.Position(typeSpec.Pos()))
+		failf(fset.Position(typeSpec.Pos()), "target struct is incoplete or has zero fields %+v", typeSpec)
 	}
 	return structType
 }
@@ -230,7 +840,7 @@ func sortedStates(m map[state]stateDefinition) []state {
 	return result
 }
 
-func sortedEvents(m map[event]state) []event {
+func sortedEvents(m map[event]transition) []event {
 	var result []event
 	for key := range m {
 		result = append(result, key)
@@ -258,3 +868,12 @@ func scan(
 		}
 	}
 }
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,20 +3,680 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func TestRunGeneratorForTypes(t *testing.T) {
-	actualTestOutputFile := "./testdata/some.fsm.go"
-	expectedOutputFile := "./testdata/expected/expected.some.fsm.go"
+	dir := copyTestdata(t)
+	defer os.RemoveAll(dir)
+	RunGeneratorForTypes(dir, []string{"SomeDeclaration"}, true)
+	checkFile(t, filepath.Join(dir, "some.fsm.go"), "some.fsm.go")
+}
+
+func TestRunGeneratorWithInitialState(t *testing.T) {
+	checkGeneratedFile(t, "InitialDeclaration", "initial.fsm.go")
+}
+
+func TestRunGeneratorWithGuards(t *testing.T) {
+	checkGeneratedFile(t, "GuardedDeclaration", "guarded.fsm.go")
+}
+
+func TestRunGeneratorWithActions(t *testing.T) {
+	checkGeneratedFile(t, "ActionsDeclaration", "actions.fsm.go")
+}
+
+func TestRunGeneratorWithHierarchy(t *testing.T) {
+	checkGeneratedFile(t, "HierarchyDeclaration", "hierarchy.fsm.go")
+}
+
+func TestRunGeneratorWithRegions(t *testing.T) {
+	checkGeneratedFile(t, "LinkDeclaration", "link.fsm.go")
+}
+
+func TestRunGeneratorWithHistory(t *testing.T) {
+	checkGeneratedFile(t, "ServiceDeclaration", "service.fsm.go")
+}
+
+func TestRunGeneratorWithWildcard(t *testing.T) {
+	checkGeneratedFile(t, "JobDeclaration", "job.fsm.go")
+}
+
+func TestRunGeneratorForTypesWithTimers(t *testing.T) {
+	checkGeneratedFile(t, "RetryDeclaration", "retry.fsm.go")
+}
 
-	err := os.Remove(actualTestOutputFile)
-	if err != nil && err.Error() != fmt.Sprintf("remove %s: no such file or directory", actualTestOutputFile) {
-		t.Errorf("%s file can't be removed: %s", actualTestOutputFile, err.Error())
+func TestRunGeneratorForTypesWithAutoStates(t *testing.T) {
+	checkGeneratedFile(t, "CheckoutDeclaration", "checkout.fsm.go")
+}
+
+func TestRunGeneratorForTypesWithPayloads(t *testing.T) {
+	checkGeneratedFile(t, "TransferDeclaration", "transfer.fsm.go")
+}
+
+func TestRunGeneratorForTypesWithCommands(t *testing.T) {
+	checkGeneratedFile(t, "SessionDeclaration", "session.fsm.go")
+}
+
+func TestRunGeneratorForTypesWithAttributes(t *testing.T) {
+	checkGeneratedFile(t, "DoorDeclaration", "door.fsm.go")
+}
+
+func TestRunGeneratorForTypesWithPinnedValues(t *testing.T) {
+	expected, err := ioutil.ReadFile("./testdata/order.fsm.lock")
+	if err != nil {
+		t.Errorf("can't read lock file: %s", err.Error())
+	}
+	dir := copyTestdata(t)
+	defer os.RemoveAll(dir)
+	RunGenerator(dir, []string{"OrderDeclaration"}, Options{Verbose: true, Alphabetical: true})
+	checkFile(t, filepath.Join(dir, "order.fsm.go"), "order.fsm.go")
+	lockFile := filepath.Join(dir, "order.fsm.lock")
+	actual, err := ioutil.ReadFile(lockFile)
+	if err != nil {
+		t.Errorf("can't read lock file: %s", err.Error())
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("lock file `%s` shouldn't change, actual:\n%s", lockFile, actual)
 	}
-	RunGeneratorForTypes("./testdata", []string{"SomeDeclaration"}, true)
+
+	declarationFile := filepath.Join(dir, "etalon.go")
+	declarations, err := ioutil.ReadFile(declarationFile)
+	if err != nil {
+		t.Fatalf("can't read declarations: %s", err.Error())
+	}
+	renamed := strings.Replace(string(declarations), "Shipped", "Dispatched", -1)
+	if err := ioutil.WriteFile(declarationFile, []byte(renamed), 0664); err != nil {
+		t.Fatalf("can't write declarations: %s", err.Error())
+	}
+	RunGenerator(dir, []string{"OrderDeclaration"}, Options{Alphabetical: true})
+	actual, err = ioutil.ReadFile(lockFile)
+	if err != nil {
+		t.Errorf("can't read lock file: %s", err.Error())
+	}
+	before, err := parseLockFile(expected)
+	if err != nil {
+		t.Fatalf("lock file should be parsed: %s", err.Error())
+	}
+	after, err := parseLockFile(actual)
+	if err != nil {
+		t.Fatalf("lock file should be parsed: %s", err.Error())
+	}
+	for e, values := range before {
+		for name, value := range values {
+			if after[e][name] != value {
+				t.Errorf("%s `%s` should keep value %d after state rename, actual: %d", e.Kind, name, value, after[e][name])
+			}
+		}
+	}
+	for _, line := range []string{"state Shipped 4 reserved\n", "state Dispatched 11\n", "event Shipped.Deliver 1 reserved\n", "event Dispatched.Deliver 1\n"} {
+		if !strings.Contains(string(actual), line) {
+			t.Errorf("lock file should contain {%s}; actual:\n%s", line, actual)
+		}
+	}
+}
+
+func TestAssignValues(t *testing.T) {
+	locked := map[string]int{"Closed": 1, "Removed": 2, "Opened": 3}
+	values, err := assignValues([]string{"Alpha", "Closed", "Exit", "Opened"}, map[string]int{"Exit": 7}, locked)
+	if err != nil {
+		t.Errorf("values should be assigned: %s", err.Error())
+	}
+	expected := map[string]int{"Alpha": 8, "Closed": 1, "Exit": 7, "Opened": 3}
+	if fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Errorf("expected {%v}; actual: {%v}", expected, values)
+	}
+	if locked["Removed"] != 2 || locked["Alpha"] != 8 {
+		t.Errorf("lock should keep removed and record new values: %v", locked)
+	}
+	for _, c := range []struct {
+		pinned      map[string]int
+		expectedErr string
+	}{
+		{map[string]int{"Closed": 4}, "`Closed` is pinned to 4, but lock file records 1. Values can't be renumbered"},
+		{map[string]int{"Exit": 2}, "value 2 of `Exit` is reserved for removed `Removed`"},
+		{map[string]int{"Exit": 3}, "value 3 of `Exit` is already used by `Opened`"},
+	} {
+		locked := map[string]int{"Closed": 1, "Removed": 2, "Opened": 3}
+		_, err := assignValues([]string{"Closed", "Exit", "Opened"}, c.pinned, locked)
+		if err == nil || err.Error() != c.expectedErr {
+			t.Errorf("pinned %v: expected {%s}; actual: {%v}", c.pinned, c.expectedErr, err)
+		}
+	}
+	_, err = assignValues([]string{"Closed", "Exit"}, map[string]int{"Closed": 5, "Exit": 5}, map[string]int{})
+	if err == nil || err.Error() != "value 5 of `Exit` is already used by `Closed`" {
+		t.Errorf("reused value should be rejected: %v", err)
+	}
+}
+
+func TestPinnedEventValues(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+//fsm:machine
+type OrderDeclaration struct {
+	Placed FSMState ` + "`Pay:\"Active\" Cancel:\"Voided\" fsm:\"initial,value.Cancel=5,value.Noop=9\"`" + `
+	Active struct {
+		Paid   FSMState ` + "`Pack:\"Packed\" fsm:\"initial\"`" + `
+		Packed FSMState ` + "`Ship:\"Voided\" fsm:\"value.Cancel=3\"`" + `
+	} ` + "`Cancel:\"Voided\" fsm:\"value.Cancel=7\"`" + `
+	Voided FSMState
+}
+
+//fsm:machine
+type LampDeclaration struct {
+	On  FSMState ` + "`Toggle:\"Off\" fsm:\"initial,value.Switch=2\"`" + `
+	Off FSMState ` + "`Toggle:\"On\"`" + `
+}
+`
+	dir := writeDeclarations(t, map[string]string{"fsm.go": src})
+	defer os.RemoveAll(dir)
+	fset, pkgs := parseDir(dir)
+	pkg := pkgs["fsm"]
+	obj := pkg.Files[filepath.Join(dir, "fsm.go")].Scope.Lookup("OrderDeclaration")
+	definition := parseDefinition(Options{}, "Order", dir, pkg, fset, obj)
+	assignStateAndEventValues(fset, &definition, lockFile{}, false)
+	var actual []string
+	for _, name := range definition.StateOrder {
+		st := definition.States[name]
+		if st.IsComposite || st.IsTerminal {
+			continue
+		}
+		for _, ev := range st.TransitionOrder {
+			actual = append(actual, fmt.Sprintf("%s.%s=%d", name, ev, st.Transitions[ev].Value))
+		}
+		actual = append(actual, fmt.Sprintf("%s.%s=%d", name, noopEvent, st.NoopValue))
+	}
+	expected := []string{
+		"Placed.Pay=10", "Placed.Cancel=5", "Placed.Noop=9",
+		"Paid.Pack=8", "Paid.Cancel=7", "Paid.Noop=9",
+		"Packed.Ship=4", "Packed.Cancel=3", "Packed.Noop=5",
+	}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected {%v}; actual: {%v}", expected, actual)
+	}
+
+	var diagnostics []string
+	for _, d := range CheckDeclarations(dir, []string{"LampDeclaration"}, Options{}) {
+		diagnostics = append(diagnostics, d.Message)
+	}
+	expectedDiagnostics := []string{"can't pin value of event `Switch`, state `On` doesn't have it"}
+	if fmt.Sprint(diagnostics) != fmt.Sprint(expectedDiagnostics) {
+		t.Errorf("expected {%v}; actual: {%v}", expectedDiagnostics, diagnostics)
+	}
+}
+
+func TestRunGeneratorInDeclarationOrder(t *testing.T) {
+	checkGeneratedFileWithOptions(t, []string{"PumpDeclaration"}, "pump.fsm.go", Options{Verbose: true})
+}
+
+func TestRunGeneratorWithPrefix(t *testing.T) {
+	checkGeneratedFileWithOptions(t, []string{"EngineDeclaration"}, "engine.fsm.go", Options{Verbose: true, Prefix: "{{.MachineName}}"})
+}
+
+func TestRunGeneratorWithEmbeddedDeclaration(t *testing.T) {
+	checkGeneratedFileWithOptions(t, []string{"RetryDeclaration", "UploadDeclaration"}, "upload.fsm.go", Options{Verbose: true})
+}
+
+func TestRunGeneratorWithDocComments(t *testing.T) {
+	checkGeneratedFileWithOptions(t, []string{"LampDeclaration"}, "lamp.fsm.go", Options{Verbose: true})
+}
+
+func TestRunGeneratorForMarkedTypes(t *testing.T) {
+	checkGeneratedFileWithOptions(t, nil, "valve.fsm.go", Options{Verbose: true})
+}
+
+func TestCollectMarkers(t *testing.T) {
+	src := `package fsm
+
+//fsm:machine Name=Valve
+type ValveStates struct{}
+
+// DoorDeclaration is marked without name
+//fsm:machine
+type DoorDeclaration struct{}
+
+type (
+	//fsm:machine Name=Tap
+	TapStates struct{}
+	PlainStates struct{}
+)
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "fsm.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("source should be parsed: %s", err.Error())
+	}
+	markers := collectMarkers(fset, map[string]*ast.Package{"fsm": {Name: "fsm", Files: map[string]*ast.File{"fsm.go": file}}})
+	expected := map[string]string{"ValveStates": "Valve", "DoorDeclaration": "Door", "TapStates": "Tap"}
+	if fmt.Sprint(markers) != fmt.Sprint(expected) {
+		t.Errorf("expected {%v}; actual: {%v}", expected, markers)
+	}
+	if actual := machineTypes(nil, markers); fmt.Sprint(actual) != "[DoorDeclaration TapStates ValveStates]" {
+		t.Errorf("marked types should be used when types aren't specified: %v", actual)
+	}
+	if machineName("PlainDeclaration", markers) != "Plain" || machineName("TapStates", markers) != "Tap" {
+		t.Errorf("machine names resolved incorrectly")
+	}
+}
+
+func TestStateIdentifiers(t *testing.T) {
+	closed := stateDefinition{Name: "Closed", Transitions: map[event]transition{"Error": {Event: "Error"}}}
+	closedError := stateDefinition{Name: "ClosedError", IsTerminal: true}
+	identifiers := stateIdentifiers(machineDefinition{States: map[state]stateDefinition{"Closed": closed, "ClosedError": closedError}})
+	expected := "[event `Error` of state `Closed` state `ClosedError`]"
+	if actual := fmt.Sprint(identifiers["ClosedError"]); actual != expected {
+		t.Errorf("expected {%s}; actual: {%s}", expected, actual)
+	}
+	if actual := fmt.Sprint(identifiers["_ClosedNoop"]); actual != "[event `Noop` of state `Closed`]" {
+		t.Errorf("unexpected origin of _ClosedNoop: %s", actual)
+	}
+	if prefixed("CBM", "_ClosedNoop") != "_CBMClosedNoop" || prefixed("CBM", "Closed") != "CBMClosed" {
+		t.Errorf("identifiers prefixed incorrectly")
+	}
+}
+
+func TestDefinitionWithoutInitialState(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+type GateDeclaration struct {
+	Opened FSMState ` + "`Close:\"Closed\"`" + `
+	Closed FSMState ` + "`Open:\"Opened\"`" + `
+}
+`
+	err := catchDeclarationError(func() {
+		parseTestDefinition(t, src)
+	})
+	expected := "machine Gate should have initial state. fsm.go:5:22"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected {%s}; actual: {%v}", expected, err)
+	}
+}
+
+func TestAnalyzeDefinition(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+type PollerDeclaration struct {
+	Idle     FSMState ` + "`Poll:\"Polling\" Stop:\"Stopped\" fsm:\"initial\"`" + `
+	Polling  FSMState ` + "`Retry:\"Waiting\"`" + `
+	Waiting  FSMState ` + "`Retry:\"Polling\"`" + `
+	Orphaned FSMState ` + "`Adopt:\"Idle\"`" + `
+	Stopped  FSMState
+}
+`
+	fset, definition := parseTestDefinition(t, src)
+	var actual []string
+	for _, d := range analyzeDefinition(fset, definition, false) {
+		actual = append(actual, d.Message+". "+d.Position.String())
+	}
+	expected := []string{
+		"state `Orphaned` can't be reached from initial state. fsm.go:9:2",
+		"states `Polling`, `Waiting` form a cycle that can't reach any terminal state. fsm.go:7:2",
+	}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected {%v}; actual: {%v}", expected, actual)
+	}
+}
+
+func TestCheckProperties(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+// WorkerDeclaration of worker
+//
+//fsm:assert reachable Stopped
+//fsm:assert never Idle -> Stopped
+//fsm:assert never Stopped -> Idle
+//fsm:assert leaves Busy
+type WorkerDeclaration struct {
+	Idle FSMState ` + "`Poll:\"Busy\" Stop:\"Stopped\" Park:\"Parked\" fsm:\"initial\"`" + `
+	Busy struct {
+		Polling FSMState ` + "`Retry:\"Waiting\" fsm:\"initial\"`" + `
+		Waiting FSMState ` + "`Retry:\"Polling\"`" + `
+	} ` + "`Cancel:\"Idle\"`" + `
+	Parked  FSMState
+	Stopped FSMState
+}
+`
+	fset, definition := parseTestDefinition(t, src)
+	var actual []string
+	for _, d := range checkProperties(fset, definition) {
+		actual = append(actual, d.Message+". "+d.Position.String())
+	}
+	expected := []string{
+		"property `reachable Stopped` fails, `Stopped` can't be reached from `Parked`: Idle -Park-> Parked. fsm.go:7:1",
+		"property `never Idle -> Stopped` fails, `Idle` is followed by `Stopped`: Idle -Stop-> Stopped. fsm.go:8:1",
+		"property `leaves Busy` fails, machine can stay inside of `Busy` forever: Idle -Poll-> Polling -Retry-> Waiting -Retry-> Polling. fsm.go:10:1",
+	}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected {%v}; actual: {%v}", expected, actual)
+	}
+}
+
+func TestCheckDeclarations(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+//fsm:machine
+//fsm:assert never Idle -> Stopped
+type WorkerDeclaration struct {
+	Idle     FSMState ` + "`Stop:\"Stopped\" fsm:\"initial\"`" + `
+	Orphaned FSMState ` + "`Adopt:\"Idle\"`" + `
+	Stopped  FSMState
+}
+
+//fsm:machine
+type DoorDeclaration struct {
+	Opened FSMState ` + "`Close:\"Locked\" Ring(string):\"Opened\" fsm:\"initial\"`" + `
+	Closed FSMState ` + "`Ring(int):\"Closed\"`" + `
+}
+
+//fsm:machine
+type LampDeclaration struct {
+	On  FSMState ` + "`Toggle:\"Off\" fsm:\"initial\"`" + `
+	Off FSMState ` + "`Toggle:\"On\" fsm:\"initial\"`" + `
+}
+`
+	dir := writeDeclarations(t, map[string]string{"fsm.go": src, "worker.fsm.lock": "state Idle\n"})
+	defer os.RemoveAll(dir)
+	var actual []string
+	for _, d := range CheckDeclarations(dir, nil, Options{}) {
+		d.Position.Filename = filepath.Base(d.Position.Filename)
+		actual = append(actual, d.String())
+	}
+	expected := []string{
+		"fsm.go:15:2: error: You've defined (Opened) -[Close]-> (Locked). But there is no such destination state as `Locked`",
+		"fsm.go:15:2: error: event `Ring` carries payload `string` on state `Opened`, but `int` on state `Closed`",
+		"fsm.go:22:2: error: only one initial state allowed, but both `On` and `Off` are initial",
+		"fsm.go:9:2: warning: state `Orphaned` can't be reached from initial state",
+		"fsm.go:6:1: error: property `never Idle -> Stopped` fails, `Idle` is followed by `Stopped`: Idle -Stop-> Stopped",
+		"worker.fsm.lock: error: unsupported lock file format: line 1: expected `<kind> <name> <value>`, got `state Idle`",
+	}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected {%v}; actual: {%v}", expected, actual)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 2 {
+		t.Errorf("check should not write files: %v %v", files, err)
+	}
+}
+
+func TestGuardClashesWithBehaviourMethod(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+//fsm:machine
+type DoorDeclaration struct {
+	Opened FSMState ` + "`Close:\"Closed\" fsm:\"initial\"`" + `
+	Closed FSMState ` + "`Lock:\"Closed\" Open:\"Opened [OperateOpened]\"`" + `
+}
+`
+	dir := writeDeclarations(t, map[string]string{"fsm.go": src})
+	defer os.RemoveAll(dir)
+	var actual []string
+	for _, d := range CheckDeclarations(dir, nil, Options{}) {
+		d.Position.Filename = filepath.Base(d.Position.Filename)
+		actual = append(actual, d.String())
+	}
+	expected := []string{
+		"fsm.go:8:33: error: guard `OperateOpened` of event `Open` on state `Closed` clashes with generated behaviour method `OperateOpened`",
+	}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected {%v}; actual: {%v}", expected, actual)
+	}
+}
+
+func TestEmbeddedMachineGeneratedInSamePackage(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+type RetryDeclaration struct {
+	Attempting FSMState ` + "`Succeed:\"Delivered\" fsm:\"initial\"`" + `
+	Delivered  FSMState
+}
+
+type UploadDeclaration struct {
+	Selected         FSMState ` + "`Send:\"UploadRetry\" fsm:\"initial\"`" + `
+	RetryDeclaration ` + "`Delivered:\"Uploaded\" fsm:\"prefix=Upload\"`" + `
+	Uploaded         FSMState
+}
+`
+	dir := writeDeclarations(t, map[string]string{"fsm.go": src})
+	defer os.RemoveAll(dir)
+	var actual []string
+	for _, d := range CheckDeclarations(dir, []string{"UploadDeclaration"}, Options{}) {
+		d.Position.Filename = filepath.Base(d.Position.Filename)
+		actual = append(actual, d.String())
+	}
+	expected := []string{
+		"fsm.go:12:2: error: embedded machine Retry isn't generated in package of Upload, generate it together with Upload",
+	}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected {%v}; actual: {%v}", expected, actual)
+	}
+	if diagnostics := CheckDeclarations(dir, []string{"RetryDeclaration", "UploadDeclaration"}, Options{}); len(diagnostics) != 0 {
+		t.Errorf("machine embedded into machine of the same run should be accepted: %v", diagnostics)
+	}
+}
+
+func TestPayloadTypesResolvedInDeclaringFile(t *testing.T) {
+	src := `package fsm
+
+import "time"
+
+type FSMState int
+
+var _ time.Duration
+
+type TimerDeclaration struct {
+	Idle    FSMState ` + "`Wait(time.Duration):\"Waiting\" fsm:\"initial\"`" + `
+	Waiting FSMState
+}
+
+type BrokenDeclaration struct {
+	Idle    FSMState ` + "`Wait(time.Missing):\"Waiting\" fsm:\"initial\"`" + `
+	Waiting FSMState
+}
+`
+	dir := writeDeclarations(t, map[string]string{
+		"a.go": "package fsm\n\nimport time \"strings\"\n\nvar _ = time.ToUpper\n",
+		"b.go": src,
+	})
+	defer os.RemoveAll(dir)
+	fset, pkgs := parseDir(dir)
+	var imports []string
+	forEachMachine(fset, pkgs, []string{"TimerDeclaration"}, func(ns *namespace, machineName string, pkg *ast.Package, obj *ast.Object) {
+		imports = parseDefinition(Options{}, machineName, dir, pkg, fset, obj).PayloadImports
+	})
+	if expected := "[\"time\"]"; fmt.Sprint(imports) != expected {
+		t.Errorf("expected {%s}; actual: {%v}", expected, imports)
+	}
+
+	diagnostics := CheckDeclarations(dir, []string{"BrokenDeclaration"}, Options{})
+	expected := "b.go:15:2: error: event `Wait` on state `Idle`: payload type `time.Missing` is invalid: "
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic {%s...}; actual: {%v}", expected, diagnostics)
+	}
+	diagnostics[0].Position.Filename = filepath.Base(diagnostics[0].Position.Filename)
+	if actual := diagnostics[0].String(); !strings.HasPrefix(actual, expected) {
+		t.Errorf("expected {%s...}; actual: {%s}", expected, actual)
+	}
+}
+
+func TestVerifyGenerated(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+//fsm:machine
+type DoorDeclaration struct {
+	Opened FSMState ` + "`Close:\"Closed\" fsm:\"initial\"`" + `
+	Closed FSMState
+}
+`
+	dir := writeDeclarations(t, map[string]string{"fsm.go": src})
+	defer os.RemoveAll(dir)
+	declarationPath := filepath.Join(dir, "fsm.go")
+	RunGenerator(dir, nil, Options{})
+	output := &bytes.Buffer{}
+	if !VerifyGenerated(dir, nil, Options{}, output) || output.Len() != 0 {
+		t.Errorf("generated file should be up to date: %s", output.String())
+	}
+
+	changed := strings.Replace(src, "Closed FSMState", "Closed FSMState `Open:\"Opened\"`", 1)
+	if err := ioutil.WriteFile(declarationPath, []byte(changed), 0664); err != nil {
+		t.Fatalf("declaration should be written: %s", err.Error())
+	}
+	if VerifyGenerated(dir, nil, Options{}, output) {
+		t.Errorf("generated file should be stale")
+	}
+	for _, expected := range []string{
+		"door.fsm.go: declaration `DoorDeclaration` changed since file was generated, run go generate\n",
+		"\n+\tDoorClosedState\n",
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("output should contain {%s}; actual: {%s}", expected, output.String())
+		}
+	}
+
+	diff := unifiedDiff("a", "b", []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"), []byte("1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\n"))
+	expected := "--- a\n+++ b\n@@ -1,7 +1,7 @@\n 1\n 2\n 3\n-4\n+four\n 5\n 6\n 7\n@@ -8,3 +8,4 @@\n 8\n 9\n 10\n+11\n"
+	if diff != expected {
+		t.Errorf("expected {%s}; actual: {%s}", expected, diff)
+	}
+}
+
+func TestDiffDeclarations(t *testing.T) {
+	previous := `package fsm
+
+type FSMState int
+
+//fsm:machine
+type JobDeclaration struct {
+	Queued    FSMState ` + "`Start:\"Running\" fsm:\"initial\"`" + `
+	Running   FSMState ` + "`Done:\"Succeeded\" Fail:\"Failed\" Pause:\"Paused\"`" + `
+	Paused    FSMState ` + "`Resume:\"Running\"`" + `
+	Succeeded FSMState
+	Failed    FSMState
+}
+`
+	current := `package fsm
+
+type FSMState int
+
+//fsm:machine
+type JobDeclaration struct {
+	Queued    FSMState ` + "`Start:\"Running\" Cancel:\"Cancelled\" fsm:\"initial\"`" + `
+	Running   FSMState ` + "`Done:\"Succeeded\" Fail:\"Queued\"`" + `
+	Paused    FSMState ` + "`Resume:\"Running\"`" + `
+	Succeeded FSMState
+	Cancelled FSMState
+}
+`
+	var dirs []string
+	for _, src := range []string{previous, current} {
+		dir := writeDeclarations(t, map[string]string{"fsm.go": src})
+		defer os.RemoveAll(dir)
+		dirs = append(dirs, dir)
+	}
+	output := &bytes.Buffer{}
+	if breaking, err := DiffDeclarations(dirs[0], dirs[0], nil, Options{}, output); err != nil || breaking || output.Len() != 0 {
+		t.Errorf("the same declarations should have no changes: %v %s", err, output.String())
+	}
+	if breaking, err := DiffDeclarations(dirs[0], dirs[1], nil, Options{}, output); err != nil || !breaking {
+		t.Errorf("breaking changes should be found: %v", err)
+	}
+	expected := strings.Join([]string{
+		"Job: breaking: state `Failed` removed",
+		"Job: additive: state `Cancelled` added",
+		"Job: additive: event `Cancel` added to state `Queued`",
+		"Job: breaking: event `Noop` of state `Queued` renumbered from 2 to 3",
+		"Job: breaking: event `Fail` of state `Running` retargeted from `Failed` to `Queued`",
+		"Job: breaking: event `Pause` removed from state `Running`",
+		"Job: breaking: event `Noop` of state `Running` renumbered from 4 to 3",
+		"Job: breaking: machine event `Done` renumbered from 2 to 3",
+		"Job: breaking: machine event `Fail` renumbered from 3 to 4",
+		"Job: breaking: state `Paused` can't be reached from initial state anymore",
+		"",
+	}, "\n")
+	if output.String() != expected {
+		t.Errorf("expected {%s}; actual: {%s}", expected, output.String())
+	}
+}
+
+func TestDiffDeclarationFiles(t *testing.T) {
+	files := map[string]string{
+		"job.go": `package fsm
+
+type FSMState int
+
+//fsm:machine
+type JobDeclaration struct {
+	Queued  FSMState ` + "`Start:\"Running\" fsm:\"initial\"`" + `
+	Running RunningDeclaration ` + "`Cancel:\"Queued\"`" + `
+}
+`,
+		"running.go": `package fsm
+
+type RunningDeclaration struct {
+	Working FSMState ` + "`Pause:\"Paused\" fsm:\"initial\"`" + `
+	Paused  FSMState ` + "`Resume:\"Working\"`" + `
+}
+
+//fsm:machine
+type LampDeclaration struct {
+	On  FSMState ` + "`Toggle:\"Off\" fsm:\"initial\"`" + `
+	Off FSMState ` + "`Toggle:\"On\" fsm:\"initial\"`" + `
+}
+`,
+	}
+	dir := writeDeclarations(t, files)
+	defer os.RemoveAll(dir)
+	job := filepath.Join(dir, "job.go")
+	output := &bytes.Buffer{}
+	if breaking, err := DiffDeclarations(job, job, nil, Options{}, output); err != nil || breaking || output.Len() != 0 {
+		t.Errorf("machine of file should be compared with states declared in other files: %v %s", err, output.String())
+	}
+	expected := "only one initial state allowed, but both `On` and `Off` are initial. " + filepath.Join(dir, "running.go") + ":11:2"
+	if _, err := DiffDeclarations(dir, dir, nil, Options{}, output); err == nil || err.Error() != expected {
+		t.Errorf("expected {%s}; actual: {%v}", expected, err)
+	}
+	if _, err := DiffDeclarations(filepath.Join(dir, "missing.go"), job, nil, Options{}, output); err == nil {
+		t.Errorf("missing declarations should be reported")
+	}
+}
+
+func checkGeneratedFile(t *testing.T, typeName string, fileName string) {
+	checkGeneratedFileWithOptions(t, []string{typeName}, fileName, Options{Verbose: true, Alphabetical: true})
+}
+
+// checkGeneratedFileWithOptions generates machines in temporary copy of testdata,
+// so files generated by other tests can't affect result
+func checkGeneratedFileWithOptions(t *testing.T, types []string, fileName string, options Options) {
+	dir := copyTestdata(t)
+	defer os.RemoveAll(dir)
+	RunGenerator(dir, types, options)
+	checkFile(t, filepath.Join(dir, fileName), fileName)
+}
+
+// checkFile compares generated file with the expected one from testdata
+func checkFile(t *testing.T, actualTestOutputFile string, fileName string) {
+	expectedOutputFile := "./testdata/expected/expected." + fileName
 
 	expected, err := ioutil.ReadFile(expectedOutputFile)
 	if err != nil {
@@ -32,6 +692,42 @@ func TestRunGeneratorForTypes(t *testing.T) {
 	}
 }
 
+// copyTestdata copies declarations and lock files of testdata to temporary dir, without generated files.
+// Caller removes the dir.
+func copyTestdata(t *testing.T) string {
+	files, err := ioutil.ReadDir("./testdata")
+	if err != nil {
+		t.Fatalf("can't read testdata: %s", err.Error())
+	}
+	contents := map[string]string{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasSuffix(name, ".fsm.go") || !strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, lockFileSuffix) {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join("./testdata", name))
+		if err != nil {
+			t.Fatalf("can't read testdata file: %s", err.Error())
+		}
+		contents[name] = string(content)
+	}
+	return writeDeclarations(t, contents)
+}
+
+// writeDeclarations writes files with specified names and contents to temporary dir. Caller removes the dir.
+func writeDeclarations(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "fsm")
+	if err != nil {
+		t.Fatalf("temp dir should be created: %s", err.Error())
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0664); err != nil {
+			t.Fatalf("file `%s` should be written: %s", name, err.Error())
+		}
+	}
+	return dir
+}
+
 func TestVerifyTypeNames(t *testing.T) {
 	err := verifySpecifiedTypes([]string{"OfLibertyDeclaration"})
 	if err != nil {
@@ -47,3 +743,249 @@ func TestVerifyTypeNames(t *testing.T) {
 		t.Errorf("expected {%s}; actual: {%s}", expected, err.Error())
 	}
 }
+
+func TestParseTag(t *testing.T) {
+	expected := []tagPair{{Key: "Success", Value: "Closed"}, {Key: "Failure", Value: "Opened"}}
+	for literal, offsets := range map[string][2]int{
+		"`Success:\"Closed\" Failure:\"Opened\"`":           {1, 18},
+		"`Success:\"Closed\",Failure:\"Opened\"`":           {1, 18},
+		"`  Success:\"Closed\"   Failure:\"Opened\" `":      {3, 22},
+		"\"Success:\\\"Closed\\\" Failure:\\\"Opened\\\"\"": {1, 20},
+	} {
+		actual, err := parseTag(literal)
+		if err != nil {
+			t.Errorf("tag %s should be parsed: %s", literal, err.Error())
+			continue
+		}
+		expected[0].Offset, expected[1].Offset = offsets[0], offsets[1]
+		if fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Errorf("tag %s: expected {%v}; actual: {%v}", literal, expected, actual)
+		}
+	}
+
+	expectedErr := "destination `` of event `Error` is not a valid identifier"
+	if _, err := parseTransition("Error", ""); err == nil || err.Error() != expectedErr {
+		t.Errorf("empty transition: expected {%s}; actual: {%v}", expectedErr, err)
+	}
+
+	actual, err := parseTag("`Reason:\"a, b: \\\"c\\\"\"`")
+	if err != nil {
+		t.Errorf("quoted value should be parsed: %s", err.Error())
+	} else if len(actual) != 1 || actual[0].Value != "a, b: \"c\"" {
+		t.Errorf("quoted value parsed incorrectly: %v", actual)
+	}
+
+	for literal, expectedErr := range map[string]string{
+		"`Success:Closed`":                         "pair #1 `Success:Closed` should have form Success:\"value\"",
+		"`Success:\"Closed\" Failure`":             "pair #2 `Failure` should have form Failure:\"value\"",
+		"`Success:\"Closed\" :\"Opened\"`":         "pair #2 `:\"Opened\"` has no key",
+		"`Success:\"Closed\" Failure:\"Open`":      "pair #2 `Failure:\"Open` has unterminated value",
+		"`Success:\"Closed\"Failure:\"Open\"`":     "pair #1 `Success:\"Closed\"` should be separated from `Failure:\"Open\"` by space",
+		"`Success:\"Closed\",,Failure:\"Opened\"`": "pair #2 `,Failure:\"Opened\"` has no key",
+		"`Success:\"\\q\"`":                        "pair #1 `Success:\"\\q\"` has malformed value: invalid syntax",
+	} {
+		_, err := parseTag(literal)
+		if err == nil {
+			t.Errorf("tag %s should not be parsed", literal)
+			continue
+		}
+		if err.Error() != expectedErr {
+			t.Errorf("tag %s: expected {%s}; actual: {%s}", literal, expectedErr, err.Error())
+		}
+	}
+}
+
+func TestParseGuard(t *testing.T) {
+	checkParsedTransitions(t, "Error", map[string]transition{
+		" Opened  [ThresholdReached] ": {Destination: "Opened", Guard: "ThresholdReached"},
+	}, map[string]string{
+		"Opened [ThresholdReached": "guard of event `Error` in `Opened [ThresholdReached` should be closed with `]`",
+		"Opened []":                "guard `` of event `Error` is not a valid identifier",
+		"Opened [Ok] extra":        "unexpected `extra` in transition `Opened [Ok] extra` of event `Error`",
+		"Opened [func]":            "guard `func` of event `Error` is a Go keyword",
+	})
+}
+
+func TestParseHistory(t *testing.T) {
+	checkParsedTransitions(t, "Resume", map[string]transition{
+		"Working":            {Destination: "Working"},
+		"Working.H":          {Destination: "Working", History: shallowHistory},
+		"Working.H* [Ready]": {Destination: "Working", History: deepHistory, Guard: "Ready"},
+	}, map[string]string{
+		"Working.X": "destination `Working.X` of event `Resume` is not a valid identifier",
+	})
+}
+
+func TestParseTimer(t *testing.T) {
+	for ev, expected := range map[event]string{
+		"After100ms":  "100 * time.Millisecond",
+		"After1m30s":  "90 * time.Second",
+		"After2h":     "2 * time.Hour",
+		"AfterUpdate": "",
+		"After0s":     "",
+	} {
+		tr, err := parseTransition(ev, "Opened")
+		if err != nil {
+			t.Errorf("timed transition %s should be parsed: %s", ev, err.Error())
+			continue
+		}
+		definition := machineDefinition{States: map[state]stateDefinition{
+			"Closed": {Name: "Closed", Events: map[event]transition{ev: tr}},
+		}}
+		actual := collectTimers(definition, "Closed")
+		if expected == "" && len(actual) != 0 || expected != "" && (len(actual) != 1 || actual[0].Duration != expected || actual[0].Field != "closedEnteredAt") {
+			t.Errorf("event %s: expected timer {%s}; actual: {%v}", ev, expected, actual)
+		}
+	}
+}
+
+func TestParseEventKey(t *testing.T) {
+	for key, expected := range map[string]string{
+		"Failure":                "",
+		"Failure(*errors.Error)": "*errors.Error",
+	} {
+		ev, payload, err := parseEventKey(key)
+		if err != nil {
+			t.Errorf("event key `%s` should be parsed: %s", key, err.Error())
+			continue
+		}
+		if ev != "Failure" || payload != expected {
+			t.Errorf("event key `%s` parsed incorrectly: %s %s", key, ev, payload)
+		}
+	}
+	for key, expectedErr := range map[string]string{
+		"Failure(error": "payload of event `Failure` should be closed with `)`",
+		"Failure()":     "event `Failure` has empty payload type",
+		"(error)":       "event `` is not a valid identifier",
+	} {
+		if _, _, err := parseEventKey(key); err == nil || err.Error() != expectedErr {
+			t.Errorf("event key `%s`: expected {%s}; actual: {%v}", key, expectedErr, err)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	checkParsedTransitions(t, "Fin", map[string]transition{
+		"Closed [Drained]":           {Destination: "Closed", Guard: "Drained"},
+		"Closed / SendAck":           {Destination: "Closed", Command: "SendAck"},
+		"Closed [Drained] / SendAck": {Destination: "Closed", Guard: "Drained", Command: "SendAck"},
+	}, map[string]string{
+		"Closed / Send Ack": "command `Send Ack` of event `Fin` is not a valid identifier",
+	})
+}
+
+// checkParsedTransitions checks transitions parsed from tag values of event and errors of malformed values
+func checkParsedTransitions(t *testing.T, ev event, parsed map[string]transition, malformed map[string]string) {
+	for value, expected := range parsed {
+		expected.Event = ev
+		actual, err := parseTransition(ev, value)
+		if err != nil {
+			t.Errorf("transition `%s` should be parsed: %s", value, err.Error())
+			continue
+		}
+		if fmt.Sprintf("%+v", actual) != fmt.Sprintf("%+v", expected) {
+			t.Errorf("transition `%s`: expected {%+v}; actual: {%+v}", value, expected, actual)
+		}
+	}
+	for value, expectedErr := range malformed {
+		if _, err := parseTransition(ev, value); err == nil || err.Error() != expectedErr {
+			t.Errorf("transition `%s`: expected {%s}; actual: {%v}", value, expectedErr, err)
+		}
+	}
+}
+
+func TestParseAttribute(t *testing.T) {
+	for _, c := range []struct {
+		key      string
+		value    string
+		expected attributeValue
+	}{
+		{"fsm.label", "Half open", attributeValue{Type: "string", Literal: `"Half open"`}},
+		{"fsm.label(string)", "true", attributeValue{Type: "string", Explicit: true, Literal: `"true"`}},
+		{"fsm.retryable", "true", attributeValue{Type: "bool", Literal: "true"}},
+		{"fsm.retries", "3", attributeValue{Type: "int", Literal: "3"}},
+		{"fsm.ratio", "0.5", attributeValue{Type: "float64", Literal: "0.5"}},
+		{"fsm.retries", "08", attributeValue{Type: "int", Literal: "8"}},
+		{"fsm.retries(int64)", "010", attributeValue{Type: "int64", Explicit: true, Literal: "10"}},
+		{"fsm.ratio", "1e3", attributeValue{Type: "float64", Literal: "1000"}},
+		{"fsm.ratio(float64)", "+.5", attributeValue{Type: "float64", Explicit: true, Literal: "0.5"}},
+		{"fsm.label", "Infinity", attributeValue{Type: "string", Literal: `"Infinity"`}},
+		{"fsm.label", "NaN", attributeValue{Type: "string", Literal: `"NaN"`}},
+	} {
+		_, actual, err := parseAttribute(c.key, c.value)
+		if err != nil {
+			t.Errorf("attribute `%s:%s` should be parsed: %s", c.key, c.value, err.Error())
+			continue
+		}
+		if actual != c.expected {
+			t.Errorf("attribute `%s:%s`: expected {%v}; actual: {%v}", c.key, c.value, c.expected, actual)
+		}
+	}
+	for key, expectedErr := range map[string]string{
+		"fsm.ratio(float64)": "attribute `ratio`: value `+Inf` isn't float64",
+		"fsm.retries(int)":   "attribute `retries`: value `+Inf` isn't int",
+		"fsm.retries(uint8)": "attribute `retries`: type `uint8` is unknown, supported types are string, bool, int, int64 and float64",
+		"fsm.retries(int":    "type of attribute `retries` should be closed with `)`",
+		"fsm.re-tries":       "attribute `re-tries` is not a valid identifier",
+	} {
+		if _, _, err := parseAttribute(key, "+Inf"); err == nil || err.Error() != expectedErr {
+			t.Errorf("attribute `%s`: expected {%s}; actual: {%v}", key, expectedErr, err)
+		}
+	}
+}
+
+func TestCollectAttributesInDeclarationOrder(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+type DoorDeclaration struct {
+	Shut struct {
+		Latched   FSMState ` + "`Unlatch:\"Unlatched\" fsm:\"initial\"`" + `
+		Unlatched FSMState ` + "`Latch:\"Latched\"`" + `
+	} ` + "`Pull:\"Ajar\" fsm:\"initial\" fsm.label:\"Shut\"`" + `
+	Ajar FSMState ` + "`Push:\"Shut\" fsm.label:\"Ajar\"`" + `
+}
+`
+	_, definition := parseTestDefinition(t, src)
+	expected := "[{label Label string [{Shut \"Shut\"} {Latched \"Shut\"} {Unlatched \"Shut\"} {Ajar \"Ajar\"}]}]"
+	if actual := fmt.Sprint(definition.Attributes); actual != expected {
+		t.Errorf("expected {%s}; actual: {%s}", expected, actual)
+	}
+}
+
+func TestAttributesWithSameMethod(t *testing.T) {
+	src := `package fsm
+
+type FSMState int
+
+type DoorDeclaration struct {
+	Shut FSMState ` + "`Pull:\"Ajar\" fsm:\"initial\" fsm.label:\"Shut\"`" + `
+	Ajar FSMState ` + "`Push:\"Shut\" fsm.Label:\"Ajar\"`" + `
+}
+`
+	err := catchDeclarationError(func() {
+		parseTestDefinition(t, src)
+	})
+	expected := "attributes `Label` and `label` both generate method `Label` of state type. fsm.go:6:2"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected {%s}; actual: {%v}", expected, err)
+	}
+}
+
+// parseTestDefinition parses and verifies the only machine declared in source
+func parseTestDefinition(t *testing.T, src string) (*token.FileSet, machineDefinition) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "fsm.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("source should be parsed: %s", err.Error())
+	}
+	pkg := &ast.Package{Name: "fsm", Files: map[string]*ast.File{"fsm.go": file}}
+	for name, obj := range file.Scope.Objects {
+		if strings.HasSuffix(name, declarationTag) && obj.Kind == ast.Typ {
+			return fset, parseDefinition(Options{}, strings.TrimSuffix(name, declarationTag), ".", pkg, fset, obj)
+		}
+	}
+	t.Fatalf("source should declare machine")
+	return nil, machineDefinition{}
+}
@@ -0,0 +1,189 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// parseDeclaration collects states declared by fields of structType into states map
+// and returns names of declared states together with the initial one.
+// Fields of struct types are composite states and their fields are nested states.
+// Events of the special `Any` field are collected into wildcards map by parent state.
+func parseDeclaration(
+	fset *token.FileSet, pkg *ast.Package, structType *ast.StructType, parent state,
+	states map[state]stateDefinition, wildcards map[state]stateDefinition, visiting map[*ast.StructType]bool,
+) ([]state, state) {
+	visiting[structType] = true
+	defer delete(visiting, structType)
+
+	var declared []state
+	var initial state
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			st := parseEmbedding(fset, pkg, field, parent, states, wildcards, visiting)
+			declared, initial = declareState(fset, states, st, declared, initial)
+			continue
+		}
+		verifyField(fset, field)
+		st := parseStateDefinition(fset, field)
+		st.Parent = parent
+		if st.Name == wildcardState {
+			verifyWildcard(fset, pkg, st)
+			wildcards[parent] = st
+			continue
+		}
+		if nested := nestedDeclaration(pkg, field); nested != nil {
+			if visiting[nested] {
+				failf(fset.Position(field.Pos()), "composite state `%s` recursively contains itself", st.Name)
+			}
+			st.IsComposite = true
+			st.Children, st.InitialChild = parseDeclaration(fset, pkg, nested, st.Name, states, wildcards, visiting)
+			if st.InitialChild == "" {
+				failf(fset.Position(field.Pos()), "composite state `%s` should have initial sub-state", st.Name)
+			}
+		}
+		declared, initial = declareState(fset, states, st, declared, initial)
+	}
+	sort.Slice(declared, func(i, j int) bool {
+		return string(declared[i]) < string(declared[j])
+	})
+	return declared, initial
+}
+
+// declareState adds st to states and to sibling states declared so far
+func declareState(fset *token.FileSet, states map[state]stateDefinition, st stateDefinition, declared []state, initial state) ([]state, state) {
+	if _, ok := states[st.Name]; ok {
+		failf(fset.Position(st.Field.Pos()), "state `%s` declared more than once", st.Name)
+	}
+	if st.IsInitial && initial == "" {
+		initial = st.Name
+	}
+	states[st.Name] = st
+	return append(declared, st.Name), initial
+}
+
+// nestedDeclaration returns declaration of sub-states if field has non-empty struct type
+func nestedDeclaration(pkg *ast.Package, field *ast.Field) *ast.StructType {
+	var structType *ast.StructType
+	switch fieldType := field.Type.(type) {
+	case *ast.StructType:
+		structType = fieldType
+	case *ast.Ident:
+		obj := lookupType(pkg, fieldType.Name)
+		if obj == nil {
+			return nil
+		}
+		typeSpec, ok := obj.Decl.(*ast.TypeSpec)
+		if !ok {
+			return nil
+		}
+		structType, _ = typeSpec.Type.(*ast.StructType)
+	}
+	if structType == nil || structType.Fields == nil || len(structType.Fields.List) == 0 {
+		return nil
+	}
+	return structType
+}
+
+func lookupType(pkg *ast.Package, name string) *ast.Object {
+	for _, file := range pkg.Files {
+		obj := file.Scope.Lookup(name)
+		if obj != nil && obj.Kind == ast.Typ {
+			return obj
+		}
+	}
+	return nil
+}
+
+// resolveTransitions calculates transitions for every leaf state.
+// Leaf inherits events of its parents, but own events of inner state take precedence.
+func resolveTransitions(definition machineDefinition) {
+	for name, st := range definition.States {
+		if st.IsComposite {
+			st.IsTerminal = false
+			definition.States[name] = st
+			continue
+		}
+		st.Transitions = map[event]transition{}
+		definition.States[name] = st
+		for s := name; s != ""; s = definition.States[s].Parent {
+			for ev, tr := range definition.States[s].Events {
+				if _, ok := st.Transitions[ev]; ok {
+					continue
+				}
+				st.Transitions[ev] = definition.resolveTransition(name, tr)
+			}
+		}
+		st.IsTerminal = len(st.Transitions) == 0
+		st.Guards = collectGuards(st.Transitions)
+		st.Timers = collectTimers(definition, name)
+		definition.States[name] = st
+	}
+}
+
+// resolveTransition calculates leaf destination of transition from leaf state
+// and actions that should be executed on the way.
+// All states up to the closest common parent of source and destination are left and entered again.
+// Timers of states inside of destination restored from history start on the next Operate call.
+func (m machineDefinition) resolveTransition(from state, tr transition) transition {
+	tr.Leaf = m.leafOf(tr.Destination)
+	common := m.commonParent(from, tr.Destination)
+	child := from
+	var left []state
+	for s := from; s != common; s = m.States[s].Parent {
+		if m.States[s].HasExit {
+			tr.ExitActions = append(tr.ExitActions, s)
+		}
+		tr.HistoryRecords = append(tr.HistoryRecords, m.recordHistory(s, child, from)...)
+		if hasTimers(m.States[s]) {
+			left = append(left, s)
+		}
+		child = s
+	}
+	lowest := tr.Leaf
+	if tr.History != "" {
+		tr.RestoredHistory = m.historyOf(tr.Destination, tr.History)
+		lowest = tr.Destination
+	}
+	var entered []state
+	var started []string
+	for s := lowest; s != common; s = m.States[s].Parent {
+		if m.States[s].HasEntry {
+			entered = append([]state{s}, entered...)
+		}
+		if hasTimers(m.States[s]) {
+			started = append([]string{enteredAtField(s)}, started...)
+		}
+	}
+	tr.EntryActions = entered
+	for _, s := range left {
+		if !contains(started, enteredAtField(s)) {
+			tr.StoppedTimers = append(tr.StoppedTimers, enteredAtField(s))
+		}
+	}
+	tr.StartedTimers = started
+	return tr
+}
+
+// commonParent returns the closest composite state that contains both a and b, or empty state if there is none
+func (m machineDefinition) commonParent(a state, b state) state {
+	parents := map[state]bool{}
+	for s := m.States[a].Parent; s != ""; s = m.States[s].Parent {
+		parents[s] = true
+	}
+	for s := m.States[b].Parent; s != ""; s = m.States[s].Parent {
+		if parents[s] {
+			return s
+		}
+	}
+	return ""
+}
+
+// leafOf follows initial sub-states of composite state down to the leaf
+func (m machineDefinition) leafOf(s state) state {
+	for m.States[s].IsComposite {
+		s = m.States[s].InitialChild
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+package generator
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// history markers of composite states
+const (
+	shallowHistory = "H"
+	deepHistory    = "H*"
+)
+
+// history of composite state, shallow history remembers the last active sub-state,
+// and deep history remembers the last active leaf state inside of composite state
+type history struct {
+	Name       string
+	Composite  state
+	Deep       bool
+	Method     string
+	Field      string
+	StateField string
+	// Default is the state composite is entered with, if it has no history yet
+	Default state
+	// Values are the states history can hold with entry actions executed inside of composite state on restoration
+	Values map[state][]state
+	// HasEntryActions reports whether any restored state requires entry actions
+	HasEntryActions bool
+}
+
+type historyRecord struct {
+	Field string
+	State state
+}
+
+// collectHistories finds composite states which history is resumed by transitions
+func collectHistories(fset *token.FileSet, definition machineDefinition) []history {
+	found := map[string]history{}
+	for _, name := range sortedStates(definition.States) {
+		st := definition.States[name]
+		for _, ev := range sortedEvents(st.Events) {
+			tr := st.Events[ev]
+			if tr.History == "" {
+				continue
+			}
+			composite := definition.States[tr.Destination]
+			if !composite.IsComposite {
+				failf(
+					fset.Position(st.Field.Pos()),
+					"You've defined (%v) -%v-> (%v.%v). But only composite states have history",
+					name, ev, tr.Destination, tr.History,
+				)
+			}
+			h := history{
+				Name:       string(tr.Destination) + "." + tr.History,
+				Composite:  tr.Destination,
+				Deep:       tr.History == deepHistory,
+				Method:     string(tr.Destination) + "History",
+				StateField: composite.StateField,
+				Default:    definition.leafOf(tr.Destination),
+				Values:     map[state][]state{},
+			}
+			if h.Deep {
+				h.Method = string(tr.Destination) + "DeepHistory"
+			}
+			h.Field = strings.ToLower(h.Method[:1]) + h.Method[1:]
+			found[h.Name] = h
+		}
+	}
+
+	var result []history
+	for _, h := range found {
+		for _, leaf := range definition.leavesOf(h.Composite) {
+			value := leaf
+			if !h.Deep {
+				value = definition.leafOf(definition.childOn(h.Composite, leaf))
+			}
+			var entered []state
+			for s := value; s != h.Composite; s = definition.States[s].Parent {
+				if definition.States[s].HasEntry {
+					entered = append([]state{s}, entered...)
+				}
+			}
+			h.Values[value] = entered
+			h.HasEntryActions = h.HasEntryActions || len(entered) > 0
+		}
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// recordHistory returns history records of state s left during transition from leaf state,
+// child is the sub-state of s that was active
+func (m machineDefinition) recordHistory(s state, child state, leaf state) []historyRecord {
+	var records []historyRecord
+	for _, h := range m.Histories {
+		if h.Composite != s {
+			continue
+		}
+		if h.Deep {
+			records = append(records, historyRecord{Field: h.Field, State: leaf})
+		} else {
+			records = append(records, historyRecord{Field: h.Field, State: m.leafOf(child)})
+		}
+	}
+	return records
+}
+
+func (m machineDefinition) historyOf(composite state, marker string) *history {
+	for i := range m.Histories {
+		if m.Histories[i].Name == string(composite)+"."+marker {
+			return &m.Histories[i]
+		}
+	}
+	return nil
+}
+
+// historyNode returns name of Graphviz node that represents history of composite state
+func historyNode(composite state, deep bool) string {
+	if deep {
+		return string(composite) + "__Hdeep"
+	}
+	return string(composite) + "__H"
+}
+
+// leavesOf returns leaf states nested inside of composite state
+func (m machineDefinition) leavesOf(composite state) []state {
+	var result []state
+	for _, child := range m.States[composite].Children {
+		if m.States[child].IsComposite {
+			result = append(result, m.leavesOf(child)...)
+			continue
+		}
+		result = append(result, child)
+	}
+	return result
+}
+
+// childOn returns direct sub-state of composite state on the way to the leaf
+func (m machineDefinition) childOn(composite state, leaf state) state {
+	for m.States[leaf].Parent != composite {
+		leaf = m.States[leaf].Parent
+	}
+	return leaf
+}
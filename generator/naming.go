@@ -0,0 +1,233 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// namespace holds top-level identifiers of package and of machines generated in one run,
+// so generated identifiers can be checked for collisions before files are written
+type namespace struct {
+	// outputs are files of machines generated in this run, their current content is going to be replaced
+	outputs map[string]bool
+	// machines are names of machines generated in this run
+	machines map[string]bool
+	// declared describes identifiers generated for machines of this run
+	declared map[string]string
+}
+
+func newNamespace(machines []string) *namespace {
+	ns := &namespace{outputs: map[string]bool{}, machines: map[string]bool{}, declared: map[string]string{}}
+	for _, machine := range machines {
+		ns.outputs[outputFileName(machine)] = true
+		ns.machines[machine] = true
+	}
+	return ns
+}
+
+func outputFileName(machineName string) string {
+	return strings.ToLower(machineName + ".fsm.go")
+}
+
+// machinePrefix executes prefix template against machine definition, like `{{.MachineName}}`
+func machinePrefix(fset *token.FileSet, definition machineDefinition, prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+	tmpl, err := template.New("prefix").Parse(prefix)
+	if err != nil {
+		failf(token.Position{}, "can't parse prefix template `%s`: %v", prefix, err)
+	}
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, definition)
+	if err != nil {
+		failf(token.Position{}, "can't execute prefix template `%s`: %v", prefix, err)
+	}
+	result := b.String()
+	if !isIdentifier(result) {
+		failf(fset.Position(definition.Struct.Pos()), "prefix `%s` of machine %s is not a valid identifier", result, definition.MachineName)
+	}
+	return result
+}
+
+// origin is declaration generated identifier comes from
+type origin struct {
+	State stateDefinition
+	Event event
+}
+
+func (o origin) String() string {
+	if o.Event != "" {
+		return "event `" + string(o.Event) + "` of state `" + string(o.State.Name) + "`"
+	}
+	return "state `" + string(o.State.Name) + "`"
+}
+
+// stateIdentifiers maps constants and functions generated for states and their events
+// to declarations they are generated for. Identifier can have several origins, if they collide.
+func stateIdentifiers(definition machineDefinition) map[string][]origin {
+	result := map[string][]origin{}
+	for _, name := range sortedStates(definition.States) {
+		st := definition.States[name]
+		result[string(name)] = append(result[string(name)], origin{State: st})
+		if st.IsComposite || st.IsTerminal || st.Embedded != "" {
+			continue
+		}
+		for _, id := range eventIdentifiers(st) {
+			result[id.Name] = append(result[id.Name], origin{State: st, Event: id.Event})
+		}
+	}
+	return result
+}
+
+// eventIdentifier is constant or function generated for event of state
+type eventIdentifier struct {
+	Name  string
+	Event event
+}
+
+func eventIdentifiers(st stateDefinition) []eventIdentifier {
+	prefix := string(st.Name)
+	if st.Embedded != "" {
+		prefix = string(st.Origin)
+	}
+	var result []eventIdentifier
+	for _, ev := range append(sortedEvents(st.Transitions), noopEvent) {
+		for _, name := range []string{prefix + string(ev), "_" + prefix + string(ev)} {
+			result = append(result, eventIdentifier{Name: name, Event: ev})
+		}
+	}
+	return result
+}
+
+// prefixed returns identifier with prefix, unexported identifiers keep leading underscore
+func prefixed(prefix string, name string) string {
+	if strings.HasPrefix(name, "_") {
+		return "_" + prefix + name[1:]
+	}
+	return prefix + name
+}
+
+// StateConst returns name of constant generated for state, prefixed to namespace machines
+// with the same states generated into one package. Names used for serialization stay the same.
+func (m machineDefinition) StateConst(s state) string {
+	return prefixed(m.Prefix, string(s))
+}
+
+// EventConst returns name of constant or constructor generated for event of state.
+// Events of states spliced from embedded machine are generated with prefix of that machine.
+func (m machineDefinition) EventConst(st stateDefinition, ev event) string {
+	if st.Embedded != "" {
+		return prefixed(m.EmbeddedPrefixes[st.Embedded], string(st.Origin)+string(ev))
+	}
+	return prefixed(m.Prefix, string(st.Name)+string(ev))
+}
+
+// verifyIdentifiers checks that top-level identifiers of generated source don't collide
+// with identifiers declared in package or generated for other machines in the same run
+func verifyIdentifiers(fset *token.FileSet, pkg *ast.Package, definition machineDefinition, src []byte, ns *namespace) {
+	existing := map[string]string{}
+	for _, fileName := range sortedFileNames(pkg) {
+		base := filepath.Base(fileName)
+		if ns.outputs[base] || base == outputFileName(definition.MachineName) {
+			continue
+		}
+		for name, obj := range pkg.Files[fileName].Scope.Objects {
+			existing[name] = fmt.Sprintf("`%s` declared at %v", name, fset.Position(obj.Pos()))
+		}
+	}
+	for name, description := range ns.declared {
+		existing[name] = description
+	}
+
+	origins := map[string][]origin{}
+	for name, from := range stateIdentifiers(definition) {
+		origins[prefixed(definition.Prefix, name)] = from
+	}
+	for _, name := range sortedOriginNames(origins) {
+		if from := origins[name]; len(from) > 1 {
+			failf(
+				fset.Position(from[1].State.Field.Pos()),
+				"identifier `%s` generated for %v collides with the one generated for %v",
+				name, from[0], from[1],
+			)
+		}
+	}
+
+	generated, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		failf(token.Position{}, "can't parse source generated for machine %s: %v", definition.MachineName, err)
+	}
+	var names []string
+	for name := range generated.Scope.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if duplicate := duplicateDeclaration(generated); duplicate != "" {
+		failf(fset.Position(definition.Struct.Pos()), "identifier `%s` is generated twice for machine %s", duplicate, definition.MachineName)
+	}
+	for _, name := range names {
+		position := fset.Position(definition.Struct.Pos())
+		subject := "machine " + definition.MachineName
+		if from, ok := origins[name]; ok {
+			position = fset.Position(from[0].State.Field.Pos())
+			subject = from[0].String() + " of " + definition.MachineName
+		}
+		if collision, ok := existing[name]; ok {
+			failf(
+				position,
+				"identifier `%s` generated for %s collides with %s. Use prefix to namespace generated identifiers",
+				name, subject, collision,
+			)
+		}
+		ns.declared[name] = fmt.Sprintf("`%s` generated for %s at %v", name, subject, position)
+	}
+}
+
+func sortedOriginNames(m map[string][]origin) []string {
+	result := make([]string, 0, len(m))
+	for name := range m {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// duplicateDeclaration returns top-level identifier declared more than once in file
+func duplicateDeclaration(file *ast.File) string {
+	seen := map[string]bool{}
+	var names []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				names = append(names, d.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.ValueSpec:
+					for _, name := range sp.Names {
+						names = append(names, name.Name)
+					}
+				case *ast.TypeSpec:
+					names = append(names, sp.Name.Name)
+				}
+			}
+		}
+	}
+	for _, name := range names {
+		if seen[name] && name != "_" {
+			return name
+		}
+		seen[name] = true
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"sort"
+)
+
+// orderDefinition arranges states, regions and events of machine in the order they are declared,
+// so generated code and visualization follow the declaration.
+// Parent states go before their sub-states, own events of state go before inherited ones
+// and events of `Any` field go last.
+// With alphabetical option everything is ordered by name instead.
+func orderDefinition(definition *machineDefinition, alphabetical bool) {
+	if alphabetical {
+		definition.StateOrder = sortedStates(definition.States)
+		for name, st := range definition.States {
+			st.EventOrder = sortedEvents(st.Events)
+			st.TransitionOrder = sortedEvents(st.Transitions)
+			definition.States[name] = st
+		}
+		return
+	}
+
+	sort.SliceStable(definition.Regions, func(i, j int) bool {
+		return definition.Regions[i].Field.Pos() < definition.Regions[j].Field.Pos()
+	})
+	for i, r := range definition.Regions {
+		definition.Regions[i].States = definition.declarationOrder(r.States)
+	}
+	definition.TopLevel = definition.declarationOrder(definition.TopLevel)
+	for name, st := range definition.States {
+		st.Children = definition.declarationOrder(st.Children)
+		st.EventOrder = declaredEvents(st.Events)
+		definition.States[name] = st
+	}
+
+	definition.StateOrder = nil
+	var walk func(states []state)
+	walk = func(states []state) {
+		for _, name := range states {
+			definition.StateOrder = append(definition.StateOrder, name)
+			walk(definition.States[name].Children)
+		}
+	}
+	walk(definition.TopLevel)
+
+	for name, st := range definition.States {
+		if st.IsComposite {
+			continue
+		}
+		st.TransitionOrder = nil
+		for s := name; s != ""; s = definition.States[s].Parent {
+			declaring := definition.States[s]
+			for _, ev := range declaring.EventOrder {
+				if declaredOn(declaring, declaring.Events[ev]) {
+					st.TransitionOrder = appendTransition(st, ev)
+				}
+			}
+		}
+		for _, ev := range declaredEvents(st.Transitions) {
+			st.TransitionOrder = appendTransition(st, ev)
+		}
+		definition.States[name] = st
+	}
+}
+
+// declaredOn reports whether transition is declared in tag of state itself rather than in `Any` field
+func declaredOn(st stateDefinition, tr transition) bool {
+	tag := st.Field.Tag
+	return tag != nil && tag.Pos() <= tr.Pos && tr.Pos < tag.End()
+}
+
+// appendTransition appends event of state transition to its transition order, if it isn't there yet
+func appendTransition(st stateDefinition, ev event) []event {
+	if _, ok := st.Transitions[ev]; !ok || containsEvent(st.TransitionOrder, ev) {
+		return st.TransitionOrder
+	}
+	return append(st.TransitionOrder, ev)
+}
+
+// declarationOrder sorts sibling states by position of their fields
+func (m machineDefinition) declarationOrder(states []state) []state {
+	result := append([]state(nil), states...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return m.States[result[i]].Field.Pos() < m.States[result[j]].Field.Pos()
+	})
+	return result
+}
+
+func declaredEvents(events map[event]transition) []event {
+	result := sortedEvents(events)
+	sort.SliceStable(result, func(i, j int) bool {
+		return events[result[i]].Pos < events[result[j]].Pos
+	})
+	return result
+}
+
+func containsEvent(list []event, value event) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderedStates returns all states in generation order
+func (m machineDefinition) OrderedStates() []stateDefinition {
+	result := make([]stateDefinition, 0, len(m.StateOrder))
+	for _, name := range m.StateOrder {
+		result = append(result, m.States[name])
+	}
+	return result
+}
+
+// OrderedLeaves returns states machine can actually be in, in generation order
+func (m machineDefinition) OrderedLeaves() []stateDefinition {
+	var result []stateDefinition
+	for _, name := range m.StateOrder {
+		if st := m.States[name]; !st.IsComposite {
+			result = append(result, st)
+		}
+	}
+	return result
+}
+
+// OrderedTransitions returns own and inherited transitions of leaf state in generation order
+func (s stateDefinition) OrderedTransitions() []transition {
+	result := make([]transition, 0, len(s.TransitionOrder))
+	for _, ev := range s.TransitionOrder {
+		result = append(result, s.Transitions[ev])
+	}
+	return result
+}
@@ -0,0 +1,181 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// listener receives payload of event that leads to the state
+type listener struct {
+	Event   event
+	Payload string
+}
+
+// verifyPayloads checks that payload types of events exist in the package and that
+// the same event carries the same payload in the whole machine.
+// It returns import specs of packages payload types refer to.
+func verifyPayloads(fset *token.FileSet, pkg *ast.Package, definition machineDefinition, wildcards map[state]stateDefinition) []string {
+	declared := map[event]stateDefinition{}
+	var checked *types.Package
+	var imports []string
+	check := func(st stateDefinition) {
+		for _, ev := range sortedEvents(st.Events) {
+			tr := st.Events[ev]
+			if first, ok := declared[ev]; ok && first.Events[ev].Payload != tr.Payload {
+				failf(
+					fset.Position(st.Field.Pos()),
+					"event `%s` carries payload `%s` on state `%s`, but `%s` on state `%s`",
+					ev, tr.Payload, st.Name, first.Events[ev].Payload, first.Name,
+				)
+			}
+			declared[ev] = st
+			if tr.Payload == "" {
+				continue
+			}
+			if checked == nil {
+				checked = typeCheckPackage(fset, pkg)
+			}
+			specs, err := verifyPayloadType(fset, pkg, checked, st.Field.Pos(), tr.Payload)
+			if err != nil {
+				failf(fset.Position(st.Field.Pos()), "event `%s` on state `%s`: %v", ev, st.Name, err)
+			}
+			for _, spec := range specs {
+				if !contains(imports, spec) {
+					imports = append(imports, spec)
+				}
+			}
+		}
+	}
+	for _, name := range sortedStates(definition.States) {
+		check(definition.States[name])
+	}
+	for _, scope := range sortedStates(wildcards) {
+		check(wildcards[scope])
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// typeCheckPackage type-checks package, so payload types are resolved the way compiler resolves them.
+// Errors are ignored, since package usually doesn't compile until its machines are generated.
+func typeCheckPackage(fset *token.FileSet, pkg *ast.Package) *types.Package {
+	var files []*ast.File
+	for _, fileName := range sortedFileNames(pkg) {
+		files = append(files, pkg.Files[fileName])
+	}
+	config := types.Config{Importer: importer.For("source", nil), Error: func(error) {}}
+	checked, _ := config.Check(pkg.Name, fset, files, nil)
+	return checked
+}
+
+// verifyPayloadType checks that payload declared at position is a type in scope of the file that declares it.
+// It returns import specs of packages payload refers to, as they are imported by that file.
+func verifyPayloadType(fset *token.FileSet, pkg *ast.Package, checked *types.Package, pos token.Pos, payload string) ([]string, error) {
+	expr, err := parser.ParseExpr(payload)
+	if err != nil {
+		return nil, fmt.Errorf("payload type `%s` can't be parsed", payload)
+	}
+	tv, err := types.Eval(fset, checked, pos, payload)
+	if e, ok := err.(types.Error); ok {
+		return nil, fmt.Errorf("payload type `%s` is invalid: %s", payload, e.Msg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("payload type `%s` is invalid: %v", payload, err)
+	}
+	if !tv.IsType() {
+		return nil, fmt.Errorf("payload `%s` isn't a type", payload)
+	}
+	file := fileOf(pkg, pos)
+	var specs []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		selector, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := selector.X.(*ast.Ident); ok {
+			if spec := importSpec(file, ident.Name); spec != "" && !contains(specs, spec) {
+				specs = append(specs, spec)
+			}
+		}
+		return false
+	})
+	return specs, nil
+}
+
+// fileOf returns file of the package that contains position
+func fileOf(pkg *ast.Package, pos token.Pos) *ast.File {
+	for _, file := range pkg.Files {
+		if file.Pos() <= pos && pos <= file.End() {
+			return file
+		}
+	}
+	return nil
+}
+
+// importSpec returns import spec of package with specified name imported by file
+func importSpec(file *ast.File, name string) string {
+	if file == nil {
+		return ""
+	}
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return imp.Name.Name + " " + imp.Path.Value
+			}
+			continue
+		}
+		if path[strings.LastIndex(path, "/")+1:] == name {
+			return imp.Path.Value
+		}
+	}
+	return ""
+}
+
+func sortedFileNames(pkg *ast.Package) []string {
+	var result []string
+	for name := range pkg.Files {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// collectListeners assigns to every state payloads of events that lead to it
+func collectListeners(definition machineDefinition) {
+	listeners := map[state]map[event]string{}
+	for name, st := range definition.Leaves() {
+		for ev, tr := range st.Transitions {
+			if tr.Payload == "" {
+				continue
+			}
+			st.HasPayloads = true
+			if listeners[tr.Destination] == nil {
+				listeners[tr.Destination] = map[event]string{}
+			}
+			listeners[tr.Destination][ev] = tr.Payload
+		}
+		definition.States[name] = st
+	}
+	for name, events := range listeners {
+		st := definition.States[name]
+		st.Listeners = nil
+		for ev, payload := range events {
+			st.Listeners = append(st.Listeners, listener{Event: ev, Payload: payload})
+		}
+		sort.Slice(st.Listeners, func(i, j int) bool {
+			return st.Listeners[i].Event < st.Listeners[j].Event
+		})
+		definition.States[name] = st
+	}
+}
@@ -0,0 +1,294 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// propertyMarker is the comment that asserts property of machine on its declaration type, like `//fsm:assert reachable Exit`
+const propertyMarker = "//fsm:assert"
+
+const (
+	// reachableProperty asserts that state can be reached from every reachable state
+	reachableProperty = "reachable"
+	// neverProperty asserts that machine never moves from one state directly to another
+	neverProperty = "never"
+	// leavesProperty asserts that every path that enters state eventually leaves it
+	leavesProperty = "leaves"
+)
+
+// property is temporal property of machine checked over its transition graph
+type property struct {
+	Text   string
+	Kind   string
+	States []state
+	Pos    token.Pos
+}
+
+// step is transition taken on the way to counterexample of property
+type step struct {
+	From  state
+	Event event
+	To    state
+}
+
+// parseProperties collects properties asserted by comments of declaration type
+func parseProperties(fset *token.FileSet, pkg *ast.Package, definition machineDefinition) []property {
+	var result []property
+	doc := declarationDoc(pkg, definition.Struct)
+	if doc == nil {
+		return nil
+	}
+	for _, comment := range doc.List {
+		if !strings.HasPrefix(comment.Text, propertyMarker+" ") {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, propertyMarker))
+		p := property{Text: text, Pos: comment.Pos()}
+		fields := strings.Fields(text)
+		switch {
+		case len(fields) == 2 && (fields[0] == reachableProperty || fields[0] == leavesProperty):
+			p.Kind, p.States = fields[0], []state{state(fields[1])}
+		case len(fields) == 4 && fields[0] == neverProperty && fields[2] == "->":
+			p.Kind, p.States = fields[0], []state{state(fields[1]), state(fields[3])}
+		default:
+			failf(
+				fset.Position(comment.Pos()),
+				"unsupported property `%s`, expected `%s <State>`, `%s <State> -> <State>` or `%s <State>`",
+				text, reachableProperty, neverProperty, leavesProperty,
+			)
+		}
+		for _, s := range p.States {
+			if _, ok := definition.States[s]; !ok {
+				failf(fset.Position(comment.Pos()), "property `%s` refers to unknown state `%s`", text, s)
+			}
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// declarationDoc returns doc comment of type declared by structType
+func declarationDoc(pkg *ast.Package, structType *ast.StructType) *ast.CommentGroup {
+	for _, fileName := range sortedFileNames(pkg) {
+		for _, decl := range pkg.Files[fileName].Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.Type != structType {
+					continue
+				}
+				if typeSpec.Doc == nil && len(genDecl.Specs) == 1 {
+					return genDecl.Doc
+				}
+				return typeSpec.Doc
+			}
+		}
+	}
+	return nil
+}
+
+// checkProperties proves or refutes properties of machine and reports refuted ones with counterexample.
+// Counterexample is the shortest sequence of events from initial state that violates property.
+func checkProperties(fset *token.FileSet, definition machineDefinition) []Diagnostic {
+	var result []Diagnostic
+	for _, p := range definition.Properties {
+		counterexample, reason, ok := checkProperty(definition, p)
+		if ok {
+			continue
+		}
+		result = append(result, Diagnostic{
+			Position: fset.Position(p.Pos),
+			Severity: SeverityError,
+			Message:  "property `" + p.Text + "` fails, " + reason + ": " + counterexample.String(),
+		})
+	}
+	return result
+}
+
+func checkProperty(definition machineDefinition, p property) (trace, string, bool) {
+	graph := labelledGraph(definition)
+	subject := leafSet(definition, p.States[0])
+	starts := propertyStarts(definition, p.States[0])
+	switch p.Kind {
+	case reachableProperty:
+		reverse := map[state][]state{}
+		for _, steps := range graph {
+			for _, s := range steps {
+				reverse[s.To] = append(reverse[s.To], s.From)
+			}
+		}
+		var leaves []state
+		for _, st := range definition.OrderedLeaves() {
+			if subject[st.Name] {
+				leaves = append(leaves, st.Name)
+			}
+		}
+		reaching := reachableStates(reverse, leaves)
+		if t, found := findPath(graph, starts, func(s state) bool { return !reaching[s] }); found {
+			return t, "`" + string(p.States[0]) + "` can't be reached from `" + string(t.End()) + "`", false
+		}
+	case neverProperty:
+		destination := leafSet(definition, p.States[1])
+		violation := func(s state) (step, bool) {
+			for _, next := range graph[s] {
+				if subject[s] && destination[next.To] {
+					return next, true
+				}
+			}
+			return step{}, false
+		}
+		t, found := findPath(graph, starts, func(s state) bool {
+			_, ok := violation(s)
+			return ok
+		})
+		if found {
+			next, _ := violation(t.End())
+			t.Steps = append(t.Steps, next)
+			return t, "`" + string(p.States[0]) + "` is followed by `" + string(p.States[1]) + "`", false
+		}
+	case leavesProperty:
+		if t, found := findPath(graph, starts, func(s state) bool { return subject[s] && definition.States[s].IsTerminal }); found {
+			return t, "machine stays in terminal state `" + string(t.End()) + "`", false
+		}
+		inside := map[state][]step{}
+		targets := map[state][]state{}
+		for _, steps := range graph {
+			for _, s := range steps {
+				if subject[s.From] && subject[s.To] {
+					inside[s.From] = append(inside[s.From], s)
+					targets[s.From] = append(targets[s.From], s.To)
+				}
+			}
+		}
+		cycles := map[state]bool{}
+		for _, component := range stronglyConnectedComponents(definition, targets) {
+			if len(component) > 1 || containsState(targets[component[0]], component[0]) {
+				for _, s := range component {
+					cycles[s] = true
+				}
+			}
+		}
+		if t, found := findPath(graph, starts, func(s state) bool { return cycles[s] }); found {
+			entry := t.End()
+			for _, first := range inside[entry] {
+				loop, found := findPath(inside, []state{first.To}, func(s state) bool { return s == entry })
+				if found {
+					t.Steps = append(append(t.Steps, first), loop.Steps...)
+					break
+				}
+			}
+			return t, "machine can stay inside of `" + string(p.States[0]) + "` forever", false
+		}
+	}
+	return trace{}, "", true
+}
+
+// labelledGraph returns transitions of every leaf state to leaf states together with their events.
+// Transition that resumes history can lead to any leaf state inside of composite state.
+func labelledGraph(definition machineDefinition) map[state][]step {
+	graph := map[state][]step{}
+	for _, st := range definition.OrderedLeaves() {
+		for _, tr := range st.OrderedTransitions() {
+			targets := []state{tr.Leaf}
+			if tr.RestoredHistory != nil {
+				targets = definition.leavesOf(tr.Destination)
+			}
+			for _, to := range targets {
+				graph[st.Name] = append(graph[st.Name], step{From: st.Name, Event: tr.Event, To: to})
+			}
+		}
+	}
+	return graph
+}
+
+// leafSet returns leaf state or leaf states inside of composite state
+func leafSet(definition machineDefinition, s state) map[state]bool {
+	result := map[state]bool{}
+	if !definition.States[s].IsComposite {
+		result[s] = true
+		return result
+	}
+	for _, leaf := range definition.leavesOf(s) {
+		result[leaf] = true
+	}
+	return result
+}
+
+// propertyStarts returns initial leaf state of machine or of region state belongs to
+func propertyStarts(definition machineDefinition, s state) []state {
+	for _, r := range definition.Regions {
+		if definition.States[s].Region == r.Name {
+			return []state{r.InitialLeaf}
+		}
+	}
+	return []state{definition.InitialLeaf()}
+}
+
+// trace is the path of machine from Start state
+type trace struct {
+	Start state
+	Steps []step
+}
+
+// End returns state machine ends up in after trace
+func (t trace) End() state {
+	if len(t.Steps) == 0 {
+		return t.Start
+	}
+	return t.Steps[len(t.Steps)-1].To
+}
+
+// String prints trace like `Closed -Error-> Opened -After100ms-> HalfOpened`
+func (t trace) String() string {
+	builder := &strings.Builder{}
+	builder.WriteString(string(t.Start))
+	for _, s := range t.Steps {
+		builder.WriteString(" -")
+		builder.WriteString(string(s.Event))
+		builder.WriteString("-> ")
+		builder.WriteString(string(s.To))
+	}
+	return builder.String()
+}
+
+// findPath returns the shortest path from one of starts to state that matches predicate
+func findPath(graph map[state][]step, starts []state, predicate func(state) bool) (trace, bool) {
+	paths := map[state]trace{}
+	var queue []state
+	for _, s := range starts {
+		if _, ok := paths[s]; !ok {
+			paths[s] = trace{Start: s}
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if predicate(current) {
+			return paths[current], true
+		}
+		for _, next := range graph[current] {
+			if _, ok := paths[next.To]; ok {
+				continue
+			}
+			path := paths[current]
+			paths[next.To] = trace{Start: path.Start, Steps: append(append([]step(nil), path.Steps...), next)}
+			queue = append(queue, next.To)
+		}
+	}
+	return trace{}, false
+}
+
+func containsState(list []state, value state) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+type region struct {
+	Name        string
+	StateField  string
+	States      []state
+	Initial     state
+	InitialLeaf state
+	Field       *ast.Field
+}
+
+// collectRegions turns top-level states marked with `fsm:"region"` option into orthogonal regions
+// and returns them together with states declared directly inside of regions.
+// Machine either consists of regions only or has no regions at all.
+func collectRegions(fset *token.FileSet, definition machineDefinition) ([]region, []state) {
+	var regions []region
+	for name, st := range definition.States {
+		if st.IsRegion && st.Parent != "" {
+			failf(fset.Position(st.Field.Pos()), "region `%s` can be declared only at the top level of machine", name)
+		}
+	}
+	for _, name := range definition.TopLevel {
+		st := definition.States[name]
+		if st.IsRegion != definition.States[definition.TopLevel[0]].IsRegion {
+			failf(fset.Position(st.Field.Pos()), "state `%s` should be declared inside of region, because machine has regions", name)
+		}
+		if !st.IsRegion {
+			continue
+		}
+		if !st.IsComposite {
+			failf(fset.Position(st.Field.Pos()), "region `%s` should have struct type with states", name)
+		}
+		if len(st.Events) > 0 || st.IsInitial || st.HasEntry || st.HasExit || st.IsAuto || len(st.Attributes) > 0 || st.Value != 0 {
+			failf(fset.Position(st.Field.Pos()), "region `%s` can't have events, attributes and options other than region", name)
+		}
+		fieldPrefix := strings.ToLower(string(name[:1])) + string(name[1:])
+		regions = append(regions, region{
+			Name:        string(name),
+			StateField:  fieldPrefix + "State",
+			States:      st.Children,
+			Initial:     st.InitialChild,
+			InitialLeaf: definition.leafOf(st.InitialChild),
+			Field:       st.Field,
+		})
+	}
+	if len(regions) == 0 {
+		return nil, definition.TopLevel
+	}
+
+	var topLevel []state
+	for _, r := range regions {
+		delete(definition.States, state(r.Name))
+		for _, name := range r.States {
+			st := definition.States[name]
+			st.Parent = ""
+			definition.States[name] = st
+			assignRegion(definition, name, r)
+		}
+		topLevel = append(topLevel, r.States...)
+	}
+	verifyRegions(fset, definition)
+	return regions, topLevel
+}
+
+func assignRegion(definition machineDefinition, name state, r region) {
+	st := definition.States[name]
+	st.Region = r.Name
+	st.StateField = r.StateField
+	definition.States[name] = st
+	for _, child := range st.Children {
+		assignRegion(definition, child, r)
+	}
+}
+
+// verifyRegions checks that transitions don't cross regions
+// and the same event isn't declared in different regions
+func verifyRegions(fset *token.FileSet, definition machineDefinition) {
+	eventRegions := map[event]string{}
+	for _, name := range sortedStates(definition.States) {
+		st := definition.States[name]
+		for _, ev := range sortedEvents(st.Events) {
+			dst := st.Events[ev].Destination
+			if dstDef, ok := definition.States[dst]; ok && dstDef.Region != st.Region {
+				failf(
+					fset.Position(st.Field.Pos()),
+					"You've defined (%v) -%v-> (%v). But `%v` belongs to region `%v` and `%v` to region `%v`",
+					name, ev, dst, name, st.Region, dst, dstDef.Region,
+				)
+			}
+			if r, ok := eventRegions[ev]; ok && r != st.Region {
+				failf(
+					fset.Position(st.Field.Pos()),
+					"event `%v` of state `%v` is ambiguous, because it is declared in regions `%v` and `%v`",
+					ev, name, r, st.Region,
+				)
+			}
+			eventRegions[ev] = st.Region
+		}
+	}
+}
+
+func describeRegions(builder *strings.Builder, definition machineDefinition) {
+	for _, r := range definition.Regions {
+		builder.WriteString("	__initial_")
+		builder.WriteString(r.Name)
+		builder.WriteString(" [shape=point];\n")
+		builder.WriteString("	__initial_")
+		builder.WriteString(r.Name)
+		builder.WriteString(" -> ")
+		builder.WriteString(string(r.InitialLeaf))
+		if definition.States[r.Initial].IsComposite {
+			builder.WriteString(" [lhead=cluster_")
+			builder.WriteString(string(r.Initial))
+			builder.WriteString("]")
+		}
+		builder.WriteString(";\n")
+		builder.WriteString("	subgraph cluster_")
+		builder.WriteString(r.Name)
+		builder.WriteString(" {\n")
+		builder.WriteString("		label=")
+		builder.WriteString(r.Name)
+		builder.WriteString(";\n")
+		builder.WriteString("		style=dashed;\n")
+		describeClusters(builder, definition, r.States, "		")
+		builder.WriteString("	}\n")
+	}
+}
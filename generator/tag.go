@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tagPair struct {
+	Key   string
+	Value string
+	// Offset is byte offset of the pair key in tag literal, counting opening quote
+	Offset int
+}
+
+// parseTag splits field tag literal into key:"value" pairs.
+// Both conventional space-separated form `A:"x" B:"y"` and legacy
+// comma-separated form `A:"x",B:"y"` are supported. Values are unquoted
+// using Go quoting rules, so they can contain spaces, commas and colons.
+func parseTag(literal string) ([]tagPair, error) {
+	tag, err := strconv.Unquote(literal)
+	if err != nil {
+		return nil, fmt.Errorf("tag %s is not a valid string literal", literal)
+	}
+	offsets := literalOffsets(literal)
+	unquotedLen := len(tag)
+
+	var pairs []tagPair
+	for number := 1; ; number++ {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			return pairs, nil
+		}
+
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != ',' && tag[i] != 0x7f {
+			i++
+		}
+		key, offset := tag[:i], offsets[unquotedLen-len(tag)]
+		if key == "" {
+			return nil, fmt.Errorf("pair #%d `%s` has no key", number, nextPair(tag))
+		}
+		if i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return nil, fmt.Errorf("pair #%d `%s` should have form %s:\"value\"", number, nextPair(tag), key)
+		}
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, fmt.Errorf("pair #%d `%s:%s` has unterminated value", number, key, tag)
+		}
+		quotedValue := tag[:i+1]
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(quotedValue)
+		if err != nil {
+			return nil, fmt.Errorf("pair #%d `%s:%s` has malformed value: %v", number, key, quotedValue, err)
+		}
+		pairs = append(pairs, tagPair{Key: key, Value: value, Offset: offset})
+
+		switch {
+		case tag == "":
+		case tag[0] == ' ':
+		case tag[0] == ',':
+			tag = tag[1:]
+		default:
+			return nil, fmt.Errorf("pair #%d `%s:%s` should be separated from `%s` by space", number, key, quotedValue, nextPair(tag))
+		}
+	}
+}
+
+// literalOffsets maps every byte of unquoted string literal to offset in the literal
+// where the character it belongs to starts. Literal should be valid.
+func literalOffsets(literal string) []int {
+	var offsets []int
+	if literal[0] == '`' {
+		for i := 1; i < len(literal)-1; i++ {
+			if literal[i] != '\r' {
+				offsets = append(offsets, i)
+			}
+		}
+		return offsets
+	}
+	for rest := literal[1 : len(literal)-1]; rest != ""; {
+		offset := len(literal) - 1 - len(rest)
+		value, multibyte, tail, err := strconv.UnquoteChar(rest, '"')
+		if err != nil {
+			break
+		}
+		size := 1
+		if value >= utf8.RuneSelf && multibyte {
+			size = utf8.RuneLen(value)
+		}
+		for ; size > 0; size-- {
+			offsets = append(offsets, offset)
+		}
+		rest = tail
+	}
+	return offsets
+}
+
+// parseEventKey parses tag key of event that has form `Event` or `Event(PayloadType)`
+func parseEventKey(key string) (event, string, error) {
+	name, payload := key, ""
+	if i := strings.Index(key, "("); i >= 0 {
+		if !strings.HasSuffix(key, ")") {
+			return "", "", fmt.Errorf("payload of event `%s` should be closed with `)`", key[:i])
+		}
+		name, payload = key[:i], key[i+1:len(key)-1]
+		if payload == "" {
+			return "", "", fmt.Errorf("event `%s` has empty payload type", name)
+		}
+	}
+	if !isIdentifier(name) {
+		return "", "", fmt.Errorf("event `%s` is not a valid identifier", name)
+	}
+	return event(name), payload, nil
+}
+
+// parseTransition parses transition declared in tag value.
+// Value has form `Destination`, `Destination [Guard]` or `Destination [Guard] / Command`.
+// Destination of composite state can refer to its history as `Destination.H` or `Destination.H*`.
+// Events named like `After100ms` are timed and taken automatically once the delay passes.
+func parseTransition(ev event, value string) (transition, error) {
+	tr := transition{Event: ev, After: parseTimer(ev)}
+	rest := strings.TrimSpace(value)
+
+	i := strings.IndexAny(rest, " [/")
+	if i < 0 {
+		i = len(rest)
+	}
+	tr.Destination = state(rest[:i])
+	for _, marker := range []string{deepHistory, shallowHistory} {
+		if strings.HasSuffix(string(tr.Destination), "."+marker) {
+			tr.Destination = tr.Destination[:len(tr.Destination)-len(marker)-1]
+			tr.History = marker
+			break
+		}
+	}
+	if !isIdentifier(string(tr.Destination)) {
+		return tr, fmt.Errorf("destination `%s` of event `%s` is not a valid identifier", tr.Destination, ev)
+	}
+	rest = strings.TrimSpace(rest[i:])
+
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return tr, fmt.Errorf("guard of event `%s` in `%s` should be closed with `]`", ev, value)
+		}
+		tr.Guard = strings.TrimSpace(rest[1:end])
+		if !isIdentifier(tr.Guard) {
+			return tr, fmt.Errorf("guard `%s` of event `%s` is not a valid identifier", tr.Guard, ev)
+		}
+		if token.Lookup(tr.Guard).IsKeyword() {
+			return tr, fmt.Errorf("guard `%s` of event `%s` is a Go keyword", tr.Guard, ev)
+		}
+		rest = strings.TrimSpace(rest[end+1:])
+	}
+
+	if strings.HasPrefix(rest, "/") {
+		tr.Command = strings.TrimSpace(rest[1:])
+		if !isIdentifier(tr.Command) {
+			return tr, fmt.Errorf("command `%s` of event `%s` is not a valid identifier", tr.Command, ev)
+		}
+		rest = ""
+	}
+
+	if rest != "" {
+		return tr, fmt.Errorf("unexpected `%s` in transition `%s` of event `%s`", rest, value, ev)
+	}
+	return tr, nil
+}
+
+// nextPair returns the beginning of tag up to the next separator for error reporting
+func nextPair(tag string) string {
+	if i := strings.IndexAny(tag, " ,"); i > 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
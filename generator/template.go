@@ -5,56 +5,254 @@ import "text/template"
 var embeddedTemplate = template.Must(template.New("embedded").Parse(`
 	package {{.PkgName}}
 	
+	{{- if eq (len .Imports) 1}}
 	import "fmt"
+	{{- else}}
+	import (
+		{{- range .Imports}}
+		{{.}}
+		{{- end}}
+	)
+	{{- end}}
 
 	// Generated by go-fsm-generator. DO NOT EDIT.
+	//fsm:fingerprint {{.Declaration}} {{.Fingerprint}}
 
 	//+++ General machine definition +++
 	{{$mName := .MachineName}}
 	// {{$mName}}State type definition
 	type {{$mName}}State int
 	const (
+		{{- if not .HasValues}}
 		_ {{$mName}}State = iota
-		{{- range $st, $stDef := .States}}
-			{{$st}} // {{$st}} state
+		{{- end}}
+		{{- range $stDef := .OrderedStates}}{{$st := $stDef.Name}}
+			{{- range $stDef.Doc}}
+			// {{.}}
+			{{- end}}
+			{{$.StateConst $st}}{{if $.HasValues}} {{$mName}}State = {{$stDef.Value}}{{end}}{{if not $stDef.Doc}} // {{$.StateConst $st}} {{if $stDef.IsComposite}}composite {{end}}state{{end}}
 		{{- end}}
 	)
 
 	var _{{$mName}}StateMap = map[{{$mName}}State]string{
-		{{- range $st, $stDef := .States}}
-			{{$st}}: "{{$st}}",
+		{{- range $stDef := .OrderedStates}}{{$st := $stDef.Name}}
+			{{$.StateConst $st}}: "{{$st}}",
+		{{- end}}
+	}
+
+	{{- if .Regions}}
+	{{- range $r := .Regions}}
+
+	var _{{$mName}}{{$r.Name}}ParsingStateMap = map[string]{{$mName}}State{
+		{{- range $stDef := $.OrderedLeaves}}{{$st := $stDef.Name}}
+		{{- if eq $stDef.Region $r.Name}}
+			"{{$st}}": {{$.StateConst $st}},
+		{{- end}}
 		{{- end}}
 	}
+	{{- end}}
+	{{- else}}
 
 	var _{{$mName}}ParsingStateMap = map[string]{{$mName}}State{
-		{{- range $st, $stDef := .States}}
-			"{{$st}}": {{$st}},
+		{{- range $stDef := .OrderedLeaves}}{{$st := $stDef.Name}}
+			"{{$st}}": {{$.StateConst $st}},
 		{{- end}}
 	}
+	{{- end}}
 
 	func (s {{$mName}}State) String() string {
 		return _{{$mName}}StateMap[s]
 	}
+	{{- if .HasHierarchy}}
+
+	var _{{$mName}}ParentStateMap = map[{{$mName}}State]{{$mName}}State{
+		{{- range $stDef := .OrderedStates}}{{$st := $stDef.Name}}
+		{{- if $stDef.Parent}}
+			{{$.StateConst $st}}: {{$.StateConst $stDef.Parent}},
+		{{- end}}
+		{{- end}}
+	}
+
+	// Parent returns composite state that contains s
+	func (s {{$mName}}State) Parent() {{$mName}}State {
+		return _{{$mName}}ParentStateMap[s]
+	}
+
+	// In reports whether s is the same as state or nested inside of it
+	func (s {{$mName}}State) In(state {{$mName}}State) bool {
+		for ; s != 0; s = s.Parent() {
+			if s == state {
+				return true
+			}
+		}
+		return false
+	}
+	{{- end}}
+	{{- range .Attributes}}
+
+	var _{{$mName}}{{.Method}}Attribute = map[{{$mName}}State]{{.Type}}{
+		{{- range .Values}}
+		{{$.StateConst .State}}: {{.Literal}},
+		{{- end}}
+	}
+
+	// {{.Method}} returns {{.Name}} attribute of the state or zero value if state has no such attribute
+	func (s {{$mName}}State) {{.Method}}() {{.Type}} {
+		return _{{$mName}}{{.Method}}Attribute[s]
+	}
+	{{- end}}
 
 	// {{$mName}}Behaviour definition
 	type {{$mName}}Behaviour interface {
-	{{- range $st, $stDef := .States}}
+	{{- range $stDef := .OrderedStates}}{{$st := $stDef.Name}}
 		{{- if ($stDef.IsTerminal)}}
-		{{else}}
-		{{$mName}}{{$st}}State
+		{{else if $stDef.IsComposite}}
+		{{- else}}
+		{{$stDef.InterfacePrefix $mName}}State
+		{{- if $stDef.Guards}}
+		{{$stDef.InterfacePrefix $mName}}Guards
+		{{- end}}
+		{{- end}}
+		{{- if $stDef.HasEntry}}
+		{{$stDef.InterfacePrefix $mName}}Entry
+		{{- end}}
+		{{- if $stDef.HasExit}}
+		{{$stDef.InterfacePrefix $mName}}Exit
+		{{- end}}
+		{{- if $stDef.Listeners}}
+		{{$mName}}{{$st}}Listener
 		{{- end}}
 	{{- end}}
 	}
 	
+	{{- if .Regions}}
+	// {{$mName}} machine type with orthogonal regions
+	type {{$mName}} struct {
+		{{- range .Regions}}
+		{{.StateField}} {{$mName}}State
+		{{- end}}
+		{{- range .Histories}}
+		{{.Field}} {{$mName}}State
+		{{- end}}
+		{{- if .HasTimers}}
+		clock {{$mName}}Clock
+		{{- range .TimerFields}}
+		{{.}} time.Time
+		{{- end}}
+		{{- end}}
+	}
+
+	// New{{$mName}} creates machine with every region in its initial state
+	func New{{$mName}}() *{{$mName}} {
+		return &{{$mName}}{
+			{{- range .Regions}}
+			{{.StateField}}: {{$.StateConst .InitialLeaf}},
+			{{- end}}
+		}
+	}
+
+	// New{{$mName}}FromString can be used to deserialize machine state
+	// serialized by String method as comma-separated states of regions
+	func New{{$mName}}FromString(stateStr string) (*{{$mName}}, error) {
+		{{- if .Histories}}
+		parts := strings.Split(stateStr, ";")
+		states := strings.Split(parts[0], ",")
+		{{- else}}
+		states := strings.Split(stateStr, ",")
+		{{- end}}
+		if len(states) != {{len .Regions}} {
+			return nil, fmt.Errorf("state unknown for {{$mName}}: %s", stateStr)
+		}
+		m := &{{$mName}}{}
+		var ok bool
+		{{- range $i, $r := .Regions}}
+		m.{{$r.StateField}}, ok = _{{$mName}}{{$r.Name}}ParsingStateMap[states[{{$i}}]]
+		if !ok {
+			return nil, fmt.Errorf("state unknown for {{$mName}} region {{$r.Name}}: %s", states[{{$i}}])
+		}
+		{{- end}}
+		{{- if .Histories}}
+		if err := m.restoreHistory(parts[1:]); err != nil {
+			return nil, err
+		}
+		{{- end}}
+		return m, nil
+	}
+
+	// String returns comma-separated states of {{$mName}} regions
+	func (m *{{$mName}}) String() string {
+		return strings.Join([]string{
+			{{- range .Regions}}
+			m.{{.StateField}}.String(),
+			{{- end}}
+		}, ","){{if .Histories}} + m.historyString(){{end}}
+	}
+	{{- range .Regions}}
+
+	// Current{{.Name}} returns current state of {{$mName}} in region {{.Name}}
+	func (m *{{$mName}}) Current{{.Name}}() {{$mName}}State {
+		return m.{{.StateField}}
+	}
+	{{- end}}
+
+	// In reports whether any region of {{$mName}} is in state
+	func (m *{{$mName}}) In(state {{$mName}}State) bool {
+		return {{range $i, $r := .Regions}}{{if $i}} || {{end}}m.{{$r.StateField}}{{if $.HasHierarchy}}.In(state){{else}} == state{{end}}{{end}}
+	}
+
+	// Terminated reports whether all regions of {{$mName}} are in terminal states
+	func (m *{{$mName}}) Terminated() bool {
+		return {{range $i, $r := .Regions}}{{if $i}} && {{end}}_{{$mName}}TerminalStates[m.{{$r.StateField}}]{{end}}
+	}
+
+	var _{{$mName}}TerminalStates = map[{{$mName}}State]bool{
+		{{- range $stDef := .OrderedLeaves}}{{$st := $stDef.Name}}
+		{{- if $stDef.IsTerminal}}
+			{{$.StateConst $st}}: true,
+		{{- end}}
+		{{- end}}
+	}
+	{{- else}}
 	// {{$mName}} machine type
 	type {{$mName}} struct {
 		state {{$mName}}State
+		{{- range .Histories}}
+		{{.Field}} {{$mName}}State
+		{{- end}}
+		{{- if .HasTimers}}
+		clock {{$mName}}Clock
+		{{- range .TimerFields}}
+		{{.}} time.Time
+		{{- end}}
+		{{- end}}
 	}
 	
-	// New{{$mName}} creates machine with specified initial state
-	func New{{$mName}}(state {{$mName}}State) *{{$mName}} {
-		return &{{$mName}}{state: state}
+	// New{{$mName}} creates machine in its initial state {{$.StateConst .InitialLeaf}}
+	func New{{$mName}}() *{{$mName}} {
+		return &{{$mName}}{state: {{$.StateConst .InitialLeaf}}}
+	}
+
+	{{- if .Histories}}
+
+	// New{{$mName}}FromString can be used to deserialize machine state serialized by String method
+	func New{{$mName}}FromString(stateStr string) (*{{$mName}}, error) {
+		parts := strings.Split(stateStr, ";")
+		state, ok := _{{$mName}}ParsingStateMap[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("state unknown for {{$mName}}: %s", parts[0])
+		}
+		m := &{{$mName}}{state: state}
+		if err := m.restoreHistory(parts[1:]); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	// String returns current state of {{$mName}} with history of composite states
+	func (m *{{$mName}}) String() string {
+		return m.state.String() + m.historyString()
 	}
+	{{- else}}
 
 	// New{{$mName}}FromString can be used to deserialize  machine state
 	func New{{$mName}}FromString(stateStr string) (*{{$mName}}, error) {
@@ -64,25 +262,307 @@ var embeddedTemplate = template.Must(template.New("embedded").Parse(`
 		}
 		return &{{$mName}}{state: state}, nil
 	}
+	{{- end}}
 
 	// Current returns current state of {{$mName}}
 	func (m *{{$mName}}) Current() {{$mName}}State {
 		return m.state
 	}
+	{{- end}}
+
+	{{- if .HasTimers}}
+
+	// {{$mName}}Clock provides current time for timed transitions of {{$mName}}
+	type {{$mName}}Clock interface {
+		Now() time.Time
+	}
+
+	// WithClock replaces clock used by timed transitions of {{$mName}}, so tests can control time.
+	// Timers of the current states restart from the next Operate call.
+	func (m *{{$mName}}) WithClock(clock {{$mName}}Clock) *{{$mName}} {
+		m.clock = clock
+		{{- range .TimerFields}}
+		m.{{.}} = time.Time{}
+		{{- end}}
+		return m
+	}
+
+	// now returns current time of {{$mName}} clock
+	func (m *{{$mName}}) now() time.Time {
+		if m.clock == nil {
+			return time.Now()
+		}
+		return m.clock.Now()
+	}
+	{{- end}}
 	
+	{{- if .Histories}}
+
+	// historyString serializes history of composite states
+	func (m *{{$mName}}) historyString() string {
+		result := ""
+		{{- range .Histories}}
+		if m.{{.Field}} != 0 {
+			result += ";{{.Name}}=" + m.{{.Field}}.String()
+		}
+		{{- end}}
+		return result
+	}
+
+	// restoreHistory deserializes history of composite states
+	func (m *{{$mName}}) restoreHistory(entries []string) error {
+		for _, entry := range entries {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("history unknown for {{$mName}}: %s", entry)
+			}
+			var ok bool
+			switch parts[0] {
+			{{- range .Histories}}
+			case "{{.Name}}":
+				m.{{.Field}}, ok = _{{$mName}}{{.Method}}ParsingStateMap[parts[1]]
+			{{- end}}
+			default:
+				return fmt.Errorf("history unknown for {{$mName}}: %s", entry)
+			}
+			if !ok {
+				return fmt.Errorf("state unknown for {{$mName}} history %s: %s", parts[0], parts[1])
+			}
+		}
+		return nil
+	}
+	{{- range $h := .Histories}}
+
+	var _{{$mName}}{{$h.Method}}ParsingStateMap = map[string]{{$mName}}State{
+		{{- range $st, $entered := $h.Values}}
+			"{{$st}}": {{$.StateConst $st}},
+		{{- end}}
+	}
+
+	// restore{{$h.Method}} returns {{if $h.Deep}}the last active leaf state{{else}}the last active sub-state{{end}} of {{$.StateConst $h.Composite}} or its initial state
+	func (m *{{$mName}}) restore{{$h.Method}}() {{$mName}}State {
+		if m.{{$h.Field}} == 0 {
+			return {{$.StateConst $h.Default}}
+		}
+		return m.{{$h.Field}}
+	}
+	{{- if $h.HasEntryActions}}
+
+	// enter{{$h.Method}} executes entry actions of states inside of {{$.StateConst $h.Composite}} restored from history
+	func (m *{{$mName}}) enter{{$h.Method}}(operator {{$mName}}Behaviour) {
+		switch m.{{$h.StateField}} {
+		{{- range $st, $entered := $h.Values}}
+		{{- if $entered}}
+		case {{$.StateConst $st}}:
+			{{- range $entered}}
+			operator.On{{$.OriginOf .}}Enter()
+			{{- end}}
+		{{- end}}
+		{{- end}}
+		}
+	}
+	{{- end}}
+	{{- end}}
+	{{- end}}
+
+	{{- if .HasAuto}}
+
+	var _{{$mName}}AutoStates = map[{{$mName}}State]bool{
+		{{- range $stDef := .OrderedLeaves}}{{$st := $stDef.Name}}
+		{{- if $stDef.IsAuto}}
+			{{$.StateConst $st}}: true,
+		{{- end}}
+		{{- end}}
+	}
+	{{- end}}
+
+	{{- if .HasGuards}}
+
+	// {{$mName}}GuardError reports transition of {{$mName}} refused by guard
+	type {{$mName}}GuardError struct {
+		State {{$mName}}State
+		Event string
+		Guard string
+	}
+
+	func (e *{{$mName}}GuardError) Error() string {
+		return fmt.Sprintf("{{$mName}} transition %s from %s refused by guard %s", e.Event, e.State, e.Guard)
+	}
+	{{- end}}
+
 	// Operate executes behaviour for the current state {{$mName}}
-	func (m *{{$mName}}) Operate(operator {{$mName}}Behaviour) {
+	{{- if .HasGuards}}.
+	// If guard refuses transition, machine remains in current state and *{{$mName}}GuardError is returned.
+	{{- end}}
+	{{- if .HasActions}}
+	// On transition exit action of the current state is executed first,
+	// then state is changed and entry action of the destination state is executed.
+	{{- end}}
+	{{- if .HasAuto}}
+	// After transition to automatic state its behaviour is executed immediately, until stable state is reached.
+	{{- end}}
+	{{- if .HasCommands}}
+	// Commands produced by transitions are returned in order they were produced.
+	{{- end}}
+	{{- if .Regions}}
+	// Every region is operated independently{{if .HasGuards}} and the first guard error is returned{{end}}.
+	func (m *{{$mName}}) Operate(operator {{$mName}}Behaviour){{.OperateResults}} {
+		{{- if .HasCommands}}
+		var commands []{{$mName}}Command
+		{{- end}}
+		{{- if .HasGuards}}
+		var result error
+		{{- end}}
+		{{- range $r := .Regions}}
+		{{- if $.HasAuto}}
+		for {
+		previous := m.{{$r.StateField}}
+		{{- end}}
+		switch m.{{$r.StateField}} {
+			{{- range $stDef := $.OrderedLeaves}}{{$st := $stDef.Name}}
+			{{- if and (eq $stDef.Region $r.Name) (not $stDef.IsTerminal)}}
+			case {{$.StateConst $st}}:
+				{{- if $.HasCommands}}
+				command{{if $.HasGuards}}, err{{end}} := m.handle{{$st}}Event({{if $.NeedsOperator}}operator, {{end}}{{if $stDef.Timers}}m.operate{{$st}}(operator){{else}}operator.Operate{{$stDef.Origin}}(){{end}})
+				if command != 0 {
+					commands = append(commands, command)
+				}
+				{{- if $.HasGuards}}
+				if err != nil && result == nil {
+					result = err
+				}
+				{{- end}}
+				{{- else if $.HasGuards}}
+				if err := m.handle{{$st}}Event(operator, {{if $stDef.Timers}}m.operate{{$st}}(operator){{else}}operator.Operate{{$stDef.Origin}}(){{end}}); err != nil && result == nil {
+					result = err
+				}
+				{{- else}}
+				m.handle{{$st}}Event({{if $.NeedsOperator}}operator, {{end}}{{if $stDef.Timers}}m.operate{{$st}}(operator){{else}}operator.Operate{{$stDef.Origin}}(){{end}})
+				{{- end}}
+			{{- end}}
+			{{- end}}
+		}
+		{{- if $.HasAuto}}
+		if m.{{$r.StateField}} == previous || !_{{$mName}}AutoStates[m.{{$r.StateField}}] {
+			break
+		}
+		}
+		{{- end}}
+		{{- end}}
+		{{- if .HasCommands}}
+		return commands{{if .HasGuards}}, result{{end}}
+		{{- else if .HasGuards}}
+		return result
+		{{- end}}
+	}
+	{{- else}}
+	{{- if .HasCommands}}
+	func (m *{{$mName}}) Operate(operator {{$mName}}Behaviour){{.OperateResults}} {
+		var commands []{{$mName}}Command
+		{{- if .HasAuto}}
+		for {
+		previous := m.state
+		{{- end}}
+		command{{if .HasGuards}}, err{{end}} := m.operateCurrent(operator)
+		if command != 0 {
+			commands = append(commands, command)
+		}
+		{{- if .HasAuto}}
+		{{- if .HasGuards}}
+		if err != nil {
+			return commands, err
+		}
+		{{- end}}
+		if m.state == previous || !_{{$mName}}AutoStates[m.state] {
+			return commands{{if .HasGuards}}, nil{{end}}
+		}
+		}
+		{{- else}}
+		return commands{{if .HasGuards}}, err{{end}}
+		{{- end}}
+	}
+
+	// operateCurrent executes behaviour for the current state {{$mName}} once
+	{{- else if .HasAuto}}
+	func (m *{{$mName}}) Operate(operator {{$mName}}Behaviour){{if .HasGuards}} error{{end}} {
+		for {
+			previous := m.state
+			{{- if .HasGuards}}
+			if err := m.operateCurrent(operator); err != nil {
+				return err
+			}
+			{{- else}}
+			m.operateCurrent(operator)
+			{{- end}}
+			if m.state == previous || !_{{$mName}}AutoStates[m.state] {
+				return{{if .HasGuards}} nil{{end}}
+			}
+		}
+	}
+
+	// operateCurrent executes behaviour for the current state {{$mName}} once
+	{{- end}}
+	func (m *{{$mName}}) {{if or .HasAuto .HasCommands}}operateCurrent{{else}}Operate{{end}}(operator {{$mName}}Behaviour){{.HandlerResults}} {
 		switch m.state {
-			{{- range $st, $stDef := .States}}
+			{{- range $stDef := .OrderedLeaves}}{{$st := $stDef.Name}}
 				{{- if ($stDef.IsTerminal)}}
-				case {{$st}}:
-					return
+				case {{$.StateConst $st}}:
+					return{{$.ZeroResults}}
 				{{- else}}
-				case {{$st}}:
-					m.handle{{$st}}Event(operator.Operate{{$st}}())
+				case {{$.StateConst $st}}:
+					{{if or $.HasGuards $.HasCommands}}return {{end}}m.handle{{$st}}Event({{if $.NeedsOperator}}operator, {{end}}{{if $stDef.Timers}}m.operate{{$st}}(operator){{else}}operator.Operate{{$stDef.Origin}}(){{end}})
 				{{- end}}
 			{{- end}}
 		}
+		{{- if or .HasGuards .HasCommands}}
+		return{{.ZeroResults}}
+		{{- end}}
+	}
+	{{- end}}
+
+	// {{$mName}}EventNotAllowedError reports event fired in state of {{$mName}} that doesn't declare it
+	type {{$mName}}EventNotAllowedError struct {
+		State {{$mName}}State
+		Event {{$mName}}Event
+	}
+
+	func (e *{{$mName}}EventNotAllowedError) Error() string {
+		return fmt.Sprintf("{{$mName}} event %s not allowed in state %s", e.Event, e.State)
+	}
+
+	// Fire executes transition declared for event in the current state {{$mName}}.
+	// If current state doesn't declare event, machine remains in it and *{{$mName}}EventNotAllowedError is returned.
+	{{- if .HasGuards}}
+	// If guard refuses transition, *{{$mName}}GuardError is returned.
+	{{- end}}
+	// Fire doesn't execute state behaviour{{if .HasAuto}}, so automatic states are passed through only by Operate{{end}}.
+	{{- if .HasCommands}}
+	// Command produced by transition is returned.
+	{{- end}}
+	func (m *{{$mName}}) Fire({{if .NeedsOperator}}operator {{$mName}}Behaviour, {{end}}ev {{$mName}}Event){{if .HasCommands}} ({{$mName}}Command, error){{else}} error{{end}} {
+		switch ev{{if .HasPayloads}}.id{{end}} {
+		{{- range $e := .Events}}
+		case {{if $.HasPayloads}}_{{end}}{{$mName}}Event{{$e.Name}}:
+			switch m.{{$e.StateField}} {
+			{{- range $stDef := $.OrderedLeaves}}{{$st := $stDef.Name}}
+			{{- $tr := index $stDef.Transitions $e.Name}}
+			{{- if $tr.Event}}
+			case {{$.StateConst $st}}:
+				{{- if $.HasGuards}}
+				return m.handle{{$st}}Event({{if $.NeedsOperator}}operator, {{end}}{{$.EventConst $stDef $e.Name}}{{if $tr.Payload}}(ev.{{$tr.PayloadField}}){{end}})
+				{{- else if $.HasCommands}}
+				return m.handle{{$st}}Event({{if $.NeedsOperator}}operator, {{end}}{{$.EventConst $stDef $e.Name}}{{if $tr.Payload}}(ev.{{$tr.PayloadField}}){{end}}), nil
+				{{- else}}
+				m.handle{{$st}}Event({{if $.NeedsOperator}}operator, {{end}}{{$.EventConst $stDef $e.Name}}{{if $tr.Payload}}(ev.{{$tr.PayloadField}}){{end}})
+				return nil
+				{{- end}}
+			{{- end}}
+			{{- end}}
+			}
+			return {{if $.HasCommands}}0, {{end}}&{{$mName}}EventNotAllowedError{State: m.{{$e.StateField}}, Event: ev}
+		{{- end}}
+		}
+		return {{if .HasCommands}}0, {{end}}&{{$mName}}EventNotAllowedError{{if .Regions}}{Event: ev}{{else}}{State: m.state, Event: ev}{{end}}
 	}
 
 	// Visualize states and events for {{$mName}} in Graphviz format
@@ -91,52 +571,325 @@ var embeddedTemplate = template.Must(template.New("embedded").Parse(`
 	}
 
 	// Handlers for state transitions
-	{{range $st, $stDef := .States}}
+	{{range $stDef := .OrderedLeaves}}{{$st := $stDef.Name}}
 	{{- if ($stDef.IsTerminal)}}
 	{{- else}}
-		func (m *{{$mName}}) handle{{$st}}Event(event {{$mName}}{{$st}}Event) {
-			switch event {
-			{{- range $ev, $dst := $stDef.Events}}
-			case {{$st}}{{$ev}}:
-				m.state = {{$dst}}
+		func (m *{{$mName}}) handle{{$st}}Event({{if $.NeedsOperator}}operator {{$mName}}Behaviour, {{end}}event {{$stDef.InterfacePrefix $mName}}Event){{$.HandlerResults}} {
+			switch event{{if $stDef.HasPayloads}}.id{{end}} {
+			{{- range $tr := $stDef.OrderedTransitions}}{{$ev := $tr.Event}}
+			case {{if $stDef.HasPayloads}}_{{end}}{{$.EventConst $stDef $ev}}:
+				{{- if $tr.Guard}}
+				if !operator.{{$tr.Guard}}() {
+					return {{if $.HasCommands}}0, {{end}}&{{$mName}}GuardError{State: {{$.StateConst $st}}, Event: event.String(), Guard: "{{$tr.Guard}}"}
+				}
+				{{- end}}
+				{{- range $tr.ExitActions}}
+				operator.On{{$.OriginOf .}}Exit()
+				{{- end}}
+				{{- range $tr.HistoryRecords}}
+				m.{{.Field}} = {{$.StateConst .State}}
+				{{- end}}
+				{{- if $tr.RestoredHistory}}
+				m.{{$stDef.StateField}} = m.restore{{$tr.RestoredHistory.Method}}()
+				{{- else}}
+				m.{{$stDef.StateField}} = {{$.StateConst $tr.Leaf}}
+				{{- end}}
+				{{- range $tr.StoppedTimers}}
+				m.{{.}} = time.Time{}
+				{{- end}}
+				{{- range $tr.StartedTimers}}
+				m.{{.}} = m.now()
+				{{- end}}
+				{{- range $tr.EntryActions}}
+				operator.On{{$.OriginOf .}}Enter()
+				{{- end}}
+				{{- if $tr.RestoredHistory}}
+				{{- if $tr.RestoredHistory.HasEntryActions}}
+				m.enter{{$tr.RestoredHistory.Method}}(operator)
+				{{- end}}
+				{{- end}}
+				{{- if $tr.Payload}}
+				operator.On{{$.OriginOf $tr.Destination}}{{$ev}}(event.{{$tr.PayloadField}})
+				{{- end}}
+				{{- if $tr.Command}}
+				return {{$mName}}Command{{$tr.Command}}{{if $.HasGuards}}, nil{{end}}
+				{{- end}}
+			{{- end}}
+			case {{if $stDef.HasPayloads}}_{{end}}{{$.EventConst $stDef "Noop"}}:
+			}
+			{{- if or $.HasGuards $.HasCommands}}
+			return{{$.ZeroResults}}
+			{{- end}}
+		}
+		{{- if $stDef.Timers}}
+
+		// operate{{$st}} takes the expired timed transition or executes behaviour of {{$.StateConst $st}}
+		func (m *{{$mName}}) operate{{$st}}(operator {{$mName}}Behaviour) {{$stDef.InterfacePrefix $mName}}Event {
+			now := m.now()
+			{{- range $stDef.TimerFields}}
+			if m.{{.}}.IsZero() {
+				m.{{.}} = now
+			}
 			{{- end}}
-			case {{$st}}Noop:
+			{{- range $stDef.Timers}}
+			if now.Sub(m.{{.Field}}) >= {{.Duration}} {
+				return {{$.EventConst $stDef .Event}}
 			}
+			{{- end}}
+			return operator.Operate{{$stDef.Origin}}()
 		}
+		{{- end}}
 	{{- end}}
 	{{end}}
 
 	//--- Here we will define all events ---
-	{{range $st, $stDef := .States}}
-		{{if ($stDef.IsTerminal)}}
+
+	//=== {{$mName}}Event definition ===
+	{{- if .HasPayloads}}
+
+	// {{$mName}}Event is event of any state of {{$mName}} that can be fired, some of events carry payload
+	type {{$mName}}Event struct {
+		id int
+		{{- range .Events}}
+		{{- if .Payload}}
+		{{.PayloadField}} {{.Payload}}
+		{{- end}}
+		{{- end}}
+	}
+
+	const (
+		{{- if not .HasValues}}
+		_ = iota
+		{{- end}}
+		{{- range .Events}}
+		_{{$mName}}Event{{.Name}}{{if $.HasValues}} = {{.Value}}{{end}}
+		{{- end}}
+	)
+
+	var (
+		{{- range .Events}}
+		{{- if not .Payload}}
+		{{$mName}}Event{{.Name}} = {{$mName}}Event{id: _{{$mName}}Event{{.Name}}}
+		{{- end}}
+		{{- end}}
+	)
+	{{- range .Events}}
+	{{- if .Payload}}
+
+	// {{$mName}}Event{{.Name}} creates event {{.Name}} that carries payload
+	func {{$mName}}Event{{.Name}}(payload {{.Payload}}) {{$mName}}Event {
+		return {{$mName}}Event{id: _{{$mName}}Event{{.Name}}, {{.PayloadField}}: payload}
+	}
+	{{- end}}
+	{{- end}}
+
+	var _{{$mName}}EventMap = map[int]string{
+		{{- range .Events}}
+		_{{$mName}}Event{{.Name}}: "{{.Name}}",
+		{{- end}}
+	}
+
+	func (m {{$mName}}Event) String() string {
+		return _{{$mName}}EventMap[m.id]
+	}
+	{{- else}}
+
+	// {{$mName}}Event is event of any state of {{$mName}} that can be fired
+	type {{$mName}}Event int
+
+	const (
+		{{- if not .HasValues}}
+		_ {{$mName}}Event = iota
+		{{- end}}
+		{{- range .Events}}
+		{{$mName}}Event{{.Name}}{{if $.HasValues}} {{$mName}}Event = {{.Value}}{{end}}
+		{{- end}}
+	)
+
+	var _{{$mName}}EventMap = map[{{$mName}}Event]string{
+		{{- range .Events}}
+		{{$mName}}Event{{.Name}}: "{{.Name}}",
+		{{- end}}
+	}
+
+	func (m {{$mName}}Event) String() string {
+		return _{{$mName}}EventMap[m]
+	}
+	{{- end}}
+	{{range $stDef := .OrderedLeaves}}{{$st := $stDef.Name}}
+		{{if or $stDef.IsTerminal $stDef.Embedded}}
 		{{else}}
 		//=== {{$mName}}{{$st}}Event definition ===
+		{{- if $stDef.HasPayloads}}
+
+		// {{$mName}}{{$st}}Event definition, some of events carry payload
+		type {{$mName}}{{$st}}Event struct {
+			id int
+			{{- range $tr := $stDef.OrderedTransitions}}{{$ev := $tr.Event}}
+			{{- if $tr.Payload}}
+			{{$tr.PayloadField}} {{$tr.Payload}}
+			{{- end}}
+			{{- end}}
+		}
+
+		const (
+			{{- if not $.HasValues}}
+			_ = iota
+			{{- end}}
+			{{- range $tr := $stDef.OrderedTransitions}}{{$ev := $tr.Event}}
+			_{{$.EventConst $stDef $ev}}{{if $.HasValues}} = {{$tr.Value}}{{end}}
+			{{- end}}
+			_{{$.EventConst $stDef "Noop"}}{{if $.HasValues}} = {{$stDef.NoopValue}}{{end}}
+		)
+
+		var (
+			{{- range $tr := $stDef.OrderedTransitions}}{{$ev := $tr.Event}}
+			{{- if not $tr.Payload}}
+			{{- range $tr.Doc}}
+			// {{$.EventConst $stDef $ev}} {{.}}
+			{{- end}}
+			{{$.EventConst $stDef $ev}} = {{$mName}}{{$st}}Event{id: _{{$.EventConst $stDef $ev}}} // {{$.EventConst $stDef $ev}} -> {{$.StateConst $tr.Destination}}{{if $tr.Guard}} if {{$tr.Guard}}{{end}}{{if $tr.Command}} / {{$tr.Command}}{{end}}
+			{{- end}}
+			{{- end}}
+			{{$.EventConst $stDef "Noop"}} = {{$mName}}{{$st}}Event{id: _{{$.EventConst $stDef "Noop"}}} // remain in {{$.StateConst $st}}
+		)
+		{{- range $tr := $stDef.OrderedTransitions}}{{$ev := $tr.Event}}
+		{{- if $tr.Payload}}
+
+		// {{$.EventConst $stDef $ev}} creates event {{$.EventConst $stDef $ev}} -> {{$.StateConst $tr.Destination}}{{if $tr.Guard}} if {{$tr.Guard}}{{end}}{{if $tr.Command}} / {{$tr.Command}}{{end}} that carries payload
+		{{- range $tr.Doc}}
+		// {{$.EventConst $stDef $ev}} {{.}}
+		{{- end}}
+		func {{$.EventConst $stDef $ev}}(payload {{$tr.Payload}}) {{$mName}}{{$st}}Event {
+			return {{$mName}}{{$st}}Event{id: _{{$.EventConst $stDef $ev}}, {{$tr.PayloadField}}: payload}
+		}
+		{{- end}}
+		{{- end}}
+
+		var _{{$mName}}{{$st}}EventMap = map[int]string{
+			{{- range $tr := $stDef.OrderedTransitions}}{{$ev := $tr.Event}}
+				_{{$.EventConst $stDef $ev}}: "{{$st}}{{$ev}}",
+			{{- end}}
+			_{{$.EventConst $stDef "Noop"}}: "{{$st}}Noop",
+		}
+
+		func (m {{$mName}}{{$st}}Event) String() string {
+			return _{{$mName}}{{$st}}EventMap[m.id]
+		}
+		{{- else}}
 		
 		// {{$mName}}{{$st}}Event definition
 		type {{$mName}}{{$st}}Event int
 		const (
+			{{- if not $.HasValues}}
 			_ {{$mName}}{{$st}}Event = iota
-			{{- range $ev, $dst := $stDef.Events}}
-				{{$st}}{{$ev}} // {{$st}}{{$ev}} -> {{$dst}}
 			{{- end}}
-			{{$st}}Noop // remain in {{$st}}
+			{{- range $tr := $stDef.OrderedTransitions}}{{$ev := $tr.Event}}
+				{{- range $tr.Doc}}
+				// {{$.EventConst $stDef $ev}} {{.}}
+				{{- end}}
+				{{$.EventConst $stDef $ev}}{{if $.HasValues}} {{$mName}}{{$st}}Event = {{$tr.Value}}{{end}} // {{$.EventConst $stDef $ev}} -> {{$.StateConst $tr.Destination}}{{if $tr.Guard}} if {{$tr.Guard}}{{end}}{{if $tr.Command}} / {{$tr.Command}}{{end}}
+			{{- end}}
+			{{$.EventConst $stDef "Noop"}}{{if $.HasValues}} {{$mName}}{{$st}}Event = {{$stDef.NoopValue}}{{end}} // remain in {{$.StateConst $st}}
 		)
 
 		var _{{$mName}}{{$st}}EventMap = map[{{$mName}}{{$st}}Event]string{
-			{{- range $ev, $dst := $stDef.Events}}
-				{{$st}}{{$ev}}: "{{$st}}{{$ev}}",
+			{{- range $tr := $stDef.OrderedTransitions}}{{$ev := $tr.Event}}
+				{{$.EventConst $stDef $ev}}: "{{$st}}{{$ev}}",
 			{{- end}}
-			{{$st}}Noop: "{{$st}}Noop",
+			{{$.EventConst $stDef "Noop"}}: "{{$st}}Noop",
 		}
 
 		func (m {{$mName}}{{$st}}Event) String() string {
 			return _{{$mName}}{{$st}}EventMap[m]
 		}
+		{{- end}}
 		
 		// {{$mName}}{{$st}}State behaviour
 		type {{$mName}}{{$st}}State interface {
+			{{- range $stDef.Doc}}
+			// {{.}}
+			{{- end}}
+			{{- range $tr := $stDef.OrderedTransitions}}
+			{{- range $tr.Doc}}
+			// {{$.EventConst $stDef $tr.Event}} {{.}}
+			{{- end}}
+			{{- end}}
 			Operate{{$st}}() {{$mName}}{{$st}}Event
 		}
+		{{- if $stDef.Guards}}
+
+		// {{$mName}}{{$st}}Guards allow or refuse transitions from {{$.StateConst $st}}
+		type {{$mName}}{{$st}}Guards interface {
+			{{- range $stDef.Guards}}
+			{{.}}() bool
+			{{- end}}
+		}
+		{{- end}}
 		{{end}}
 	{{end}}
+
+	{{- if .HasActions}}
+
+	//--- Here we will define all state actions ---
+	{{- range $stDef := .OrderedStates}}{{$st := $stDef.Name}}
+		{{- if $stDef.Embedded}}
+		{{- else if $stDef.HasEntry}}
+
+		// {{$mName}}{{$st}}Entry action executed when machine enters {{$.StateConst $st}}
+		type {{$mName}}{{$st}}Entry interface {
+			On{{$st}}Enter()
+		}
+		{{- end}}
+		{{- if $stDef.Embedded}}
+		{{- else if $stDef.HasExit}}
+
+		// {{$mName}}{{$st}}Exit action executed when machine leaves {{$.StateConst $st}}
+		type {{$mName}}{{$st}}Exit interface {
+			On{{$st}}Exit()
+		}
+		{{- end}}
+	{{- end}}
+	{{- end}}
+
+	{{- if .HasPayloads}}
+
+	//--- Here we will define all payload listeners ---
+	{{- range $stDef := .OrderedStates}}{{$st := $stDef.Name}}
+		{{- if $stDef.Listeners}}
+
+		// {{$mName}}{{$st}}Listener receives payloads of events that lead to {{$.StateConst $st}}
+		type {{$mName}}{{$st}}Listener interface {
+			{{- range $stDef.Listeners}}
+			On{{$stDef.Origin}}{{.Event}}(payload {{.Payload}})
+			{{- end}}
+		}
+		{{- end}}
+	{{- end}}
+	{{- end}}
+
+	{{- if .HasCommands}}
+
+	//--- Here we will define all commands ---
+
+	// {{$mName}}Command is output of {{$mName}} transition that caller should execute
+	type {{$mName}}Command int
+
+	const (
+		_ {{$mName}}Command = iota
+		{{- range .Commands}}
+		{{$mName}}Command{{.}}
+		{{- end}}
+	)
+
+	var _{{$mName}}CommandMap = map[{{$mName}}Command]string{
+		{{- range .Commands}}
+		{{$mName}}Command{{.}}: "{{.}}",
+		{{- end}}
+	}
+
+	func (m {{$mName}}Command) String() string {
+		return _{{$mName}}CommandMap[m]
+	}
+	{{- end}}
 `))
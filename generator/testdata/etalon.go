@@ -1,12 +1,198 @@
 package testdata
 
+import "time"
+
 // SomeState placeholder type
 type FSMState int
 
 // SomeDeclaration of the circuit breaker state machine
 type SomeDeclaration struct {
-	First  FSMState `Aa:"Second"`
+	First  FSMState `Aa:"Second" fsm:"initial"`
 	Second FSMState `Bb:"Third",Cc:"First",Zz:"Fourth"`
 	Third  FSMState `Dd:"First",Zz:"Fourth"`
 	Fourth FSMState
 }
+
+// InitialDeclaration of the state machine with explicit initial state
+type InitialDeclaration struct {
+	Idle    FSMState `Start:"Running" fsm:"initial"`
+	Running FSMState `Stop:"Idle" Fail:"Failed"`
+	Failed  FSMState
+}
+
+// GuardedDeclaration of the state machine with guarded transitions
+type GuardedDeclaration struct {
+	Locked   FSMState `Coin:"Unlocked [PaymentAccepted]" Kick:"Broken" fsm:"initial"`
+	Unlocked FSMState `Push:"Locked" Kick:"Broken [Alarmed]" Coin:"Unlocked [PaymentAccepted]"`
+	Broken   FSMState
+}
+
+// ActionsDeclaration of the state machine with entry and exit actions
+type ActionsDeclaration struct {
+	Stopped FSMState `Play:"Playing" Halt:"Halted" fsm:"initial,exit"`
+	Playing FSMState `Pause:"Paused" Stop:"Stopped" Halt:"Halted [Overheated]" fsm:"entry,exit"`
+	Paused  FSMState `Play:"Playing" Stop:"Stopped" fsm:"entry"`
+	Halted  FSMState `fsm:"entry"`
+}
+
+// HierarchyDeclaration of the state machine with composite states
+type HierarchyDeclaration struct {
+	Online  OnlineDeclaration `Unplug:"Offline" fsm:"initial,exit"`
+	Offline FSMState          `Plug:"Online" Repair:"Degraded"`
+	Dead    FSMState
+}
+
+// OnlineDeclaration of sub-states of the online composite state
+type OnlineDeclaration struct {
+	Ready    FSMState `Send:"Sending" fsm:"initial"`
+	Sending  FSMState `Done:"Ready" Unplug:"Dead [Fatal]" fsm:"entry"`
+	Degraded struct {
+		Probing FSMState `Probe:"Ready" Wait:"Waiting" fsm:"initial"`
+		Waiting FSMState `Reset:"Online"`
+	} `Tick:"Probing" fsm:"entry"`
+}
+
+// LinkDeclaration of the state machine with orthogonal regions
+type LinkDeclaration struct {
+	Connection struct {
+		Disconnected FSMState `Dial:"Connected" fsm:"initial"`
+		Connected    FSMState `Drop:"Disconnected" Close:"Closed"`
+		Closed       FSMState
+	} `fsm:"region"`
+	Auth AuthDeclaration `fsm:"region"`
+}
+
+// AuthDeclaration of the authentication region
+type AuthDeclaration struct {
+	Anonymous     FSMState `Login:"Authenticated [CredentialsValid]" fsm:"initial"`
+	Authenticated FSMState `Logout:"Anonymous" Expire:"Expired"`
+	Expired       FSMState
+}
+
+// ServiceDeclaration of the state machine that resumes composite state from history
+type ServiceDeclaration struct {
+	Operating struct {
+		Starting FSMState `Started:"Serving" fsm:"initial"`
+		Serving  struct {
+			Light FSMState `Load:"Heavy" fsm:"initial"`
+			Heavy FSMState `Relief:"Light" fsm:"entry"`
+		} `Drain:"Draining"`
+		Draining FSMState `Drained:"Starting"`
+	} `Interrupt:"Maintenance" fsm:"initial,exit"`
+	Maintenance FSMState `Resume:"Operating.H" ResumeDeep:"Operating.H*" Abort:"Down"`
+	Down        FSMState
+}
+
+// JobDeclaration of the state machine with events available from any state
+type JobDeclaration struct {
+	Queued    FSMState `Start:"Executing" fsm:"initial"`
+	Executing struct {
+		Fetching  FSMState `Fetched:"Computing" fsm:"initial"`
+		Computing FSMState `Computed:"Succeeded"`
+	} `Cancel:"Queued"`
+	Succeeded FSMState
+	Cancelled FSMState
+	Any       FSMState `Cancel:"Cancelled" Kill:"Cancelled"`
+}
+
+// RetryDeclaration of the state machine with timed transitions
+type RetryDeclaration struct {
+	Attempting FSMState `Failed:"Backoff" Succeeded:"Delivered" After30s:"Abandoned" fsm:"initial"`
+	Backoff    FSMState `After250ms:"Attempting" Abort:"Abandoned"`
+	Delivered  FSMState
+	Abandoned  FSMState
+}
+
+// CheckoutDeclaration of the state machine with automatic states
+type CheckoutDeclaration struct {
+	Cart       FSMState `Submit:"Validating" fsm:"initial"`
+	Validating FSMState `Valid:"Charging" Invalid:"Cart" fsm:"auto"`
+	Charging   FSMState `Charged:"Completed" Declined:"Cart [RetryAllowed]" fsm:"auto"`
+	Completed  FSMState
+}
+
+// TransferDeclaration of the state machine with events that carry payload
+type TransferDeclaration struct {
+	Requested    FSMState `Approve(*Approval):"Transferring" Reject(error):"Refused" fsm:"initial"`
+	Transferring FSMState `Settle(time.Duration):"Settled" Bounce(error):"Requested"`
+	Settled      FSMState
+	Refused      FSMState `Appeal:"Requested"`
+}
+
+// Approval of the transfer
+type Approval struct {
+	Approver   string
+	ApprovedAt time.Time
+}
+
+// SessionDeclaration of the protocol controller which transitions produce commands
+type SessionDeclaration struct {
+	Listening   FSMState `Syn:"Handshaking / SendSynAck" fsm:"initial"`
+	Handshaking FSMState `Ack:"Established" Rst:"Listening / CloseSocket"`
+	Established FSMState `Data:"Established / SendAck" Fin:"Finished [Drained] / CloseSocket"`
+	Finished    FSMState
+}
+
+// DoorDeclaration of the state machine with state attributes
+type DoorDeclaration struct {
+	Shut struct {
+		Latched   FSMState `Unlatch:"Unlatched" fsm:"initial" fsm.locked:"true"`
+		Unlatched FSMState `Latch:"Latched" Swing:"Ajar"`
+	} `fsm:"initial" fsm.label:"Shut" fsm.locked:"false" fsm.weight(float64):"1"`
+	Ajar FSMState `Push:"Shut" fsm.label:"Ajar" fsm.angle:"45" fsm.weight:"0.5"`
+}
+
+// OrderDeclaration of the state machine with states persisted as numbers
+type OrderDeclaration struct {
+	Placed   FSMState `Pay:"Paid" Cancel:"Voided" fsm:"initial,value=1"`
+	Paid     FSMState `Ship:"Shipped" Refund:"Voided" fsm:"value=2"`
+	Shipped  FSMState `Deliver:"Received"`
+	Received FSMState
+	Voided   FSMState `fsm:"value=9"`
+}
+
+// PumpDeclaration of the state machine generated in declaration order
+type PumpDeclaration struct {
+	Standby FSMState `Start:"Active" fsm:"initial"`
+	Active  struct {
+		Priming FSMState `Primed:"Flowing / OpenValve" fsm:"initial"`
+		Flowing FSMState `Dry:"Priming / CloseValve"`
+	} `Stop:"Standby / CloseValve"`
+	Jammed FSMState
+	Any    FSMState `Fault:"Jammed"`
+}
+
+// EngineDeclaration of the state machine with the same states as InitialDeclaration and namespaced identifiers
+type EngineDeclaration struct {
+	Idle FSMState `Start:"Running" fsm:"initial"`
+	// Running engine returns to Idle once stopped.
+	Running FSMState `Stop:"Idle" Fail(error):"Failed"`
+	Failed  FSMState
+}
+
+// ValveStates of the state machine discovered by marker comment
+//
+//fsm:machine Name=Valve
+type ValveStates struct {
+	Sealed  FSMState `Vent:"Venting" fsm:"initial"`
+	Venting FSMState `Seal:"Sealed"`
+}
+
+// UploadDeclaration of the state machine that reuses states of RetryDeclaration
+type UploadDeclaration struct {
+	Selected         FSMState `Send:"UploadRetry" fsm:"initial"`
+	RetryDeclaration `Delivered:"Uploaded" Abandoned:"Selected" fsm:"prefix=Upload"`
+	Uploaded         FSMState
+}
+
+// LampDeclaration of the state machine with documented states and events
+type LampDeclaration struct {
+	// Dark lamp waits to be switched on.
+	// Flip: turns the lamp on.
+	Dark FSMState `Flip:"Lit" fsm:"initial"`
+	// Lit lamp shines until it is switched off or its filament burns out.
+	// Flip: turns the lamp off.
+	// Burn: reports "broken" filament with its `cause`.
+	Lit   FSMState `Flip:"Dark" Burn(error):"Burnt"`
+	Burnt FSMState // Burnt lamp has to be replaced.
+}
@@ -0,0 +1,362 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint ActionsDeclaration 5e699dbae91652b0
+
+//+++ General machine definition +++
+
+// ActionsState type definition
+type ActionsState int
+
+const (
+	_       ActionsState = iota
+	Halted               // Halted state
+	Paused               // Paused state
+	Playing              // Playing state
+	Stopped              // Stopped state
+)
+
+var _ActionsStateMap = map[ActionsState]string{
+	Halted:  "Halted",
+	Paused:  "Paused",
+	Playing: "Playing",
+	Stopped: "Stopped",
+}
+
+var _ActionsParsingStateMap = map[string]ActionsState{
+	"Halted":  Halted,
+	"Paused":  Paused,
+	"Playing": Playing,
+	"Stopped": Stopped,
+}
+
+func (s ActionsState) String() string {
+	return _ActionsStateMap[s]
+}
+
+// ActionsBehaviour definition
+type ActionsBehaviour interface {
+	ActionsHaltedEntry
+	ActionsPausedState
+	ActionsPausedEntry
+	ActionsPlayingState
+	ActionsPlayingGuards
+	ActionsPlayingEntry
+	ActionsPlayingExit
+	ActionsStoppedState
+	ActionsStoppedExit
+}
+
+// Actions machine type
+type Actions struct {
+	state ActionsState
+}
+
+// NewActions creates machine in its initial state Stopped
+func NewActions() *Actions {
+	return &Actions{state: Stopped}
+}
+
+// NewActionsFromString can be used to deserialize  machine state
+func NewActionsFromString(stateStr string) (*Actions, error) {
+	state, ok := _ActionsParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Actions: %s", stateStr)
+	}
+	return &Actions{state: state}, nil
+}
+
+// Current returns current state of Actions
+func (m *Actions) Current() ActionsState {
+	return m.state
+}
+
+// ActionsGuardError reports transition of Actions refused by guard
+type ActionsGuardError struct {
+	State ActionsState
+	Event string
+	Guard string
+}
+
+func (e *ActionsGuardError) Error() string {
+	return fmt.Sprintf("Actions transition %s from %s refused by guard %s", e.Event, e.State, e.Guard)
+}
+
+// Operate executes behaviour for the current state Actions.
+// If guard refuses transition, machine remains in current state and *ActionsGuardError is returned.
+// On transition exit action of the current state is executed first,
+// then state is changed and entry action of the destination state is executed.
+func (m *Actions) Operate(operator ActionsBehaviour) error {
+	switch m.state {
+	case Halted:
+		return nil
+	case Paused:
+		return m.handlePausedEvent(operator, operator.OperatePaused())
+	case Playing:
+		return m.handlePlayingEvent(operator, operator.OperatePlaying())
+	case Stopped:
+		return m.handleStoppedEvent(operator, operator.OperateStopped())
+	}
+	return nil
+}
+
+// ActionsEventNotAllowedError reports event fired in state of Actions that doesn't declare it
+type ActionsEventNotAllowedError struct {
+	State ActionsState
+	Event ActionsEvent
+}
+
+func (e *ActionsEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Actions event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Actions.
+// If current state doesn't declare event, machine remains in it and *ActionsEventNotAllowedError is returned.
+// If guard refuses transition, *ActionsGuardError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Actions) Fire(operator ActionsBehaviour, ev ActionsEvent) error {
+	switch ev {
+	case ActionsEventHalt:
+		switch m.state {
+		case Playing:
+			return m.handlePlayingEvent(operator, PlayingHalt)
+		case Stopped:
+			return m.handleStoppedEvent(operator, StoppedHalt)
+		}
+		return &ActionsEventNotAllowedError{State: m.state, Event: ev}
+	case ActionsEventPause:
+		switch m.state {
+		case Playing:
+			return m.handlePlayingEvent(operator, PlayingPause)
+		}
+		return &ActionsEventNotAllowedError{State: m.state, Event: ev}
+	case ActionsEventPlay:
+		switch m.state {
+		case Paused:
+			return m.handlePausedEvent(operator, PausedPlay)
+		case Stopped:
+			return m.handleStoppedEvent(operator, StoppedPlay)
+		}
+		return &ActionsEventNotAllowedError{State: m.state, Event: ev}
+	case ActionsEventStop:
+		switch m.state {
+		case Paused:
+			return m.handlePausedEvent(operator, PausedStop)
+		case Playing:
+			return m.handlePlayingEvent(operator, PlayingStop)
+		}
+		return &ActionsEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &ActionsEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Actions in Graphviz format
+func (m *Actions) Visualize() string {
+	return `// Definition for Actions in Graphviz format 
+digraph Actions {
+	__initial [shape=point];
+	__initial -> Stopped;
+	Halted [shape=Msquare];
+	Paused -> Playing [label=Play];
+	Paused -> Stopped [label=Stop];
+	Playing -> Halted [label="Halt [Overheated]"];
+	Playing -> Paused [label=Pause];
+	Playing -> Stopped [label=Stop];
+	Stopped -> Halted [label=Halt];
+	Stopped -> Playing [label=Play];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Actions) handlePausedEvent(operator ActionsBehaviour, event ActionsPausedEvent) error {
+	switch event {
+	case PausedPlay:
+		m.state = Playing
+		operator.OnPlayingEnter()
+	case PausedStop:
+		m.state = Stopped
+	case PausedNoop:
+	}
+	return nil
+}
+
+func (m *Actions) handlePlayingEvent(operator ActionsBehaviour, event ActionsPlayingEvent) error {
+	switch event {
+	case PlayingHalt:
+		if !operator.Overheated() {
+			return &ActionsGuardError{State: Playing, Event: event.String(), Guard: "Overheated"}
+		}
+		operator.OnPlayingExit()
+		m.state = Halted
+		operator.OnHaltedEnter()
+	case PlayingPause:
+		operator.OnPlayingExit()
+		m.state = Paused
+		operator.OnPausedEnter()
+	case PlayingStop:
+		operator.OnPlayingExit()
+		m.state = Stopped
+	case PlayingNoop:
+	}
+	return nil
+}
+
+func (m *Actions) handleStoppedEvent(operator ActionsBehaviour, event ActionsStoppedEvent) error {
+	switch event {
+	case StoppedHalt:
+		operator.OnStoppedExit()
+		m.state = Halted
+		operator.OnHaltedEnter()
+	case StoppedPlay:
+		operator.OnStoppedExit()
+		m.state = Playing
+		operator.OnPlayingEnter()
+	case StoppedNoop:
+	}
+	return nil
+}
+
+//--- Here we will define all events ---
+
+//=== ActionsEvent definition ===
+
+// ActionsEvent is event of any state of Actions that can be fired
+type ActionsEvent int
+
+const (
+	_ ActionsEvent = iota
+	ActionsEventHalt
+	ActionsEventPause
+	ActionsEventPlay
+	ActionsEventStop
+)
+
+var _ActionsEventMap = map[ActionsEvent]string{
+	ActionsEventHalt:  "Halt",
+	ActionsEventPause: "Pause",
+	ActionsEventPlay:  "Play",
+	ActionsEventStop:  "Stop",
+}
+
+func (m ActionsEvent) String() string {
+	return _ActionsEventMap[m]
+}
+
+//=== ActionsPausedEvent definition ===
+
+// ActionsPausedEvent definition
+type ActionsPausedEvent int
+
+const (
+	_          ActionsPausedEvent = iota
+	PausedPlay                    // PausedPlay -> Playing
+	PausedStop                    // PausedStop -> Stopped
+	PausedNoop                    // remain in Paused
+)
+
+var _ActionsPausedEventMap = map[ActionsPausedEvent]string{
+	PausedPlay: "PausedPlay",
+	PausedStop: "PausedStop",
+	PausedNoop: "PausedNoop",
+}
+
+func (m ActionsPausedEvent) String() string {
+	return _ActionsPausedEventMap[m]
+}
+
+// ActionsPausedState behaviour
+type ActionsPausedState interface {
+	OperatePaused() ActionsPausedEvent
+}
+
+//=== ActionsPlayingEvent definition ===
+
+// ActionsPlayingEvent definition
+type ActionsPlayingEvent int
+
+const (
+	_            ActionsPlayingEvent = iota
+	PlayingHalt                      // PlayingHalt -> Halted if Overheated
+	PlayingPause                     // PlayingPause -> Paused
+	PlayingStop                      // PlayingStop -> Stopped
+	PlayingNoop                      // remain in Playing
+)
+
+var _ActionsPlayingEventMap = map[ActionsPlayingEvent]string{
+	PlayingHalt:  "PlayingHalt",
+	PlayingPause: "PlayingPause",
+	PlayingStop:  "PlayingStop",
+	PlayingNoop:  "PlayingNoop",
+}
+
+func (m ActionsPlayingEvent) String() string {
+	return _ActionsPlayingEventMap[m]
+}
+
+// ActionsPlayingState behaviour
+type ActionsPlayingState interface {
+	OperatePlaying() ActionsPlayingEvent
+}
+
+// ActionsPlayingGuards allow or refuse transitions from Playing
+type ActionsPlayingGuards interface {
+	Overheated() bool
+}
+
+//=== ActionsStoppedEvent definition ===
+
+// ActionsStoppedEvent definition
+type ActionsStoppedEvent int
+
+const (
+	_           ActionsStoppedEvent = iota
+	StoppedHalt                     // StoppedHalt -> Halted
+	StoppedPlay                     // StoppedPlay -> Playing
+	StoppedNoop                     // remain in Stopped
+)
+
+var _ActionsStoppedEventMap = map[ActionsStoppedEvent]string{
+	StoppedHalt: "StoppedHalt",
+	StoppedPlay: "StoppedPlay",
+	StoppedNoop: "StoppedNoop",
+}
+
+func (m ActionsStoppedEvent) String() string {
+	return _ActionsStoppedEventMap[m]
+}
+
+// ActionsStoppedState behaviour
+type ActionsStoppedState interface {
+	OperateStopped() ActionsStoppedEvent
+}
+
+//--- Here we will define all state actions ---
+
+// ActionsHaltedEntry action executed when machine enters Halted
+type ActionsHaltedEntry interface {
+	OnHaltedEnter()
+}
+
+// ActionsPausedEntry action executed when machine enters Paused
+type ActionsPausedEntry interface {
+	OnPausedEnter()
+}
+
+// ActionsPlayingEntry action executed when machine enters Playing
+type ActionsPlayingEntry interface {
+	OnPlayingEnter()
+}
+
+// ActionsPlayingExit action executed when machine leaves Playing
+type ActionsPlayingExit interface {
+	OnPlayingExit()
+}
+
+// ActionsStoppedExit action executed when machine leaves Stopped
+type ActionsStoppedExit interface {
+	OnStoppedExit()
+}
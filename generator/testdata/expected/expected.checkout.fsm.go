@@ -0,0 +1,330 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint CheckoutDeclaration a0eaefca214db27c
+
+//+++ General machine definition +++
+
+// CheckoutState type definition
+type CheckoutState int
+
+const (
+	_          CheckoutState = iota
+	Cart                     // Cart state
+	Charging                 // Charging state
+	Completed                // Completed state
+	Validating               // Validating state
+)
+
+var _CheckoutStateMap = map[CheckoutState]string{
+	Cart:       "Cart",
+	Charging:   "Charging",
+	Completed:  "Completed",
+	Validating: "Validating",
+}
+
+var _CheckoutParsingStateMap = map[string]CheckoutState{
+	"Cart":       Cart,
+	"Charging":   Charging,
+	"Completed":  Completed,
+	"Validating": Validating,
+}
+
+func (s CheckoutState) String() string {
+	return _CheckoutStateMap[s]
+}
+
+// CheckoutBehaviour definition
+type CheckoutBehaviour interface {
+	CheckoutCartState
+	CheckoutChargingState
+	CheckoutChargingGuards
+
+	CheckoutValidatingState
+}
+
+// Checkout machine type
+type Checkout struct {
+	state CheckoutState
+}
+
+// NewCheckout creates machine in its initial state Cart
+func NewCheckout() *Checkout {
+	return &Checkout{state: Cart}
+}
+
+// NewCheckoutFromString can be used to deserialize  machine state
+func NewCheckoutFromString(stateStr string) (*Checkout, error) {
+	state, ok := _CheckoutParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Checkout: %s", stateStr)
+	}
+	return &Checkout{state: state}, nil
+}
+
+// Current returns current state of Checkout
+func (m *Checkout) Current() CheckoutState {
+	return m.state
+}
+
+var _CheckoutAutoStates = map[CheckoutState]bool{
+	Charging:   true,
+	Validating: true,
+}
+
+// CheckoutGuardError reports transition of Checkout refused by guard
+type CheckoutGuardError struct {
+	State CheckoutState
+	Event string
+	Guard string
+}
+
+func (e *CheckoutGuardError) Error() string {
+	return fmt.Sprintf("Checkout transition %s from %s refused by guard %s", e.Event, e.State, e.Guard)
+}
+
+// Operate executes behaviour for the current state Checkout.
+// If guard refuses transition, machine remains in current state and *CheckoutGuardError is returned.
+// After transition to automatic state its behaviour is executed immediately, until stable state is reached.
+func (m *Checkout) Operate(operator CheckoutBehaviour) error {
+	for {
+		previous := m.state
+		if err := m.operateCurrent(operator); err != nil {
+			return err
+		}
+		if m.state == previous || !_CheckoutAutoStates[m.state] {
+			return nil
+		}
+	}
+}
+
+// operateCurrent executes behaviour for the current state Checkout once
+func (m *Checkout) operateCurrent(operator CheckoutBehaviour) error {
+	switch m.state {
+	case Cart:
+		return m.handleCartEvent(operator, operator.OperateCart())
+	case Charging:
+		return m.handleChargingEvent(operator, operator.OperateCharging())
+	case Completed:
+		return nil
+	case Validating:
+		return m.handleValidatingEvent(operator, operator.OperateValidating())
+	}
+	return nil
+}
+
+// CheckoutEventNotAllowedError reports event fired in state of Checkout that doesn't declare it
+type CheckoutEventNotAllowedError struct {
+	State CheckoutState
+	Event CheckoutEvent
+}
+
+func (e *CheckoutEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Checkout event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Checkout.
+// If current state doesn't declare event, machine remains in it and *CheckoutEventNotAllowedError is returned.
+// If guard refuses transition, *CheckoutGuardError is returned.
+// Fire doesn't execute state behaviour, so automatic states are passed through only by Operate.
+func (m *Checkout) Fire(operator CheckoutBehaviour, ev CheckoutEvent) error {
+	switch ev {
+	case CheckoutEventCharged:
+		switch m.state {
+		case Charging:
+			return m.handleChargingEvent(operator, ChargingCharged)
+		}
+		return &CheckoutEventNotAllowedError{State: m.state, Event: ev}
+	case CheckoutEventDeclined:
+		switch m.state {
+		case Charging:
+			return m.handleChargingEvent(operator, ChargingDeclined)
+		}
+		return &CheckoutEventNotAllowedError{State: m.state, Event: ev}
+	case CheckoutEventInvalid:
+		switch m.state {
+		case Validating:
+			return m.handleValidatingEvent(operator, ValidatingInvalid)
+		}
+		return &CheckoutEventNotAllowedError{State: m.state, Event: ev}
+	case CheckoutEventSubmit:
+		switch m.state {
+		case Cart:
+			return m.handleCartEvent(operator, CartSubmit)
+		}
+		return &CheckoutEventNotAllowedError{State: m.state, Event: ev}
+	case CheckoutEventValid:
+		switch m.state {
+		case Validating:
+			return m.handleValidatingEvent(operator, ValidatingValid)
+		}
+		return &CheckoutEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &CheckoutEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Checkout in Graphviz format
+func (m *Checkout) Visualize() string {
+	return `// Definition for Checkout in Graphviz format 
+digraph Checkout {
+	__initial [shape=point];
+	__initial -> Cart;
+	Cart -> Validating [label=Submit];
+	Charging -> Completed [label=Charged];
+	Charging -> Cart [label="Declined [RetryAllowed]"];
+	Completed [shape=Msquare];
+	Validating -> Cart [label=Invalid];
+	Validating -> Charging [label=Valid];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Checkout) handleCartEvent(operator CheckoutBehaviour, event CheckoutCartEvent) error {
+	switch event {
+	case CartSubmit:
+		m.state = Validating
+	case CartNoop:
+	}
+	return nil
+}
+
+func (m *Checkout) handleChargingEvent(operator CheckoutBehaviour, event CheckoutChargingEvent) error {
+	switch event {
+	case ChargingCharged:
+		m.state = Completed
+	case ChargingDeclined:
+		if !operator.RetryAllowed() {
+			return &CheckoutGuardError{State: Charging, Event: event.String(), Guard: "RetryAllowed"}
+		}
+		m.state = Cart
+	case ChargingNoop:
+	}
+	return nil
+}
+
+func (m *Checkout) handleValidatingEvent(operator CheckoutBehaviour, event CheckoutValidatingEvent) error {
+	switch event {
+	case ValidatingInvalid:
+		m.state = Cart
+	case ValidatingValid:
+		m.state = Charging
+	case ValidatingNoop:
+	}
+	return nil
+}
+
+//--- Here we will define all events ---
+
+//=== CheckoutEvent definition ===
+
+// CheckoutEvent is event of any state of Checkout that can be fired
+type CheckoutEvent int
+
+const (
+	_ CheckoutEvent = iota
+	CheckoutEventCharged
+	CheckoutEventDeclined
+	CheckoutEventInvalid
+	CheckoutEventSubmit
+	CheckoutEventValid
+)
+
+var _CheckoutEventMap = map[CheckoutEvent]string{
+	CheckoutEventCharged:  "Charged",
+	CheckoutEventDeclined: "Declined",
+	CheckoutEventInvalid:  "Invalid",
+	CheckoutEventSubmit:   "Submit",
+	CheckoutEventValid:    "Valid",
+}
+
+func (m CheckoutEvent) String() string {
+	return _CheckoutEventMap[m]
+}
+
+//=== CheckoutCartEvent definition ===
+
+// CheckoutCartEvent definition
+type CheckoutCartEvent int
+
+const (
+	_          CheckoutCartEvent = iota
+	CartSubmit                   // CartSubmit -> Validating
+	CartNoop                     // remain in Cart
+)
+
+var _CheckoutCartEventMap = map[CheckoutCartEvent]string{
+	CartSubmit: "CartSubmit",
+	CartNoop:   "CartNoop",
+}
+
+func (m CheckoutCartEvent) String() string {
+	return _CheckoutCartEventMap[m]
+}
+
+// CheckoutCartState behaviour
+type CheckoutCartState interface {
+	OperateCart() CheckoutCartEvent
+}
+
+//=== CheckoutChargingEvent definition ===
+
+// CheckoutChargingEvent definition
+type CheckoutChargingEvent int
+
+const (
+	_                CheckoutChargingEvent = iota
+	ChargingCharged                        // ChargingCharged -> Completed
+	ChargingDeclined                       // ChargingDeclined -> Cart if RetryAllowed
+	ChargingNoop                           // remain in Charging
+)
+
+var _CheckoutChargingEventMap = map[CheckoutChargingEvent]string{
+	ChargingCharged:  "ChargingCharged",
+	ChargingDeclined: "ChargingDeclined",
+	ChargingNoop:     "ChargingNoop",
+}
+
+func (m CheckoutChargingEvent) String() string {
+	return _CheckoutChargingEventMap[m]
+}
+
+// CheckoutChargingState behaviour
+type CheckoutChargingState interface {
+	OperateCharging() CheckoutChargingEvent
+}
+
+// CheckoutChargingGuards allow or refuse transitions from Charging
+type CheckoutChargingGuards interface {
+	RetryAllowed() bool
+}
+
+//=== CheckoutValidatingEvent definition ===
+
+// CheckoutValidatingEvent definition
+type CheckoutValidatingEvent int
+
+const (
+	_                 CheckoutValidatingEvent = iota
+	ValidatingInvalid                         // ValidatingInvalid -> Cart
+	ValidatingValid                           // ValidatingValid -> Charging
+	ValidatingNoop                            // remain in Validating
+)
+
+var _CheckoutValidatingEventMap = map[CheckoutValidatingEvent]string{
+	ValidatingInvalid: "ValidatingInvalid",
+	ValidatingValid:   "ValidatingValid",
+	ValidatingNoop:    "ValidatingNoop",
+}
+
+func (m CheckoutValidatingEvent) String() string {
+	return _CheckoutValidatingEventMap[m]
+}
+
+// CheckoutValidatingState behaviour
+type CheckoutValidatingState interface {
+	OperateValidating() CheckoutValidatingEvent
+}
@@ -0,0 +1,342 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint DoorDeclaration b2eb139df0f42dbe
+
+//+++ General machine definition +++
+
+// DoorState type definition
+type DoorState int
+
+const (
+	_         DoorState = iota
+	Ajar                // Ajar state
+	Latched             // Latched state
+	Shut                // Shut composite state
+	Unlatched           // Unlatched state
+)
+
+var _DoorStateMap = map[DoorState]string{
+	Ajar:      "Ajar",
+	Latched:   "Latched",
+	Shut:      "Shut",
+	Unlatched: "Unlatched",
+}
+
+var _DoorParsingStateMap = map[string]DoorState{
+	"Ajar":      Ajar,
+	"Latched":   Latched,
+	"Unlatched": Unlatched,
+}
+
+func (s DoorState) String() string {
+	return _DoorStateMap[s]
+}
+
+var _DoorParentStateMap = map[DoorState]DoorState{
+	Latched:   Shut,
+	Unlatched: Shut,
+}
+
+// Parent returns composite state that contains s
+func (s DoorState) Parent() DoorState {
+	return _DoorParentStateMap[s]
+}
+
+// In reports whether s is the same as state or nested inside of it
+func (s DoorState) In(state DoorState) bool {
+	for ; s != 0; s = s.Parent() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+var _DoorAngleAttribute = map[DoorState]int{
+	Ajar: 45,
+}
+
+// Angle returns angle attribute of the state or zero value if state has no such attribute
+func (s DoorState) Angle() int {
+	return _DoorAngleAttribute[s]
+}
+
+var _DoorLabelAttribute = map[DoorState]string{
+	Ajar:      "Ajar",
+	Latched:   "Shut",
+	Shut:      "Shut",
+	Unlatched: "Shut",
+}
+
+// Label returns label attribute of the state or zero value if state has no such attribute
+func (s DoorState) Label() string {
+	return _DoorLabelAttribute[s]
+}
+
+var _DoorLockedAttribute = map[DoorState]bool{
+	Latched:   true,
+	Shut:      false,
+	Unlatched: false,
+}
+
+// Locked returns locked attribute of the state or zero value if state has no such attribute
+func (s DoorState) Locked() bool {
+	return _DoorLockedAttribute[s]
+}
+
+var _DoorWeightAttribute = map[DoorState]float64{
+	Ajar:      0.5,
+	Latched:   1,
+	Shut:      1,
+	Unlatched: 1,
+}
+
+// Weight returns weight attribute of the state or zero value if state has no such attribute
+func (s DoorState) Weight() float64 {
+	return _DoorWeightAttribute[s]
+}
+
+// DoorBehaviour definition
+type DoorBehaviour interface {
+	DoorAjarState
+	DoorLatchedState
+	DoorUnlatchedState
+}
+
+// Door machine type
+type Door struct {
+	state DoorState
+}
+
+// NewDoor creates machine in its initial state Latched
+func NewDoor() *Door {
+	return &Door{state: Latched}
+}
+
+// NewDoorFromString can be used to deserialize  machine state
+func NewDoorFromString(stateStr string) (*Door, error) {
+	state, ok := _DoorParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Door: %s", stateStr)
+	}
+	return &Door{state: state}, nil
+}
+
+// Current returns current state of Door
+func (m *Door) Current() DoorState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Door
+func (m *Door) Operate(operator DoorBehaviour) {
+	switch m.state {
+	case Ajar:
+		m.handleAjarEvent(operator.OperateAjar())
+	case Latched:
+		m.handleLatchedEvent(operator.OperateLatched())
+	case Unlatched:
+		m.handleUnlatchedEvent(operator.OperateUnlatched())
+	}
+}
+
+// DoorEventNotAllowedError reports event fired in state of Door that doesn't declare it
+type DoorEventNotAllowedError struct {
+	State DoorState
+	Event DoorEvent
+}
+
+func (e *DoorEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Door event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Door.
+// If current state doesn't declare event, machine remains in it and *DoorEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Door) Fire(ev DoorEvent) error {
+	switch ev {
+	case DoorEventLatch:
+		switch m.state {
+		case Unlatched:
+			m.handleUnlatchedEvent(UnlatchedLatch)
+			return nil
+		}
+		return &DoorEventNotAllowedError{State: m.state, Event: ev}
+	case DoorEventPush:
+		switch m.state {
+		case Ajar:
+			m.handleAjarEvent(AjarPush)
+			return nil
+		}
+		return &DoorEventNotAllowedError{State: m.state, Event: ev}
+	case DoorEventSwing:
+		switch m.state {
+		case Unlatched:
+			m.handleUnlatchedEvent(UnlatchedSwing)
+			return nil
+		}
+		return &DoorEventNotAllowedError{State: m.state, Event: ev}
+	case DoorEventUnlatch:
+		switch m.state {
+		case Latched:
+			m.handleLatchedEvent(LatchedUnlatch)
+			return nil
+		}
+		return &DoorEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &DoorEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Door in Graphviz format
+func (m *Door) Visualize() string {
+	return `// Definition for Door in Graphviz format 
+digraph Door {
+	compound=true;
+	__initial [shape=point];
+	__initial -> Latched [lhead=cluster_Shut];
+	Ajar;
+	subgraph cluster_Shut {
+		label=Shut;
+		Latched;
+		Unlatched;
+	}
+	Ajar -> Latched [label=Push, lhead=cluster_Shut];
+	Latched -> Unlatched [label=Unlatch];
+	Unlatched -> Latched [label=Latch];
+	Unlatched -> Ajar [label=Swing];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Door) handleAjarEvent(event DoorAjarEvent) {
+	switch event {
+	case AjarPush:
+		m.state = Latched
+	case AjarNoop:
+	}
+}
+
+func (m *Door) handleLatchedEvent(event DoorLatchedEvent) {
+	switch event {
+	case LatchedUnlatch:
+		m.state = Unlatched
+	case LatchedNoop:
+	}
+}
+
+func (m *Door) handleUnlatchedEvent(event DoorUnlatchedEvent) {
+	switch event {
+	case UnlatchedLatch:
+		m.state = Latched
+	case UnlatchedSwing:
+		m.state = Ajar
+	case UnlatchedNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== DoorEvent definition ===
+
+// DoorEvent is event of any state of Door that can be fired
+type DoorEvent int
+
+const (
+	_ DoorEvent = iota
+	DoorEventLatch
+	DoorEventPush
+	DoorEventSwing
+	DoorEventUnlatch
+)
+
+var _DoorEventMap = map[DoorEvent]string{
+	DoorEventLatch:   "Latch",
+	DoorEventPush:    "Push",
+	DoorEventSwing:   "Swing",
+	DoorEventUnlatch: "Unlatch",
+}
+
+func (m DoorEvent) String() string {
+	return _DoorEventMap[m]
+}
+
+//=== DoorAjarEvent definition ===
+
+// DoorAjarEvent definition
+type DoorAjarEvent int
+
+const (
+	_        DoorAjarEvent = iota
+	AjarPush               // AjarPush -> Shut
+	AjarNoop               // remain in Ajar
+)
+
+var _DoorAjarEventMap = map[DoorAjarEvent]string{
+	AjarPush: "AjarPush",
+	AjarNoop: "AjarNoop",
+}
+
+func (m DoorAjarEvent) String() string {
+	return _DoorAjarEventMap[m]
+}
+
+// DoorAjarState behaviour
+type DoorAjarState interface {
+	OperateAjar() DoorAjarEvent
+}
+
+//=== DoorLatchedEvent definition ===
+
+// DoorLatchedEvent definition
+type DoorLatchedEvent int
+
+const (
+	_              DoorLatchedEvent = iota
+	LatchedUnlatch                  // LatchedUnlatch -> Unlatched
+	LatchedNoop                     // remain in Latched
+)
+
+var _DoorLatchedEventMap = map[DoorLatchedEvent]string{
+	LatchedUnlatch: "LatchedUnlatch",
+	LatchedNoop:    "LatchedNoop",
+}
+
+func (m DoorLatchedEvent) String() string {
+	return _DoorLatchedEventMap[m]
+}
+
+// DoorLatchedState behaviour
+type DoorLatchedState interface {
+	OperateLatched() DoorLatchedEvent
+}
+
+//=== DoorUnlatchedEvent definition ===
+
+// DoorUnlatchedEvent definition
+type DoorUnlatchedEvent int
+
+const (
+	_              DoorUnlatchedEvent = iota
+	UnlatchedLatch                    // UnlatchedLatch -> Latched
+	UnlatchedSwing                    // UnlatchedSwing -> Ajar
+	UnlatchedNoop                     // remain in Unlatched
+)
+
+var _DoorUnlatchedEventMap = map[DoorUnlatchedEvent]string{
+	UnlatchedLatch: "UnlatchedLatch",
+	UnlatchedSwing: "UnlatchedSwing",
+	UnlatchedNoop:  "UnlatchedNoop",
+}
+
+func (m DoorUnlatchedEvent) String() string {
+	return _DoorUnlatchedEventMap[m]
+}
+
+// DoorUnlatchedState behaviour
+type DoorUnlatchedState interface {
+	OperateUnlatched() DoorUnlatchedEvent
+}
@@ -0,0 +1,265 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint EngineDeclaration 350214c6a760c036
+
+//+++ General machine definition +++
+
+// EngineState type definition
+type EngineState int
+
+const (
+	_          EngineState = iota
+	EngineIdle             // EngineIdle state
+	// Running engine returns to Idle once stopped.
+	EngineRunning
+	EngineFailed // EngineFailed state
+)
+
+var _EngineStateMap = map[EngineState]string{
+	EngineIdle:    "Idle",
+	EngineRunning: "Running",
+	EngineFailed:  "Failed",
+}
+
+var _EngineParsingStateMap = map[string]EngineState{
+	"Idle":    EngineIdle,
+	"Running": EngineRunning,
+	"Failed":  EngineFailed,
+}
+
+func (s EngineState) String() string {
+	return _EngineStateMap[s]
+}
+
+// EngineBehaviour definition
+type EngineBehaviour interface {
+	EngineIdleState
+	EngineRunningState
+
+	EngineFailedListener
+}
+
+// Engine machine type
+type Engine struct {
+	state EngineState
+}
+
+// NewEngine creates machine in its initial state EngineIdle
+func NewEngine() *Engine {
+	return &Engine{state: EngineIdle}
+}
+
+// NewEngineFromString can be used to deserialize  machine state
+func NewEngineFromString(stateStr string) (*Engine, error) {
+	state, ok := _EngineParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Engine: %s", stateStr)
+	}
+	return &Engine{state: state}, nil
+}
+
+// Current returns current state of Engine
+func (m *Engine) Current() EngineState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Engine
+func (m *Engine) Operate(operator EngineBehaviour) {
+	switch m.state {
+	case EngineIdle:
+		m.handleIdleEvent(operator, operator.OperateIdle())
+	case EngineRunning:
+		m.handleRunningEvent(operator, operator.OperateRunning())
+	case EngineFailed:
+		return
+	}
+}
+
+// EngineEventNotAllowedError reports event fired in state of Engine that doesn't declare it
+type EngineEventNotAllowedError struct {
+	State EngineState
+	Event EngineEvent
+}
+
+func (e *EngineEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Engine event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Engine.
+// If current state doesn't declare event, machine remains in it and *EngineEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Engine) Fire(operator EngineBehaviour, ev EngineEvent) error {
+	switch ev.id {
+	case _EngineEventStart:
+		switch m.state {
+		case EngineIdle:
+			m.handleIdleEvent(operator, EngineIdleStart)
+			return nil
+		}
+		return &EngineEventNotAllowedError{State: m.state, Event: ev}
+	case _EngineEventStop:
+		switch m.state {
+		case EngineRunning:
+			m.handleRunningEvent(operator, EngineRunningStop)
+			return nil
+		}
+		return &EngineEventNotAllowedError{State: m.state, Event: ev}
+	case _EngineEventFail:
+		switch m.state {
+		case EngineRunning:
+			m.handleRunningEvent(operator, EngineRunningFail(ev.failPayload))
+			return nil
+		}
+		return &EngineEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &EngineEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Engine in Graphviz format
+func (m *Engine) Visualize() string {
+	return `// Definition for Engine in Graphviz format 
+digraph Engine {
+	__initial [shape=point];
+	__initial -> Idle;
+	Idle -> Running [label=Start];
+	Running [tooltip="Running engine returns to Idle once stopped."];
+	Running -> Idle [label=Stop];
+	Running -> Failed [label=Fail];
+	Failed [shape=Msquare];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Engine) handleIdleEvent(operator EngineBehaviour, event EngineIdleEvent) {
+	switch event {
+	case EngineIdleStart:
+		m.state = EngineRunning
+	case EngineIdleNoop:
+	}
+}
+
+func (m *Engine) handleRunningEvent(operator EngineBehaviour, event EngineRunningEvent) {
+	switch event.id {
+	case _EngineRunningStop:
+		m.state = EngineIdle
+	case _EngineRunningFail:
+		m.state = EngineFailed
+		operator.OnFailedFail(event.failPayload)
+	case _EngineRunningNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== EngineEvent definition ===
+
+// EngineEvent is event of any state of Engine that can be fired, some of events carry payload
+type EngineEvent struct {
+	id          int
+	failPayload error
+}
+
+const (
+	_ = iota
+	_EngineEventStart
+	_EngineEventStop
+	_EngineEventFail
+)
+
+var (
+	EngineEventStart = EngineEvent{id: _EngineEventStart}
+	EngineEventStop  = EngineEvent{id: _EngineEventStop}
+)
+
+// EngineEventFail creates event Fail that carries payload
+func EngineEventFail(payload error) EngineEvent {
+	return EngineEvent{id: _EngineEventFail, failPayload: payload}
+}
+
+var _EngineEventMap = map[int]string{
+	_EngineEventStart: "Start",
+	_EngineEventStop:  "Stop",
+	_EngineEventFail:  "Fail",
+}
+
+func (m EngineEvent) String() string {
+	return _EngineEventMap[m.id]
+}
+
+//=== EngineIdleEvent definition ===
+
+// EngineIdleEvent definition
+type EngineIdleEvent int
+
+const (
+	_               EngineIdleEvent = iota
+	EngineIdleStart                 // EngineIdleStart -> EngineRunning
+	EngineIdleNoop                  // remain in EngineIdle
+)
+
+var _EngineIdleEventMap = map[EngineIdleEvent]string{
+	EngineIdleStart: "IdleStart",
+	EngineIdleNoop:  "IdleNoop",
+}
+
+func (m EngineIdleEvent) String() string {
+	return _EngineIdleEventMap[m]
+}
+
+// EngineIdleState behaviour
+type EngineIdleState interface {
+	OperateIdle() EngineIdleEvent
+}
+
+//=== EngineRunningEvent definition ===
+
+// EngineRunningEvent definition, some of events carry payload
+type EngineRunningEvent struct {
+	id          int
+	failPayload error
+}
+
+const (
+	_ = iota
+	_EngineRunningStop
+	_EngineRunningFail
+	_EngineRunningNoop
+)
+
+var (
+	EngineRunningStop = EngineRunningEvent{id: _EngineRunningStop} // EngineRunningStop -> EngineIdle
+	EngineRunningNoop = EngineRunningEvent{id: _EngineRunningNoop} // remain in EngineRunning
+)
+
+// EngineRunningFail creates event EngineRunningFail -> EngineFailed that carries payload
+func EngineRunningFail(payload error) EngineRunningEvent {
+	return EngineRunningEvent{id: _EngineRunningFail, failPayload: payload}
+}
+
+var _EngineRunningEventMap = map[int]string{
+	_EngineRunningStop: "RunningStop",
+	_EngineRunningFail: "RunningFail",
+	_EngineRunningNoop: "RunningNoop",
+}
+
+func (m EngineRunningEvent) String() string {
+	return _EngineRunningEventMap[m.id]
+}
+
+// EngineRunningState behaviour
+type EngineRunningState interface {
+	// Running engine returns to Idle once stopped.
+	OperateRunning() EngineRunningEvent
+}
+
+//--- Here we will define all payload listeners ---
+
+// EngineFailedListener receives payloads of events that lead to EngineFailed
+type EngineFailedListener interface {
+	OnFailedFail(payload error)
+}
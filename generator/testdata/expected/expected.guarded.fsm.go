@@ -0,0 +1,275 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint GuardedDeclaration 0574128f15019e63
+
+//+++ General machine definition +++
+
+// GuardedState type definition
+type GuardedState int
+
+const (
+	_        GuardedState = iota
+	Broken                // Broken state
+	Locked                // Locked state
+	Unlocked              // Unlocked state
+)
+
+var _GuardedStateMap = map[GuardedState]string{
+	Broken:   "Broken",
+	Locked:   "Locked",
+	Unlocked: "Unlocked",
+}
+
+var _GuardedParsingStateMap = map[string]GuardedState{
+	"Broken":   Broken,
+	"Locked":   Locked,
+	"Unlocked": Unlocked,
+}
+
+func (s GuardedState) String() string {
+	return _GuardedStateMap[s]
+}
+
+// GuardedBehaviour definition
+type GuardedBehaviour interface {
+	GuardedLockedState
+	GuardedLockedGuards
+	GuardedUnlockedState
+	GuardedUnlockedGuards
+}
+
+// Guarded machine type
+type Guarded struct {
+	state GuardedState
+}
+
+// NewGuarded creates machine in its initial state Locked
+func NewGuarded() *Guarded {
+	return &Guarded{state: Locked}
+}
+
+// NewGuardedFromString can be used to deserialize  machine state
+func NewGuardedFromString(stateStr string) (*Guarded, error) {
+	state, ok := _GuardedParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Guarded: %s", stateStr)
+	}
+	return &Guarded{state: state}, nil
+}
+
+// Current returns current state of Guarded
+func (m *Guarded) Current() GuardedState {
+	return m.state
+}
+
+// GuardedGuardError reports transition of Guarded refused by guard
+type GuardedGuardError struct {
+	State GuardedState
+	Event string
+	Guard string
+}
+
+func (e *GuardedGuardError) Error() string {
+	return fmt.Sprintf("Guarded transition %s from %s refused by guard %s", e.Event, e.State, e.Guard)
+}
+
+// Operate executes behaviour for the current state Guarded.
+// If guard refuses transition, machine remains in current state and *GuardedGuardError is returned.
+func (m *Guarded) Operate(operator GuardedBehaviour) error {
+	switch m.state {
+	case Broken:
+		return nil
+	case Locked:
+		return m.handleLockedEvent(operator, operator.OperateLocked())
+	case Unlocked:
+		return m.handleUnlockedEvent(operator, operator.OperateUnlocked())
+	}
+	return nil
+}
+
+// GuardedEventNotAllowedError reports event fired in state of Guarded that doesn't declare it
+type GuardedEventNotAllowedError struct {
+	State GuardedState
+	Event GuardedEvent
+}
+
+func (e *GuardedEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Guarded event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Guarded.
+// If current state doesn't declare event, machine remains in it and *GuardedEventNotAllowedError is returned.
+// If guard refuses transition, *GuardedGuardError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Guarded) Fire(operator GuardedBehaviour, ev GuardedEvent) error {
+	switch ev {
+	case GuardedEventCoin:
+		switch m.state {
+		case Locked:
+			return m.handleLockedEvent(operator, LockedCoin)
+		case Unlocked:
+			return m.handleUnlockedEvent(operator, UnlockedCoin)
+		}
+		return &GuardedEventNotAllowedError{State: m.state, Event: ev}
+	case GuardedEventKick:
+		switch m.state {
+		case Locked:
+			return m.handleLockedEvent(operator, LockedKick)
+		case Unlocked:
+			return m.handleUnlockedEvent(operator, UnlockedKick)
+		}
+		return &GuardedEventNotAllowedError{State: m.state, Event: ev}
+	case GuardedEventPush:
+		switch m.state {
+		case Unlocked:
+			return m.handleUnlockedEvent(operator, UnlockedPush)
+		}
+		return &GuardedEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &GuardedEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Guarded in Graphviz format
+func (m *Guarded) Visualize() string {
+	return `// Definition for Guarded in Graphviz format 
+digraph Guarded {
+	__initial [shape=point];
+	__initial -> Locked;
+	Broken [shape=Msquare];
+	Locked -> Unlocked [label="Coin [PaymentAccepted]"];
+	Locked -> Broken [label=Kick];
+	Unlocked -> Unlocked [label="Coin [PaymentAccepted]"];
+	Unlocked -> Broken [label="Kick [Alarmed]"];
+	Unlocked -> Locked [label=Push];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Guarded) handleLockedEvent(operator GuardedBehaviour, event GuardedLockedEvent) error {
+	switch event {
+	case LockedCoin:
+		if !operator.PaymentAccepted() {
+			return &GuardedGuardError{State: Locked, Event: event.String(), Guard: "PaymentAccepted"}
+		}
+		m.state = Unlocked
+	case LockedKick:
+		m.state = Broken
+	case LockedNoop:
+	}
+	return nil
+}
+
+func (m *Guarded) handleUnlockedEvent(operator GuardedBehaviour, event GuardedUnlockedEvent) error {
+	switch event {
+	case UnlockedCoin:
+		if !operator.PaymentAccepted() {
+			return &GuardedGuardError{State: Unlocked, Event: event.String(), Guard: "PaymentAccepted"}
+		}
+		m.state = Unlocked
+	case UnlockedKick:
+		if !operator.Alarmed() {
+			return &GuardedGuardError{State: Unlocked, Event: event.String(), Guard: "Alarmed"}
+		}
+		m.state = Broken
+	case UnlockedPush:
+		m.state = Locked
+	case UnlockedNoop:
+	}
+	return nil
+}
+
+//--- Here we will define all events ---
+
+//=== GuardedEvent definition ===
+
+// GuardedEvent is event of any state of Guarded that can be fired
+type GuardedEvent int
+
+const (
+	_ GuardedEvent = iota
+	GuardedEventCoin
+	GuardedEventKick
+	GuardedEventPush
+)
+
+var _GuardedEventMap = map[GuardedEvent]string{
+	GuardedEventCoin: "Coin",
+	GuardedEventKick: "Kick",
+	GuardedEventPush: "Push",
+}
+
+func (m GuardedEvent) String() string {
+	return _GuardedEventMap[m]
+}
+
+//=== GuardedLockedEvent definition ===
+
+// GuardedLockedEvent definition
+type GuardedLockedEvent int
+
+const (
+	_          GuardedLockedEvent = iota
+	LockedCoin                    // LockedCoin -> Unlocked if PaymentAccepted
+	LockedKick                    // LockedKick -> Broken
+	LockedNoop                    // remain in Locked
+)
+
+var _GuardedLockedEventMap = map[GuardedLockedEvent]string{
+	LockedCoin: "LockedCoin",
+	LockedKick: "LockedKick",
+	LockedNoop: "LockedNoop",
+}
+
+func (m GuardedLockedEvent) String() string {
+	return _GuardedLockedEventMap[m]
+}
+
+// GuardedLockedState behaviour
+type GuardedLockedState interface {
+	OperateLocked() GuardedLockedEvent
+}
+
+// GuardedLockedGuards allow or refuse transitions from Locked
+type GuardedLockedGuards interface {
+	PaymentAccepted() bool
+}
+
+//=== GuardedUnlockedEvent definition ===
+
+// GuardedUnlockedEvent definition
+type GuardedUnlockedEvent int
+
+const (
+	_            GuardedUnlockedEvent = iota
+	UnlockedCoin                      // UnlockedCoin -> Unlocked if PaymentAccepted
+	UnlockedKick                      // UnlockedKick -> Broken if Alarmed
+	UnlockedPush                      // UnlockedPush -> Locked
+	UnlockedNoop                      // remain in Unlocked
+)
+
+var _GuardedUnlockedEventMap = map[GuardedUnlockedEvent]string{
+	UnlockedCoin: "UnlockedCoin",
+	UnlockedKick: "UnlockedKick",
+	UnlockedPush: "UnlockedPush",
+	UnlockedNoop: "UnlockedNoop",
+}
+
+func (m GuardedUnlockedEvent) String() string {
+	return _GuardedUnlockedEventMap[m]
+}
+
+// GuardedUnlockedState behaviour
+type GuardedUnlockedState interface {
+	OperateUnlocked() GuardedUnlockedEvent
+}
+
+// GuardedUnlockedGuards allow or refuse transitions from Unlocked
+type GuardedUnlockedGuards interface {
+	Alarmed() bool
+	PaymentAccepted() bool
+}
@@ -0,0 +1,527 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint HierarchyDeclaration 917b147b90d31c5c
+
+//+++ General machine definition +++
+
+// HierarchyState type definition
+type HierarchyState int
+
+const (
+	_        HierarchyState = iota
+	Dead                    // Dead state
+	Degraded                // Degraded composite state
+	Offline                 // Offline state
+	Online                  // Online composite state
+	Probing                 // Probing state
+	Ready                   // Ready state
+	Sending                 // Sending state
+	Waiting                 // Waiting state
+)
+
+var _HierarchyStateMap = map[HierarchyState]string{
+	Dead:     "Dead",
+	Degraded: "Degraded",
+	Offline:  "Offline",
+	Online:   "Online",
+	Probing:  "Probing",
+	Ready:    "Ready",
+	Sending:  "Sending",
+	Waiting:  "Waiting",
+}
+
+var _HierarchyParsingStateMap = map[string]HierarchyState{
+	"Dead":    Dead,
+	"Offline": Offline,
+	"Probing": Probing,
+	"Ready":   Ready,
+	"Sending": Sending,
+	"Waiting": Waiting,
+}
+
+func (s HierarchyState) String() string {
+	return _HierarchyStateMap[s]
+}
+
+var _HierarchyParentStateMap = map[HierarchyState]HierarchyState{
+	Degraded: Online,
+	Probing:  Degraded,
+	Ready:    Online,
+	Sending:  Online,
+	Waiting:  Degraded,
+}
+
+// Parent returns composite state that contains s
+func (s HierarchyState) Parent() HierarchyState {
+	return _HierarchyParentStateMap[s]
+}
+
+// In reports whether s is the same as state or nested inside of it
+func (s HierarchyState) In(state HierarchyState) bool {
+	for ; s != 0; s = s.Parent() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// HierarchyBehaviour definition
+type HierarchyBehaviour interface {
+	HierarchyDegradedEntry
+	HierarchyOfflineState
+	HierarchyOnlineExit
+	HierarchyProbingState
+	HierarchyReadyState
+	HierarchySendingState
+	HierarchySendingGuards
+	HierarchySendingEntry
+	HierarchyWaitingState
+}
+
+// Hierarchy machine type
+type Hierarchy struct {
+	state HierarchyState
+}
+
+// NewHierarchy creates machine in its initial state Ready
+func NewHierarchy() *Hierarchy {
+	return &Hierarchy{state: Ready}
+}
+
+// NewHierarchyFromString can be used to deserialize  machine state
+func NewHierarchyFromString(stateStr string) (*Hierarchy, error) {
+	state, ok := _HierarchyParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Hierarchy: %s", stateStr)
+	}
+	return &Hierarchy{state: state}, nil
+}
+
+// Current returns current state of Hierarchy
+func (m *Hierarchy) Current() HierarchyState {
+	return m.state
+}
+
+// HierarchyGuardError reports transition of Hierarchy refused by guard
+type HierarchyGuardError struct {
+	State HierarchyState
+	Event string
+	Guard string
+}
+
+func (e *HierarchyGuardError) Error() string {
+	return fmt.Sprintf("Hierarchy transition %s from %s refused by guard %s", e.Event, e.State, e.Guard)
+}
+
+// Operate executes behaviour for the current state Hierarchy.
+// If guard refuses transition, machine remains in current state and *HierarchyGuardError is returned.
+// On transition exit action of the current state is executed first,
+// then state is changed and entry action of the destination state is executed.
+func (m *Hierarchy) Operate(operator HierarchyBehaviour) error {
+	switch m.state {
+	case Dead:
+		return nil
+	case Offline:
+		return m.handleOfflineEvent(operator, operator.OperateOffline())
+	case Probing:
+		return m.handleProbingEvent(operator, operator.OperateProbing())
+	case Ready:
+		return m.handleReadyEvent(operator, operator.OperateReady())
+	case Sending:
+		return m.handleSendingEvent(operator, operator.OperateSending())
+	case Waiting:
+		return m.handleWaitingEvent(operator, operator.OperateWaiting())
+	}
+	return nil
+}
+
+// HierarchyEventNotAllowedError reports event fired in state of Hierarchy that doesn't declare it
+type HierarchyEventNotAllowedError struct {
+	State HierarchyState
+	Event HierarchyEvent
+}
+
+func (e *HierarchyEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Hierarchy event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Hierarchy.
+// If current state doesn't declare event, machine remains in it and *HierarchyEventNotAllowedError is returned.
+// If guard refuses transition, *HierarchyGuardError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Hierarchy) Fire(operator HierarchyBehaviour, ev HierarchyEvent) error {
+	switch ev {
+	case HierarchyEventDone:
+		switch m.state {
+		case Sending:
+			return m.handleSendingEvent(operator, SendingDone)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	case HierarchyEventPlug:
+		switch m.state {
+		case Offline:
+			return m.handleOfflineEvent(operator, OfflinePlug)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	case HierarchyEventProbe:
+		switch m.state {
+		case Probing:
+			return m.handleProbingEvent(operator, ProbingProbe)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	case HierarchyEventRepair:
+		switch m.state {
+		case Offline:
+			return m.handleOfflineEvent(operator, OfflineRepair)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	case HierarchyEventReset:
+		switch m.state {
+		case Waiting:
+			return m.handleWaitingEvent(operator, WaitingReset)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	case HierarchyEventSend:
+		switch m.state {
+		case Ready:
+			return m.handleReadyEvent(operator, ReadySend)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	case HierarchyEventTick:
+		switch m.state {
+		case Probing:
+			return m.handleProbingEvent(operator, ProbingTick)
+		case Waiting:
+			return m.handleWaitingEvent(operator, WaitingTick)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	case HierarchyEventUnplug:
+		switch m.state {
+		case Probing:
+			return m.handleProbingEvent(operator, ProbingUnplug)
+		case Ready:
+			return m.handleReadyEvent(operator, ReadyUnplug)
+		case Sending:
+			return m.handleSendingEvent(operator, SendingUnplug)
+		case Waiting:
+			return m.handleWaitingEvent(operator, WaitingUnplug)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	case HierarchyEventWait:
+		switch m.state {
+		case Probing:
+			return m.handleProbingEvent(operator, ProbingWait)
+		}
+		return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &HierarchyEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Hierarchy in Graphviz format
+func (m *Hierarchy) Visualize() string {
+	return `// Definition for Hierarchy in Graphviz format 
+digraph Hierarchy {
+	compound=true;
+	__initial [shape=point];
+	__initial -> Ready [lhead=cluster_Online];
+	Dead [shape=Msquare];
+	Offline;
+	subgraph cluster_Online {
+		label=Online;
+		subgraph cluster_Degraded {
+			label=Degraded;
+			Probing;
+			Waiting;
+		}
+		Ready;
+		Sending;
+	}
+	Probing -> Probing [label=Tick, ltail=cluster_Degraded];
+	Offline -> Ready [label=Plug, lhead=cluster_Online];
+	Offline -> Probing [label=Repair, lhead=cluster_Degraded];
+	Ready -> Offline [label=Unplug, ltail=cluster_Online];
+	Probing -> Ready [label=Probe];
+	Probing -> Waiting [label=Wait];
+	Ready -> Sending [label=Send];
+	Sending -> Ready [label=Done];
+	Sending -> Dead [label="Unplug [Fatal]"];
+	Waiting -> Ready [label=Reset, lhead=cluster_Online];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Hierarchy) handleOfflineEvent(operator HierarchyBehaviour, event HierarchyOfflineEvent) error {
+	switch event {
+	case OfflinePlug:
+		m.state = Ready
+	case OfflineRepair:
+		m.state = Probing
+		operator.OnDegradedEnter()
+	case OfflineNoop:
+	}
+	return nil
+}
+
+func (m *Hierarchy) handleProbingEvent(operator HierarchyBehaviour, event HierarchyProbingEvent) error {
+	switch event {
+	case ProbingProbe:
+		m.state = Ready
+	case ProbingTick:
+		m.state = Probing
+	case ProbingUnplug:
+		operator.OnOnlineExit()
+		m.state = Offline
+	case ProbingWait:
+		m.state = Waiting
+	case ProbingNoop:
+	}
+	return nil
+}
+
+func (m *Hierarchy) handleReadyEvent(operator HierarchyBehaviour, event HierarchyReadyEvent) error {
+	switch event {
+	case ReadySend:
+		m.state = Sending
+		operator.OnSendingEnter()
+	case ReadyUnplug:
+		operator.OnOnlineExit()
+		m.state = Offline
+	case ReadyNoop:
+	}
+	return nil
+}
+
+func (m *Hierarchy) handleSendingEvent(operator HierarchyBehaviour, event HierarchySendingEvent) error {
+	switch event {
+	case SendingDone:
+		m.state = Ready
+	case SendingUnplug:
+		if !operator.Fatal() {
+			return &HierarchyGuardError{State: Sending, Event: event.String(), Guard: "Fatal"}
+		}
+		operator.OnOnlineExit()
+		m.state = Dead
+	case SendingNoop:
+	}
+	return nil
+}
+
+func (m *Hierarchy) handleWaitingEvent(operator HierarchyBehaviour, event HierarchyWaitingEvent) error {
+	switch event {
+	case WaitingReset:
+		operator.OnOnlineExit()
+		m.state = Ready
+	case WaitingTick:
+		m.state = Probing
+	case WaitingUnplug:
+		operator.OnOnlineExit()
+		m.state = Offline
+	case WaitingNoop:
+	}
+	return nil
+}
+
+//--- Here we will define all events ---
+
+//=== HierarchyEvent definition ===
+
+// HierarchyEvent is event of any state of Hierarchy that can be fired
+type HierarchyEvent int
+
+const (
+	_ HierarchyEvent = iota
+	HierarchyEventDone
+	HierarchyEventPlug
+	HierarchyEventProbe
+	HierarchyEventRepair
+	HierarchyEventReset
+	HierarchyEventSend
+	HierarchyEventTick
+	HierarchyEventUnplug
+	HierarchyEventWait
+)
+
+var _HierarchyEventMap = map[HierarchyEvent]string{
+	HierarchyEventDone:   "Done",
+	HierarchyEventPlug:   "Plug",
+	HierarchyEventProbe:  "Probe",
+	HierarchyEventRepair: "Repair",
+	HierarchyEventReset:  "Reset",
+	HierarchyEventSend:   "Send",
+	HierarchyEventTick:   "Tick",
+	HierarchyEventUnplug: "Unplug",
+	HierarchyEventWait:   "Wait",
+}
+
+func (m HierarchyEvent) String() string {
+	return _HierarchyEventMap[m]
+}
+
+//=== HierarchyOfflineEvent definition ===
+
+// HierarchyOfflineEvent definition
+type HierarchyOfflineEvent int
+
+const (
+	_             HierarchyOfflineEvent = iota
+	OfflinePlug                         // OfflinePlug -> Online
+	OfflineRepair                       // OfflineRepair -> Degraded
+	OfflineNoop                         // remain in Offline
+)
+
+var _HierarchyOfflineEventMap = map[HierarchyOfflineEvent]string{
+	OfflinePlug:   "OfflinePlug",
+	OfflineRepair: "OfflineRepair",
+	OfflineNoop:   "OfflineNoop",
+}
+
+func (m HierarchyOfflineEvent) String() string {
+	return _HierarchyOfflineEventMap[m]
+}
+
+// HierarchyOfflineState behaviour
+type HierarchyOfflineState interface {
+	OperateOffline() HierarchyOfflineEvent
+}
+
+//=== HierarchyProbingEvent definition ===
+
+// HierarchyProbingEvent definition
+type HierarchyProbingEvent int
+
+const (
+	_             HierarchyProbingEvent = iota
+	ProbingProbe                        // ProbingProbe -> Ready
+	ProbingTick                         // ProbingTick -> Probing
+	ProbingUnplug                       // ProbingUnplug -> Offline
+	ProbingWait                         // ProbingWait -> Waiting
+	ProbingNoop                         // remain in Probing
+)
+
+var _HierarchyProbingEventMap = map[HierarchyProbingEvent]string{
+	ProbingProbe:  "ProbingProbe",
+	ProbingTick:   "ProbingTick",
+	ProbingUnplug: "ProbingUnplug",
+	ProbingWait:   "ProbingWait",
+	ProbingNoop:   "ProbingNoop",
+}
+
+func (m HierarchyProbingEvent) String() string {
+	return _HierarchyProbingEventMap[m]
+}
+
+// HierarchyProbingState behaviour
+type HierarchyProbingState interface {
+	OperateProbing() HierarchyProbingEvent
+}
+
+//=== HierarchyReadyEvent definition ===
+
+// HierarchyReadyEvent definition
+type HierarchyReadyEvent int
+
+const (
+	_           HierarchyReadyEvent = iota
+	ReadySend                       // ReadySend -> Sending
+	ReadyUnplug                     // ReadyUnplug -> Offline
+	ReadyNoop                       // remain in Ready
+)
+
+var _HierarchyReadyEventMap = map[HierarchyReadyEvent]string{
+	ReadySend:   "ReadySend",
+	ReadyUnplug: "ReadyUnplug",
+	ReadyNoop:   "ReadyNoop",
+}
+
+func (m HierarchyReadyEvent) String() string {
+	return _HierarchyReadyEventMap[m]
+}
+
+// HierarchyReadyState behaviour
+type HierarchyReadyState interface {
+	OperateReady() HierarchyReadyEvent
+}
+
+//=== HierarchySendingEvent definition ===
+
+// HierarchySendingEvent definition
+type HierarchySendingEvent int
+
+const (
+	_             HierarchySendingEvent = iota
+	SendingDone                         // SendingDone -> Ready
+	SendingUnplug                       // SendingUnplug -> Dead if Fatal
+	SendingNoop                         // remain in Sending
+)
+
+var _HierarchySendingEventMap = map[HierarchySendingEvent]string{
+	SendingDone:   "SendingDone",
+	SendingUnplug: "SendingUnplug",
+	SendingNoop:   "SendingNoop",
+}
+
+func (m HierarchySendingEvent) String() string {
+	return _HierarchySendingEventMap[m]
+}
+
+// HierarchySendingState behaviour
+type HierarchySendingState interface {
+	OperateSending() HierarchySendingEvent
+}
+
+// HierarchySendingGuards allow or refuse transitions from Sending
+type HierarchySendingGuards interface {
+	Fatal() bool
+}
+
+//=== HierarchyWaitingEvent definition ===
+
+// HierarchyWaitingEvent definition
+type HierarchyWaitingEvent int
+
+const (
+	_             HierarchyWaitingEvent = iota
+	WaitingReset                        // WaitingReset -> Online
+	WaitingTick                         // WaitingTick -> Probing
+	WaitingUnplug                       // WaitingUnplug -> Offline
+	WaitingNoop                         // remain in Waiting
+)
+
+var _HierarchyWaitingEventMap = map[HierarchyWaitingEvent]string{
+	WaitingReset:  "WaitingReset",
+	WaitingTick:   "WaitingTick",
+	WaitingUnplug: "WaitingUnplug",
+	WaitingNoop:   "WaitingNoop",
+}
+
+func (m HierarchyWaitingEvent) String() string {
+	return _HierarchyWaitingEventMap[m]
+}
+
+// HierarchyWaitingState behaviour
+type HierarchyWaitingState interface {
+	OperateWaiting() HierarchyWaitingEvent
+}
+
+//--- Here we will define all state actions ---
+
+// HierarchyDegradedEntry action executed when machine enters Degraded
+type HierarchyDegradedEntry interface {
+	OnDegradedEnter()
+}
+
+// HierarchyOnlineExit action executed when machine leaves Online
+type HierarchyOnlineExit interface {
+	OnOnlineExit()
+}
+
+// HierarchySendingEntry action executed when machine enters Sending
+type HierarchySendingEntry interface {
+	OnSendingEnter()
+}
@@ -0,0 +1,226 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint InitialDeclaration 6a7a49228a9f8a56
+
+//+++ General machine definition +++
+
+// InitialState type definition
+type InitialState int
+
+const (
+	_       InitialState = iota
+	Failed               // Failed state
+	Idle                 // Idle state
+	Running              // Running state
+)
+
+var _InitialStateMap = map[InitialState]string{
+	Failed:  "Failed",
+	Idle:    "Idle",
+	Running: "Running",
+}
+
+var _InitialParsingStateMap = map[string]InitialState{
+	"Failed":  Failed,
+	"Idle":    Idle,
+	"Running": Running,
+}
+
+func (s InitialState) String() string {
+	return _InitialStateMap[s]
+}
+
+// InitialBehaviour definition
+type InitialBehaviour interface {
+	InitialIdleState
+	InitialRunningState
+}
+
+// Initial machine type
+type Initial struct {
+	state InitialState
+}
+
+// NewInitial creates machine in its initial state Idle
+func NewInitial() *Initial {
+	return &Initial{state: Idle}
+}
+
+// NewInitialFromString can be used to deserialize  machine state
+func NewInitialFromString(stateStr string) (*Initial, error) {
+	state, ok := _InitialParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Initial: %s", stateStr)
+	}
+	return &Initial{state: state}, nil
+}
+
+// Current returns current state of Initial
+func (m *Initial) Current() InitialState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Initial
+func (m *Initial) Operate(operator InitialBehaviour) {
+	switch m.state {
+	case Failed:
+		return
+	case Idle:
+		m.handleIdleEvent(operator.OperateIdle())
+	case Running:
+		m.handleRunningEvent(operator.OperateRunning())
+	}
+}
+
+// InitialEventNotAllowedError reports event fired in state of Initial that doesn't declare it
+type InitialEventNotAllowedError struct {
+	State InitialState
+	Event InitialEvent
+}
+
+func (e *InitialEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Initial event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Initial.
+// If current state doesn't declare event, machine remains in it and *InitialEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Initial) Fire(ev InitialEvent) error {
+	switch ev {
+	case InitialEventFail:
+		switch m.state {
+		case Running:
+			m.handleRunningEvent(RunningFail)
+			return nil
+		}
+		return &InitialEventNotAllowedError{State: m.state, Event: ev}
+	case InitialEventStart:
+		switch m.state {
+		case Idle:
+			m.handleIdleEvent(IdleStart)
+			return nil
+		}
+		return &InitialEventNotAllowedError{State: m.state, Event: ev}
+	case InitialEventStop:
+		switch m.state {
+		case Running:
+			m.handleRunningEvent(RunningStop)
+			return nil
+		}
+		return &InitialEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &InitialEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Initial in Graphviz format
+func (m *Initial) Visualize() string {
+	return `// Definition for Initial in Graphviz format 
+digraph Initial {
+	__initial [shape=point];
+	__initial -> Idle;
+	Failed [shape=Msquare];
+	Idle -> Running [label=Start];
+	Running -> Failed [label=Fail];
+	Running -> Idle [label=Stop];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Initial) handleIdleEvent(event InitialIdleEvent) {
+	switch event {
+	case IdleStart:
+		m.state = Running
+	case IdleNoop:
+	}
+}
+
+func (m *Initial) handleRunningEvent(event InitialRunningEvent) {
+	switch event {
+	case RunningFail:
+		m.state = Failed
+	case RunningStop:
+		m.state = Idle
+	case RunningNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== InitialEvent definition ===
+
+// InitialEvent is event of any state of Initial that can be fired
+type InitialEvent int
+
+const (
+	_ InitialEvent = iota
+	InitialEventFail
+	InitialEventStart
+	InitialEventStop
+)
+
+var _InitialEventMap = map[InitialEvent]string{
+	InitialEventFail:  "Fail",
+	InitialEventStart: "Start",
+	InitialEventStop:  "Stop",
+}
+
+func (m InitialEvent) String() string {
+	return _InitialEventMap[m]
+}
+
+//=== InitialIdleEvent definition ===
+
+// InitialIdleEvent definition
+type InitialIdleEvent int
+
+const (
+	_         InitialIdleEvent = iota
+	IdleStart                  // IdleStart -> Running
+	IdleNoop                   // remain in Idle
+)
+
+var _InitialIdleEventMap = map[InitialIdleEvent]string{
+	IdleStart: "IdleStart",
+	IdleNoop:  "IdleNoop",
+}
+
+func (m InitialIdleEvent) String() string {
+	return _InitialIdleEventMap[m]
+}
+
+// InitialIdleState behaviour
+type InitialIdleState interface {
+	OperateIdle() InitialIdleEvent
+}
+
+//=== InitialRunningEvent definition ===
+
+// InitialRunningEvent definition
+type InitialRunningEvent int
+
+const (
+	_           InitialRunningEvent = iota
+	RunningFail                     // RunningFail -> Failed
+	RunningStop                     // RunningStop -> Idle
+	RunningNoop                     // remain in Running
+)
+
+var _InitialRunningEventMap = map[InitialRunningEvent]string{
+	RunningFail: "RunningFail",
+	RunningStop: "RunningStop",
+	RunningNoop: "RunningNoop",
+}
+
+func (m InitialRunningEvent) String() string {
+	return _InitialRunningEventMap[m]
+}
+
+// InitialRunningState behaviour
+type InitialRunningState interface {
+	OperateRunning() InitialRunningEvent
+}
@@ -0,0 +1,355 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint JobDeclaration d1a3351f51beebbe
+
+//+++ General machine definition +++
+
+// JobState type definition
+type JobState int
+
+const (
+	_         JobState = iota
+	Cancelled          // Cancelled state
+	Computing          // Computing state
+	Executing          // Executing composite state
+	Fetching           // Fetching state
+	Queued             // Queued state
+	Succeeded          // Succeeded state
+)
+
+var _JobStateMap = map[JobState]string{
+	Cancelled: "Cancelled",
+	Computing: "Computing",
+	Executing: "Executing",
+	Fetching:  "Fetching",
+	Queued:    "Queued",
+	Succeeded: "Succeeded",
+}
+
+var _JobParsingStateMap = map[string]JobState{
+	"Cancelled": Cancelled,
+	"Computing": Computing,
+	"Fetching":  Fetching,
+	"Queued":    Queued,
+	"Succeeded": Succeeded,
+}
+
+func (s JobState) String() string {
+	return _JobStateMap[s]
+}
+
+var _JobParentStateMap = map[JobState]JobState{
+	Computing: Executing,
+	Fetching:  Executing,
+}
+
+// Parent returns composite state that contains s
+func (s JobState) Parent() JobState {
+	return _JobParentStateMap[s]
+}
+
+// In reports whether s is the same as state or nested inside of it
+func (s JobState) In(state JobState) bool {
+	for ; s != 0; s = s.Parent() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// JobBehaviour definition
+type JobBehaviour interface {
+	JobComputingState
+	JobFetchingState
+	JobQueuedState
+}
+
+// Job machine type
+type Job struct {
+	state JobState
+}
+
+// NewJob creates machine in its initial state Queued
+func NewJob() *Job {
+	return &Job{state: Queued}
+}
+
+// NewJobFromString can be used to deserialize  machine state
+func NewJobFromString(stateStr string) (*Job, error) {
+	state, ok := _JobParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Job: %s", stateStr)
+	}
+	return &Job{state: state}, nil
+}
+
+// Current returns current state of Job
+func (m *Job) Current() JobState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Job
+func (m *Job) Operate(operator JobBehaviour) {
+	switch m.state {
+	case Cancelled:
+		return
+	case Computing:
+		m.handleComputingEvent(operator.OperateComputing())
+	case Fetching:
+		m.handleFetchingEvent(operator.OperateFetching())
+	case Queued:
+		m.handleQueuedEvent(operator.OperateQueued())
+	case Succeeded:
+		return
+	}
+}
+
+// JobEventNotAllowedError reports event fired in state of Job that doesn't declare it
+type JobEventNotAllowedError struct {
+	State JobState
+	Event JobEvent
+}
+
+func (e *JobEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Job event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Job.
+// If current state doesn't declare event, machine remains in it and *JobEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Job) Fire(ev JobEvent) error {
+	switch ev {
+	case JobEventCancel:
+		switch m.state {
+		case Computing:
+			m.handleComputingEvent(ComputingCancel)
+			return nil
+		case Fetching:
+			m.handleFetchingEvent(FetchingCancel)
+			return nil
+		case Queued:
+			m.handleQueuedEvent(QueuedCancel)
+			return nil
+		}
+		return &JobEventNotAllowedError{State: m.state, Event: ev}
+	case JobEventComputed:
+		switch m.state {
+		case Computing:
+			m.handleComputingEvent(ComputingComputed)
+			return nil
+		}
+		return &JobEventNotAllowedError{State: m.state, Event: ev}
+	case JobEventFetched:
+		switch m.state {
+		case Fetching:
+			m.handleFetchingEvent(FetchingFetched)
+			return nil
+		}
+		return &JobEventNotAllowedError{State: m.state, Event: ev}
+	case JobEventKill:
+		switch m.state {
+		case Computing:
+			m.handleComputingEvent(ComputingKill)
+			return nil
+		case Fetching:
+			m.handleFetchingEvent(FetchingKill)
+			return nil
+		case Queued:
+			m.handleQueuedEvent(QueuedKill)
+			return nil
+		}
+		return &JobEventNotAllowedError{State: m.state, Event: ev}
+	case JobEventStart:
+		switch m.state {
+		case Queued:
+			m.handleQueuedEvent(QueuedStart)
+			return nil
+		}
+		return &JobEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &JobEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Job in Graphviz format
+func (m *Job) Visualize() string {
+	return `// Definition for Job in Graphviz format 
+digraph Job {
+	compound=true;
+	__initial [shape=point];
+	__initial -> Queued;
+	Cancelled [shape=Msquare];
+	subgraph cluster_Executing {
+		label=Executing;
+		Computing;
+		Fetching;
+	}
+	Queued;
+	Succeeded [shape=Msquare];
+	Computing -> Succeeded [label=Computed];
+	Computing -> Cancelled [label=Kill];
+	Fetching -> Queued [label=Cancel, ltail=cluster_Executing];
+	Fetching -> Computing [label=Fetched];
+	Fetching -> Cancelled [label=Kill];
+	Queued -> Cancelled [label=Cancel];
+	Queued -> Cancelled [label=Kill];
+	Queued -> Fetching [label=Start, lhead=cluster_Executing];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Job) handleComputingEvent(event JobComputingEvent) {
+	switch event {
+	case ComputingCancel:
+		m.state = Queued
+	case ComputingComputed:
+		m.state = Succeeded
+	case ComputingKill:
+		m.state = Cancelled
+	case ComputingNoop:
+	}
+}
+
+func (m *Job) handleFetchingEvent(event JobFetchingEvent) {
+	switch event {
+	case FetchingCancel:
+		m.state = Queued
+	case FetchingFetched:
+		m.state = Computing
+	case FetchingKill:
+		m.state = Cancelled
+	case FetchingNoop:
+	}
+}
+
+func (m *Job) handleQueuedEvent(event JobQueuedEvent) {
+	switch event {
+	case QueuedCancel:
+		m.state = Cancelled
+	case QueuedKill:
+		m.state = Cancelled
+	case QueuedStart:
+		m.state = Fetching
+	case QueuedNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== JobEvent definition ===
+
+// JobEvent is event of any state of Job that can be fired
+type JobEvent int
+
+const (
+	_ JobEvent = iota
+	JobEventCancel
+	JobEventComputed
+	JobEventFetched
+	JobEventKill
+	JobEventStart
+)
+
+var _JobEventMap = map[JobEvent]string{
+	JobEventCancel:   "Cancel",
+	JobEventComputed: "Computed",
+	JobEventFetched:  "Fetched",
+	JobEventKill:     "Kill",
+	JobEventStart:    "Start",
+}
+
+func (m JobEvent) String() string {
+	return _JobEventMap[m]
+}
+
+//=== JobComputingEvent definition ===
+
+// JobComputingEvent definition
+type JobComputingEvent int
+
+const (
+	_                 JobComputingEvent = iota
+	ComputingCancel                     // ComputingCancel -> Queued
+	ComputingComputed                   // ComputingComputed -> Succeeded
+	ComputingKill                       // ComputingKill -> Cancelled
+	ComputingNoop                       // remain in Computing
+)
+
+var _JobComputingEventMap = map[JobComputingEvent]string{
+	ComputingCancel:   "ComputingCancel",
+	ComputingComputed: "ComputingComputed",
+	ComputingKill:     "ComputingKill",
+	ComputingNoop:     "ComputingNoop",
+}
+
+func (m JobComputingEvent) String() string {
+	return _JobComputingEventMap[m]
+}
+
+// JobComputingState behaviour
+type JobComputingState interface {
+	OperateComputing() JobComputingEvent
+}
+
+//=== JobFetchingEvent definition ===
+
+// JobFetchingEvent definition
+type JobFetchingEvent int
+
+const (
+	_               JobFetchingEvent = iota
+	FetchingCancel                   // FetchingCancel -> Queued
+	FetchingFetched                  // FetchingFetched -> Computing
+	FetchingKill                     // FetchingKill -> Cancelled
+	FetchingNoop                     // remain in Fetching
+)
+
+var _JobFetchingEventMap = map[JobFetchingEvent]string{
+	FetchingCancel:  "FetchingCancel",
+	FetchingFetched: "FetchingFetched",
+	FetchingKill:    "FetchingKill",
+	FetchingNoop:    "FetchingNoop",
+}
+
+func (m JobFetchingEvent) String() string {
+	return _JobFetchingEventMap[m]
+}
+
+// JobFetchingState behaviour
+type JobFetchingState interface {
+	OperateFetching() JobFetchingEvent
+}
+
+//=== JobQueuedEvent definition ===
+
+// JobQueuedEvent definition
+type JobQueuedEvent int
+
+const (
+	_            JobQueuedEvent = iota
+	QueuedCancel                // QueuedCancel -> Cancelled
+	QueuedKill                  // QueuedKill -> Cancelled
+	QueuedStart                 // QueuedStart -> Executing
+	QueuedNoop                  // remain in Queued
+)
+
+var _JobQueuedEventMap = map[JobQueuedEvent]string{
+	QueuedCancel: "QueuedCancel",
+	QueuedKill:   "QueuedKill",
+	QueuedStart:  "QueuedStart",
+	QueuedNoop:   "QueuedNoop",
+}
+
+func (m JobQueuedEvent) String() string {
+	return _JobQueuedEventMap[m]
+}
+
+// JobQueuedState behaviour
+type JobQueuedState interface {
+	OperateQueued() JobQueuedEvent
+}
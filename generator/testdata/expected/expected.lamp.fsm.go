@@ -0,0 +1,268 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint LampDeclaration 20b04ab577ac00c2
+
+//+++ General machine definition +++
+
+// LampState type definition
+type LampState int
+
+const (
+	_ LampState = iota
+	// Dark lamp waits to be switched on.
+	Dark
+	// Lit lamp shines until it is switched off or its filament burns out.
+	Lit
+	// Burnt lamp has to be replaced.
+	Burnt
+)
+
+var _LampStateMap = map[LampState]string{
+	Dark:  "Dark",
+	Lit:   "Lit",
+	Burnt: "Burnt",
+}
+
+var _LampParsingStateMap = map[string]LampState{
+	"Dark":  Dark,
+	"Lit":   Lit,
+	"Burnt": Burnt,
+}
+
+func (s LampState) String() string {
+	return _LampStateMap[s]
+}
+
+// LampBehaviour definition
+type LampBehaviour interface {
+	LampDarkState
+	LampLitState
+
+	LampBurntListener
+}
+
+// Lamp machine type
+type Lamp struct {
+	state LampState
+}
+
+// NewLamp creates machine in its initial state Dark
+func NewLamp() *Lamp {
+	return &Lamp{state: Dark}
+}
+
+// NewLampFromString can be used to deserialize  machine state
+func NewLampFromString(stateStr string) (*Lamp, error) {
+	state, ok := _LampParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Lamp: %s", stateStr)
+	}
+	return &Lamp{state: state}, nil
+}
+
+// Current returns current state of Lamp
+func (m *Lamp) Current() LampState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Lamp
+func (m *Lamp) Operate(operator LampBehaviour) {
+	switch m.state {
+	case Dark:
+		m.handleDarkEvent(operator, operator.OperateDark())
+	case Lit:
+		m.handleLitEvent(operator, operator.OperateLit())
+	case Burnt:
+		return
+	}
+}
+
+// LampEventNotAllowedError reports event fired in state of Lamp that doesn't declare it
+type LampEventNotAllowedError struct {
+	State LampState
+	Event LampEvent
+}
+
+func (e *LampEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Lamp event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Lamp.
+// If current state doesn't declare event, machine remains in it and *LampEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Lamp) Fire(operator LampBehaviour, ev LampEvent) error {
+	switch ev.id {
+	case _LampEventFlip:
+		switch m.state {
+		case Dark:
+			m.handleDarkEvent(operator, DarkFlip)
+			return nil
+		case Lit:
+			m.handleLitEvent(operator, LitFlip)
+			return nil
+		}
+		return &LampEventNotAllowedError{State: m.state, Event: ev}
+	case _LampEventBurn:
+		switch m.state {
+		case Lit:
+			m.handleLitEvent(operator, LitBurn(ev.burnPayload))
+			return nil
+		}
+		return &LampEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &LampEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Lamp in Graphviz format
+func (m *Lamp) Visualize() string {
+	return `// Definition for Lamp in Graphviz format 
+digraph Lamp {
+	__initial [shape=point];
+	__initial -> Dark;
+	Dark [tooltip="Dark lamp waits to be switched on."];
+	Dark -> Lit [label=Flip, tooltip="turns the lamp on."];
+	Lit [tooltip="Lit lamp shines until it is switched off or its filament burns out."];
+	Lit -> Dark [label=Flip, tooltip="turns the lamp off."];
+	Lit -> Burnt [label=Burn, tooltip="reports \"broken\" filament with its 'cause'."];
+	Burnt [shape=Msquare, tooltip="Burnt lamp has to be replaced."];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Lamp) handleDarkEvent(operator LampBehaviour, event LampDarkEvent) {
+	switch event {
+	case DarkFlip:
+		m.state = Lit
+	case DarkNoop:
+	}
+}
+
+func (m *Lamp) handleLitEvent(operator LampBehaviour, event LampLitEvent) {
+	switch event.id {
+	case _LitFlip:
+		m.state = Dark
+	case _LitBurn:
+		m.state = Burnt
+		operator.OnBurntBurn(event.burnPayload)
+	case _LitNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== LampEvent definition ===
+
+// LampEvent is event of any state of Lamp that can be fired, some of events carry payload
+type LampEvent struct {
+	id          int
+	burnPayload error
+}
+
+const (
+	_ = iota
+	_LampEventFlip
+	_LampEventBurn
+)
+
+var (
+	LampEventFlip = LampEvent{id: _LampEventFlip}
+)
+
+// LampEventBurn creates event Burn that carries payload
+func LampEventBurn(payload error) LampEvent {
+	return LampEvent{id: _LampEventBurn, burnPayload: payload}
+}
+
+var _LampEventMap = map[int]string{
+	_LampEventFlip: "Flip",
+	_LampEventBurn: "Burn",
+}
+
+func (m LampEvent) String() string {
+	return _LampEventMap[m.id]
+}
+
+//=== LampDarkEvent definition ===
+
+// LampDarkEvent definition
+type LampDarkEvent int
+
+const (
+	_ LampDarkEvent = iota
+	// DarkFlip turns the lamp on.
+	DarkFlip // DarkFlip -> Lit
+	DarkNoop // remain in Dark
+)
+
+var _LampDarkEventMap = map[LampDarkEvent]string{
+	DarkFlip: "DarkFlip",
+	DarkNoop: "DarkNoop",
+}
+
+func (m LampDarkEvent) String() string {
+	return _LampDarkEventMap[m]
+}
+
+// LampDarkState behaviour
+type LampDarkState interface {
+	// Dark lamp waits to be switched on.
+	// DarkFlip turns the lamp on.
+	OperateDark() LampDarkEvent
+}
+
+//=== LampLitEvent definition ===
+
+// LampLitEvent definition, some of events carry payload
+type LampLitEvent struct {
+	id          int
+	burnPayload error
+}
+
+const (
+	_ = iota
+	_LitFlip
+	_LitBurn
+	_LitNoop
+)
+
+var (
+	// LitFlip turns the lamp off.
+	LitFlip = LampLitEvent{id: _LitFlip} // LitFlip -> Dark
+	LitNoop = LampLitEvent{id: _LitNoop} // remain in Lit
+)
+
+// LitBurn creates event LitBurn -> Burnt that carries payload
+// LitBurn reports "broken" filament with its `cause`.
+func LitBurn(payload error) LampLitEvent {
+	return LampLitEvent{id: _LitBurn, burnPayload: payload}
+}
+
+var _LampLitEventMap = map[int]string{
+	_LitFlip: "LitFlip",
+	_LitBurn: "LitBurn",
+	_LitNoop: "LitNoop",
+}
+
+func (m LampLitEvent) String() string {
+	return _LampLitEventMap[m.id]
+}
+
+// LampLitState behaviour
+type LampLitState interface {
+	// Lit lamp shines until it is switched off or its filament burns out.
+	// LitFlip turns the lamp off.
+	// LitBurn reports "broken" filament with its `cause`.
+	OperateLit() LampLitEvent
+}
+
+//--- Here we will define all payload listeners ---
+
+// LampBurntListener receives payloads of events that lead to Burnt
+type LampBurntListener interface {
+	OnBurntBurn(payload error)
+}
@@ -0,0 +1,438 @@
+package testdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint LinkDeclaration 6aa0cf917e24dd0c
+
+//+++ General machine definition +++
+
+// LinkState type definition
+type LinkState int
+
+const (
+	_             LinkState = iota
+	Anonymous               // Anonymous state
+	Authenticated           // Authenticated state
+	Closed                  // Closed state
+	Connected               // Connected state
+	Disconnected            // Disconnected state
+	Expired                 // Expired state
+)
+
+var _LinkStateMap = map[LinkState]string{
+	Anonymous:     "Anonymous",
+	Authenticated: "Authenticated",
+	Closed:        "Closed",
+	Connected:     "Connected",
+	Disconnected:  "Disconnected",
+	Expired:       "Expired",
+}
+
+var _LinkAuthParsingStateMap = map[string]LinkState{
+	"Anonymous":     Anonymous,
+	"Authenticated": Authenticated,
+	"Expired":       Expired,
+}
+
+var _LinkConnectionParsingStateMap = map[string]LinkState{
+	"Closed":       Closed,
+	"Connected":    Connected,
+	"Disconnected": Disconnected,
+}
+
+func (s LinkState) String() string {
+	return _LinkStateMap[s]
+}
+
+// LinkBehaviour definition
+type LinkBehaviour interface {
+	LinkAnonymousState
+	LinkAnonymousGuards
+	LinkAuthenticatedState
+
+	LinkConnectedState
+	LinkDisconnectedState
+}
+
+// Link machine type with orthogonal regions
+type Link struct {
+	authState       LinkState
+	connectionState LinkState
+}
+
+// NewLink creates machine with every region in its initial state
+func NewLink() *Link {
+	return &Link{
+		authState:       Anonymous,
+		connectionState: Disconnected,
+	}
+}
+
+// NewLinkFromString can be used to deserialize machine state
+// serialized by String method as comma-separated states of regions
+func NewLinkFromString(stateStr string) (*Link, error) {
+	states := strings.Split(stateStr, ",")
+	if len(states) != 2 {
+		return nil, fmt.Errorf("state unknown for Link: %s", stateStr)
+	}
+	m := &Link{}
+	var ok bool
+	m.authState, ok = _LinkAuthParsingStateMap[states[0]]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Link region Auth: %s", states[0])
+	}
+	m.connectionState, ok = _LinkConnectionParsingStateMap[states[1]]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Link region Connection: %s", states[1])
+	}
+	return m, nil
+}
+
+// String returns comma-separated states of Link regions
+func (m *Link) String() string {
+	return strings.Join([]string{
+		m.authState.String(),
+		m.connectionState.String(),
+	}, ",")
+}
+
+// CurrentAuth returns current state of Link in region Auth
+func (m *Link) CurrentAuth() LinkState {
+	return m.authState
+}
+
+// CurrentConnection returns current state of Link in region Connection
+func (m *Link) CurrentConnection() LinkState {
+	return m.connectionState
+}
+
+// In reports whether any region of Link is in state
+func (m *Link) In(state LinkState) bool {
+	return m.authState == state || m.connectionState == state
+}
+
+// Terminated reports whether all regions of Link are in terminal states
+func (m *Link) Terminated() bool {
+	return _LinkTerminalStates[m.authState] && _LinkTerminalStates[m.connectionState]
+}
+
+var _LinkTerminalStates = map[LinkState]bool{
+	Closed:  true,
+	Expired: true,
+}
+
+// LinkGuardError reports transition of Link refused by guard
+type LinkGuardError struct {
+	State LinkState
+	Event string
+	Guard string
+}
+
+func (e *LinkGuardError) Error() string {
+	return fmt.Sprintf("Link transition %s from %s refused by guard %s", e.Event, e.State, e.Guard)
+}
+
+// Operate executes behaviour for the current state Link.
+// If guard refuses transition, machine remains in current state and *LinkGuardError is returned.
+// Every region is operated independently and the first guard error is returned.
+func (m *Link) Operate(operator LinkBehaviour) error {
+	var result error
+	switch m.authState {
+	case Anonymous:
+		if err := m.handleAnonymousEvent(operator, operator.OperateAnonymous()); err != nil && result == nil {
+			result = err
+		}
+	case Authenticated:
+		if err := m.handleAuthenticatedEvent(operator, operator.OperateAuthenticated()); err != nil && result == nil {
+			result = err
+		}
+	}
+	switch m.connectionState {
+	case Connected:
+		if err := m.handleConnectedEvent(operator, operator.OperateConnected()); err != nil && result == nil {
+			result = err
+		}
+	case Disconnected:
+		if err := m.handleDisconnectedEvent(operator, operator.OperateDisconnected()); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// LinkEventNotAllowedError reports event fired in state of Link that doesn't declare it
+type LinkEventNotAllowedError struct {
+	State LinkState
+	Event LinkEvent
+}
+
+func (e *LinkEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Link event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Link.
+// If current state doesn't declare event, machine remains in it and *LinkEventNotAllowedError is returned.
+// If guard refuses transition, *LinkGuardError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Link) Fire(operator LinkBehaviour, ev LinkEvent) error {
+	switch ev {
+	case LinkEventClose:
+		switch m.connectionState {
+		case Connected:
+			return m.handleConnectedEvent(operator, ConnectedClose)
+		}
+		return &LinkEventNotAllowedError{State: m.connectionState, Event: ev}
+	case LinkEventDial:
+		switch m.connectionState {
+		case Disconnected:
+			return m.handleDisconnectedEvent(operator, DisconnectedDial)
+		}
+		return &LinkEventNotAllowedError{State: m.connectionState, Event: ev}
+	case LinkEventDrop:
+		switch m.connectionState {
+		case Connected:
+			return m.handleConnectedEvent(operator, ConnectedDrop)
+		}
+		return &LinkEventNotAllowedError{State: m.connectionState, Event: ev}
+	case LinkEventExpire:
+		switch m.authState {
+		case Authenticated:
+			return m.handleAuthenticatedEvent(operator, AuthenticatedExpire)
+		}
+		return &LinkEventNotAllowedError{State: m.authState, Event: ev}
+	case LinkEventLogin:
+		switch m.authState {
+		case Anonymous:
+			return m.handleAnonymousEvent(operator, AnonymousLogin)
+		}
+		return &LinkEventNotAllowedError{State: m.authState, Event: ev}
+	case LinkEventLogout:
+		switch m.authState {
+		case Authenticated:
+			return m.handleAuthenticatedEvent(operator, AuthenticatedLogout)
+		}
+		return &LinkEventNotAllowedError{State: m.authState, Event: ev}
+	}
+	return &LinkEventNotAllowedError{Event: ev}
+}
+
+// Visualize states and events for Link in Graphviz format
+func (m *Link) Visualize() string {
+	return `// Definition for Link in Graphviz format 
+digraph Link {
+	compound=true;
+	__initial_Auth [shape=point];
+	__initial_Auth -> Anonymous;
+	subgraph cluster_Auth {
+		label=Auth;
+		style=dashed;
+		Anonymous;
+		Authenticated;
+		Expired [shape=Msquare];
+	}
+	__initial_Connection [shape=point];
+	__initial_Connection -> Disconnected;
+	subgraph cluster_Connection {
+		label=Connection;
+		style=dashed;
+		Closed [shape=Msquare];
+		Connected;
+		Disconnected;
+	}
+	Anonymous -> Authenticated [label="Login [CredentialsValid]"];
+	Authenticated -> Expired [label=Expire];
+	Authenticated -> Anonymous [label=Logout];
+	Connected -> Closed [label=Close];
+	Connected -> Disconnected [label=Drop];
+	Disconnected -> Connected [label=Dial];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Link) handleAnonymousEvent(operator LinkBehaviour, event LinkAnonymousEvent) error {
+	switch event {
+	case AnonymousLogin:
+		if !operator.CredentialsValid() {
+			return &LinkGuardError{State: Anonymous, Event: event.String(), Guard: "CredentialsValid"}
+		}
+		m.authState = Authenticated
+	case AnonymousNoop:
+	}
+	return nil
+}
+
+func (m *Link) handleAuthenticatedEvent(operator LinkBehaviour, event LinkAuthenticatedEvent) error {
+	switch event {
+	case AuthenticatedExpire:
+		m.authState = Expired
+	case AuthenticatedLogout:
+		m.authState = Anonymous
+	case AuthenticatedNoop:
+	}
+	return nil
+}
+
+func (m *Link) handleConnectedEvent(operator LinkBehaviour, event LinkConnectedEvent) error {
+	switch event {
+	case ConnectedClose:
+		m.connectionState = Closed
+	case ConnectedDrop:
+		m.connectionState = Disconnected
+	case ConnectedNoop:
+	}
+	return nil
+}
+
+func (m *Link) handleDisconnectedEvent(operator LinkBehaviour, event LinkDisconnectedEvent) error {
+	switch event {
+	case DisconnectedDial:
+		m.connectionState = Connected
+	case DisconnectedNoop:
+	}
+	return nil
+}
+
+//--- Here we will define all events ---
+
+//=== LinkEvent definition ===
+
+// LinkEvent is event of any state of Link that can be fired
+type LinkEvent int
+
+const (
+	_ LinkEvent = iota
+	LinkEventClose
+	LinkEventDial
+	LinkEventDrop
+	LinkEventExpire
+	LinkEventLogin
+	LinkEventLogout
+)
+
+var _LinkEventMap = map[LinkEvent]string{
+	LinkEventClose:  "Close",
+	LinkEventDial:   "Dial",
+	LinkEventDrop:   "Drop",
+	LinkEventExpire: "Expire",
+	LinkEventLogin:  "Login",
+	LinkEventLogout: "Logout",
+}
+
+func (m LinkEvent) String() string {
+	return _LinkEventMap[m]
+}
+
+//=== LinkAnonymousEvent definition ===
+
+// LinkAnonymousEvent definition
+type LinkAnonymousEvent int
+
+const (
+	_              LinkAnonymousEvent = iota
+	AnonymousLogin                    // AnonymousLogin -> Authenticated if CredentialsValid
+	AnonymousNoop                     // remain in Anonymous
+)
+
+var _LinkAnonymousEventMap = map[LinkAnonymousEvent]string{
+	AnonymousLogin: "AnonymousLogin",
+	AnonymousNoop:  "AnonymousNoop",
+}
+
+func (m LinkAnonymousEvent) String() string {
+	return _LinkAnonymousEventMap[m]
+}
+
+// LinkAnonymousState behaviour
+type LinkAnonymousState interface {
+	OperateAnonymous() LinkAnonymousEvent
+}
+
+// LinkAnonymousGuards allow or refuse transitions from Anonymous
+type LinkAnonymousGuards interface {
+	CredentialsValid() bool
+}
+
+//=== LinkAuthenticatedEvent definition ===
+
+// LinkAuthenticatedEvent definition
+type LinkAuthenticatedEvent int
+
+const (
+	_                   LinkAuthenticatedEvent = iota
+	AuthenticatedExpire                        // AuthenticatedExpire -> Expired
+	AuthenticatedLogout                        // AuthenticatedLogout -> Anonymous
+	AuthenticatedNoop                          // remain in Authenticated
+)
+
+var _LinkAuthenticatedEventMap = map[LinkAuthenticatedEvent]string{
+	AuthenticatedExpire: "AuthenticatedExpire",
+	AuthenticatedLogout: "AuthenticatedLogout",
+	AuthenticatedNoop:   "AuthenticatedNoop",
+}
+
+func (m LinkAuthenticatedEvent) String() string {
+	return _LinkAuthenticatedEventMap[m]
+}
+
+// LinkAuthenticatedState behaviour
+type LinkAuthenticatedState interface {
+	OperateAuthenticated() LinkAuthenticatedEvent
+}
+
+//=== LinkConnectedEvent definition ===
+
+// LinkConnectedEvent definition
+type LinkConnectedEvent int
+
+const (
+	_              LinkConnectedEvent = iota
+	ConnectedClose                    // ConnectedClose -> Closed
+	ConnectedDrop                     // ConnectedDrop -> Disconnected
+	ConnectedNoop                     // remain in Connected
+)
+
+var _LinkConnectedEventMap = map[LinkConnectedEvent]string{
+	ConnectedClose: "ConnectedClose",
+	ConnectedDrop:  "ConnectedDrop",
+	ConnectedNoop:  "ConnectedNoop",
+}
+
+func (m LinkConnectedEvent) String() string {
+	return _LinkConnectedEventMap[m]
+}
+
+// LinkConnectedState behaviour
+type LinkConnectedState interface {
+	OperateConnected() LinkConnectedEvent
+}
+
+//=== LinkDisconnectedEvent definition ===
+
+// LinkDisconnectedEvent definition
+type LinkDisconnectedEvent int
+
+const (
+	_                LinkDisconnectedEvent = iota
+	DisconnectedDial                       // DisconnectedDial -> Connected
+	DisconnectedNoop                       // remain in Disconnected
+)
+
+var _LinkDisconnectedEventMap = map[LinkDisconnectedEvent]string{
+	DisconnectedDial: "DisconnectedDial",
+	DisconnectedNoop: "DisconnectedNoop",
+}
+
+func (m LinkDisconnectedEvent) String() string {
+	return _LinkDisconnectedEventMap[m]
+}
+
+// LinkDisconnectedState behaviour
+type LinkDisconnectedState interface {
+	OperateDisconnected() LinkDisconnectedEvent
+}
@@ -0,0 +1,291 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint OrderDeclaration 3292458082e11c68
+
+//+++ General machine definition +++
+
+// OrderState type definition
+type OrderState int
+
+const (
+	Paid     OrderState = 2  // Paid state
+	Placed   OrderState = 1  // Placed state
+	Received OrderState = 10 // Received state
+	Shipped  OrderState = 4  // Shipped state
+	Voided   OrderState = 9  // Voided state
+)
+
+var _OrderStateMap = map[OrderState]string{
+	Paid:     "Paid",
+	Placed:   "Placed",
+	Received: "Received",
+	Shipped:  "Shipped",
+	Voided:   "Voided",
+}
+
+var _OrderParsingStateMap = map[string]OrderState{
+	"Paid":     Paid,
+	"Placed":   Placed,
+	"Received": Received,
+	"Shipped":  Shipped,
+	"Voided":   Voided,
+}
+
+func (s OrderState) String() string {
+	return _OrderStateMap[s]
+}
+
+// OrderBehaviour definition
+type OrderBehaviour interface {
+	OrderPaidState
+	OrderPlacedState
+
+	OrderShippedState
+}
+
+// Order machine type
+type Order struct {
+	state OrderState
+}
+
+// NewOrder creates machine in its initial state Placed
+func NewOrder() *Order {
+	return &Order{state: Placed}
+}
+
+// NewOrderFromString can be used to deserialize  machine state
+func NewOrderFromString(stateStr string) (*Order, error) {
+	state, ok := _OrderParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Order: %s", stateStr)
+	}
+	return &Order{state: state}, nil
+}
+
+// Current returns current state of Order
+func (m *Order) Current() OrderState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Order
+func (m *Order) Operate(operator OrderBehaviour) {
+	switch m.state {
+	case Paid:
+		m.handlePaidEvent(operator.OperatePaid())
+	case Placed:
+		m.handlePlacedEvent(operator.OperatePlaced())
+	case Received:
+		return
+	case Shipped:
+		m.handleShippedEvent(operator.OperateShipped())
+	case Voided:
+		return
+	}
+}
+
+// OrderEventNotAllowedError reports event fired in state of Order that doesn't declare it
+type OrderEventNotAllowedError struct {
+	State OrderState
+	Event OrderEvent
+}
+
+func (e *OrderEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Order event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Order.
+// If current state doesn't declare event, machine remains in it and *OrderEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Order) Fire(ev OrderEvent) error {
+	switch ev {
+	case OrderEventCancel:
+		switch m.state {
+		case Placed:
+			m.handlePlacedEvent(PlacedCancel)
+			return nil
+		}
+		return &OrderEventNotAllowedError{State: m.state, Event: ev}
+	case OrderEventDeliver:
+		switch m.state {
+		case Shipped:
+			m.handleShippedEvent(ShippedDeliver)
+			return nil
+		}
+		return &OrderEventNotAllowedError{State: m.state, Event: ev}
+	case OrderEventPay:
+		switch m.state {
+		case Placed:
+			m.handlePlacedEvent(PlacedPay)
+			return nil
+		}
+		return &OrderEventNotAllowedError{State: m.state, Event: ev}
+	case OrderEventRefund:
+		switch m.state {
+		case Paid:
+			m.handlePaidEvent(PaidRefund)
+			return nil
+		}
+		return &OrderEventNotAllowedError{State: m.state, Event: ev}
+	case OrderEventShip:
+		switch m.state {
+		case Paid:
+			m.handlePaidEvent(PaidShip)
+			return nil
+		}
+		return &OrderEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &OrderEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Order in Graphviz format
+func (m *Order) Visualize() string {
+	return `// Definition for Order in Graphviz format 
+digraph Order {
+	__initial [shape=point];
+	__initial -> Placed;
+	Paid -> Voided [label=Refund];
+	Paid -> Shipped [label=Ship];
+	Placed -> Voided [label=Cancel];
+	Placed -> Paid [label=Pay];
+	Received [shape=Msquare];
+	Shipped -> Received [label=Deliver];
+	Voided [shape=Msquare];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Order) handlePaidEvent(event OrderPaidEvent) {
+	switch event {
+	case PaidRefund:
+		m.state = Voided
+	case PaidShip:
+		m.state = Shipped
+	case PaidNoop:
+	}
+}
+
+func (m *Order) handlePlacedEvent(event OrderPlacedEvent) {
+	switch event {
+	case PlacedCancel:
+		m.state = Voided
+	case PlacedPay:
+		m.state = Paid
+	case PlacedNoop:
+	}
+}
+
+func (m *Order) handleShippedEvent(event OrderShippedEvent) {
+	switch event {
+	case ShippedDeliver:
+		m.state = Received
+	case ShippedNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== OrderEvent definition ===
+
+// OrderEvent is event of any state of Order that can be fired
+type OrderEvent int
+
+const (
+	OrderEventCancel  OrderEvent = 1
+	OrderEventDeliver OrderEvent = 2
+	OrderEventPay     OrderEvent = 3
+	OrderEventRefund  OrderEvent = 4
+	OrderEventShip    OrderEvent = 5
+)
+
+var _OrderEventMap = map[OrderEvent]string{
+	OrderEventCancel:  "Cancel",
+	OrderEventDeliver: "Deliver",
+	OrderEventPay:     "Pay",
+	OrderEventRefund:  "Refund",
+	OrderEventShip:    "Ship",
+}
+
+func (m OrderEvent) String() string {
+	return _OrderEventMap[m]
+}
+
+//=== OrderPaidEvent definition ===
+
+// OrderPaidEvent definition
+type OrderPaidEvent int
+
+const (
+	PaidRefund OrderPaidEvent = 2 // PaidRefund -> Voided
+	PaidShip   OrderPaidEvent = 3 // PaidShip -> Shipped
+	PaidNoop   OrderPaidEvent = 4 // remain in Paid
+)
+
+var _OrderPaidEventMap = map[OrderPaidEvent]string{
+	PaidRefund: "PaidRefund",
+	PaidShip:   "PaidShip",
+	PaidNoop:   "PaidNoop",
+}
+
+func (m OrderPaidEvent) String() string {
+	return _OrderPaidEventMap[m]
+}
+
+// OrderPaidState behaviour
+type OrderPaidState interface {
+	OperatePaid() OrderPaidEvent
+}
+
+//=== OrderPlacedEvent definition ===
+
+// OrderPlacedEvent definition
+type OrderPlacedEvent int
+
+const (
+	PlacedCancel OrderPlacedEvent = 1 // PlacedCancel -> Voided
+	PlacedPay    OrderPlacedEvent = 2 // PlacedPay -> Paid
+	PlacedNoop   OrderPlacedEvent = 3 // remain in Placed
+)
+
+var _OrderPlacedEventMap = map[OrderPlacedEvent]string{
+	PlacedCancel: "PlacedCancel",
+	PlacedPay:    "PlacedPay",
+	PlacedNoop:   "PlacedNoop",
+}
+
+func (m OrderPlacedEvent) String() string {
+	return _OrderPlacedEventMap[m]
+}
+
+// OrderPlacedState behaviour
+type OrderPlacedState interface {
+	OperatePlaced() OrderPlacedEvent
+}
+
+//=== OrderShippedEvent definition ===
+
+// OrderShippedEvent definition
+type OrderShippedEvent int
+
+const (
+	ShippedDeliver OrderShippedEvent = 1 // ShippedDeliver -> Received
+	ShippedNoop    OrderShippedEvent = 2 // remain in Shipped
+)
+
+var _OrderShippedEventMap = map[OrderShippedEvent]string{
+	ShippedDeliver: "ShippedDeliver",
+	ShippedNoop:    "ShippedNoop",
+}
+
+func (m OrderShippedEvent) String() string {
+	return _OrderShippedEventMap[m]
+}
+
+// OrderShippedState behaviour
+type OrderShippedState interface {
+	OperateShipped() OrderShippedEvent
+}
@@ -0,0 +1,373 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint PumpDeclaration 9b4b14096e00e051
+
+//+++ General machine definition +++
+
+// PumpState type definition
+type PumpState int
+
+const (
+	_       PumpState = iota
+	Standby           // Standby state
+	Active            // Active composite state
+	Priming           // Priming state
+	Flowing           // Flowing state
+	Jammed            // Jammed state
+)
+
+var _PumpStateMap = map[PumpState]string{
+	Standby: "Standby",
+	Active:  "Active",
+	Priming: "Priming",
+	Flowing: "Flowing",
+	Jammed:  "Jammed",
+}
+
+var _PumpParsingStateMap = map[string]PumpState{
+	"Standby": Standby,
+	"Priming": Priming,
+	"Flowing": Flowing,
+	"Jammed":  Jammed,
+}
+
+func (s PumpState) String() string {
+	return _PumpStateMap[s]
+}
+
+var _PumpParentStateMap = map[PumpState]PumpState{
+	Priming: Active,
+	Flowing: Active,
+}
+
+// Parent returns composite state that contains s
+func (s PumpState) Parent() PumpState {
+	return _PumpParentStateMap[s]
+}
+
+// In reports whether s is the same as state or nested inside of it
+func (s PumpState) In(state PumpState) bool {
+	for ; s != 0; s = s.Parent() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// PumpBehaviour definition
+type PumpBehaviour interface {
+	PumpStandbyState
+	PumpPrimingState
+	PumpFlowingState
+}
+
+// Pump machine type
+type Pump struct {
+	state PumpState
+}
+
+// NewPump creates machine in its initial state Standby
+func NewPump() *Pump {
+	return &Pump{state: Standby}
+}
+
+// NewPumpFromString can be used to deserialize  machine state
+func NewPumpFromString(stateStr string) (*Pump, error) {
+	state, ok := _PumpParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Pump: %s", stateStr)
+	}
+	return &Pump{state: state}, nil
+}
+
+// Current returns current state of Pump
+func (m *Pump) Current() PumpState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Pump
+// Commands produced by transitions are returned in order they were produced.
+func (m *Pump) Operate(operator PumpBehaviour) []PumpCommand {
+	var commands []PumpCommand
+	command := m.operateCurrent(operator)
+	if command != 0 {
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// operateCurrent executes behaviour for the current state Pump once
+func (m *Pump) operateCurrent(operator PumpBehaviour) PumpCommand {
+	switch m.state {
+	case Standby:
+		return m.handleStandbyEvent(operator.OperateStandby())
+	case Priming:
+		return m.handlePrimingEvent(operator.OperatePriming())
+	case Flowing:
+		return m.handleFlowingEvent(operator.OperateFlowing())
+	case Jammed:
+		return 0
+	}
+	return 0
+}
+
+// PumpEventNotAllowedError reports event fired in state of Pump that doesn't declare it
+type PumpEventNotAllowedError struct {
+	State PumpState
+	Event PumpEvent
+}
+
+func (e *PumpEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Pump event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Pump.
+// If current state doesn't declare event, machine remains in it and *PumpEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+// Command produced by transition is returned.
+func (m *Pump) Fire(ev PumpEvent) (PumpCommand, error) {
+	switch ev {
+	case PumpEventStart:
+		switch m.state {
+		case Standby:
+			return m.handleStandbyEvent(StandbyStart), nil
+		}
+		return 0, &PumpEventNotAllowedError{State: m.state, Event: ev}
+	case PumpEventFault:
+		switch m.state {
+		case Standby:
+			return m.handleStandbyEvent(StandbyFault), nil
+		case Priming:
+			return m.handlePrimingEvent(PrimingFault), nil
+		case Flowing:
+			return m.handleFlowingEvent(FlowingFault), nil
+		}
+		return 0, &PumpEventNotAllowedError{State: m.state, Event: ev}
+	case PumpEventPrimed:
+		switch m.state {
+		case Priming:
+			return m.handlePrimingEvent(PrimingPrimed), nil
+		}
+		return 0, &PumpEventNotAllowedError{State: m.state, Event: ev}
+	case PumpEventStop:
+		switch m.state {
+		case Priming:
+			return m.handlePrimingEvent(PrimingStop), nil
+		case Flowing:
+			return m.handleFlowingEvent(FlowingStop), nil
+		}
+		return 0, &PumpEventNotAllowedError{State: m.state, Event: ev}
+	case PumpEventDry:
+		switch m.state {
+		case Flowing:
+			return m.handleFlowingEvent(FlowingDry), nil
+		}
+		return 0, &PumpEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return 0, &PumpEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Pump in Graphviz format
+func (m *Pump) Visualize() string {
+	return `// Definition for Pump in Graphviz format 
+digraph Pump {
+	compound=true;
+	__initial [shape=point];
+	__initial -> Standby;
+	Standby;
+	subgraph cluster_Active {
+		label=Active;
+		Priming;
+		Flowing;
+	}
+	Jammed [shape=Msquare];
+	Standby -> Priming [label=Start, lhead=cluster_Active];
+	Standby -> Jammed [label=Fault];
+	Priming -> Standby [label="Stop / CloseValve", ltail=cluster_Active];
+	Priming -> Flowing [label="Primed / OpenValve"];
+	Priming -> Jammed [label=Fault];
+	Flowing -> Priming [label="Dry / CloseValve"];
+	Flowing -> Jammed [label=Fault];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Pump) handleStandbyEvent(event PumpStandbyEvent) PumpCommand {
+	switch event {
+	case StandbyStart:
+		m.state = Priming
+	case StandbyFault:
+		m.state = Jammed
+	case StandbyNoop:
+	}
+	return 0
+}
+
+func (m *Pump) handlePrimingEvent(event PumpPrimingEvent) PumpCommand {
+	switch event {
+	case PrimingPrimed:
+		m.state = Flowing
+		return PumpCommandOpenValve
+	case PrimingStop:
+		m.state = Standby
+		return PumpCommandCloseValve
+	case PrimingFault:
+		m.state = Jammed
+	case PrimingNoop:
+	}
+	return 0
+}
+
+func (m *Pump) handleFlowingEvent(event PumpFlowingEvent) PumpCommand {
+	switch event {
+	case FlowingDry:
+		m.state = Priming
+		return PumpCommandCloseValve
+	case FlowingStop:
+		m.state = Standby
+		return PumpCommandCloseValve
+	case FlowingFault:
+		m.state = Jammed
+	case FlowingNoop:
+	}
+	return 0
+}
+
+//--- Here we will define all events ---
+
+//=== PumpEvent definition ===
+
+// PumpEvent is event of any state of Pump that can be fired
+type PumpEvent int
+
+const (
+	_ PumpEvent = iota
+	PumpEventStart
+	PumpEventFault
+	PumpEventPrimed
+	PumpEventStop
+	PumpEventDry
+)
+
+var _PumpEventMap = map[PumpEvent]string{
+	PumpEventStart:  "Start",
+	PumpEventFault:  "Fault",
+	PumpEventPrimed: "Primed",
+	PumpEventStop:   "Stop",
+	PumpEventDry:    "Dry",
+}
+
+func (m PumpEvent) String() string {
+	return _PumpEventMap[m]
+}
+
+//=== PumpStandbyEvent definition ===
+
+// PumpStandbyEvent definition
+type PumpStandbyEvent int
+
+const (
+	_            PumpStandbyEvent = iota
+	StandbyStart                  // StandbyStart -> Active
+	StandbyFault                  // StandbyFault -> Jammed
+	StandbyNoop                   // remain in Standby
+)
+
+var _PumpStandbyEventMap = map[PumpStandbyEvent]string{
+	StandbyStart: "StandbyStart",
+	StandbyFault: "StandbyFault",
+	StandbyNoop:  "StandbyNoop",
+}
+
+func (m PumpStandbyEvent) String() string {
+	return _PumpStandbyEventMap[m]
+}
+
+// PumpStandbyState behaviour
+type PumpStandbyState interface {
+	OperateStandby() PumpStandbyEvent
+}
+
+//=== PumpPrimingEvent definition ===
+
+// PumpPrimingEvent definition
+type PumpPrimingEvent int
+
+const (
+	_             PumpPrimingEvent = iota
+	PrimingPrimed                  // PrimingPrimed -> Flowing / OpenValve
+	PrimingStop                    // PrimingStop -> Standby / CloseValve
+	PrimingFault                   // PrimingFault -> Jammed
+	PrimingNoop                    // remain in Priming
+)
+
+var _PumpPrimingEventMap = map[PumpPrimingEvent]string{
+	PrimingPrimed: "PrimingPrimed",
+	PrimingStop:   "PrimingStop",
+	PrimingFault:  "PrimingFault",
+	PrimingNoop:   "PrimingNoop",
+}
+
+func (m PumpPrimingEvent) String() string {
+	return _PumpPrimingEventMap[m]
+}
+
+// PumpPrimingState behaviour
+type PumpPrimingState interface {
+	OperatePriming() PumpPrimingEvent
+}
+
+//=== PumpFlowingEvent definition ===
+
+// PumpFlowingEvent definition
+type PumpFlowingEvent int
+
+const (
+	_            PumpFlowingEvent = iota
+	FlowingDry                    // FlowingDry -> Priming / CloseValve
+	FlowingStop                   // FlowingStop -> Standby / CloseValve
+	FlowingFault                  // FlowingFault -> Jammed
+	FlowingNoop                   // remain in Flowing
+)
+
+var _PumpFlowingEventMap = map[PumpFlowingEvent]string{
+	FlowingDry:   "FlowingDry",
+	FlowingStop:  "FlowingStop",
+	FlowingFault: "FlowingFault",
+	FlowingNoop:  "FlowingNoop",
+}
+
+func (m PumpFlowingEvent) String() string {
+	return _PumpFlowingEventMap[m]
+}
+
+// PumpFlowingState behaviour
+type PumpFlowingState interface {
+	OperateFlowing() PumpFlowingEvent
+}
+
+//--- Here we will define all commands ---
+
+// PumpCommand is output of Pump transition that caller should execute
+type PumpCommand int
+
+const (
+	_ PumpCommand = iota
+	PumpCommandOpenValve
+	PumpCommandCloseValve
+)
+
+var _PumpCommandMap = map[PumpCommand]string{
+	PumpCommandOpenValve:  "OpenValve",
+	PumpCommandCloseValve: "CloseValve",
+}
+
+func (m PumpCommand) String() string {
+	return _PumpCommandMap[m]
+}
@@ -0,0 +1,301 @@
+package testdata
+
+import (
+	"fmt"
+	"time"
+)
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint RetryDeclaration ec32f06d76ff3b09
+
+//+++ General machine definition +++
+
+// RetryState type definition
+type RetryState int
+
+const (
+	_          RetryState = iota
+	Abandoned             // Abandoned state
+	Attempting            // Attempting state
+	Backoff               // Backoff state
+	Delivered             // Delivered state
+)
+
+var _RetryStateMap = map[RetryState]string{
+	Abandoned:  "Abandoned",
+	Attempting: "Attempting",
+	Backoff:    "Backoff",
+	Delivered:  "Delivered",
+}
+
+var _RetryParsingStateMap = map[string]RetryState{
+	"Abandoned":  Abandoned,
+	"Attempting": Attempting,
+	"Backoff":    Backoff,
+	"Delivered":  Delivered,
+}
+
+func (s RetryState) String() string {
+	return _RetryStateMap[s]
+}
+
+// RetryBehaviour definition
+type RetryBehaviour interface {
+	RetryAttemptingState
+	RetryBackoffState
+}
+
+// Retry machine type
+type Retry struct {
+	state               RetryState
+	clock               RetryClock
+	attemptingEnteredAt time.Time
+	backoffEnteredAt    time.Time
+}
+
+// NewRetry creates machine in its initial state Attempting
+func NewRetry() *Retry {
+	return &Retry{state: Attempting}
+}
+
+// NewRetryFromString can be used to deserialize  machine state
+func NewRetryFromString(stateStr string) (*Retry, error) {
+	state, ok := _RetryParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Retry: %s", stateStr)
+	}
+	return &Retry{state: state}, nil
+}
+
+// Current returns current state of Retry
+func (m *Retry) Current() RetryState {
+	return m.state
+}
+
+// RetryClock provides current time for timed transitions of Retry
+type RetryClock interface {
+	Now() time.Time
+}
+
+// WithClock replaces clock used by timed transitions of Retry, so tests can control time.
+// Timers of the current states restart from the next Operate call.
+func (m *Retry) WithClock(clock RetryClock) *Retry {
+	m.clock = clock
+	m.attemptingEnteredAt = time.Time{}
+	m.backoffEnteredAt = time.Time{}
+	return m
+}
+
+// now returns current time of Retry clock
+func (m *Retry) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
+
+// Operate executes behaviour for the current state Retry
+func (m *Retry) Operate(operator RetryBehaviour) {
+	switch m.state {
+	case Abandoned:
+		return
+	case Attempting:
+		m.handleAttemptingEvent(m.operateAttempting(operator))
+	case Backoff:
+		m.handleBackoffEvent(m.operateBackoff(operator))
+	case Delivered:
+		return
+	}
+}
+
+// RetryEventNotAllowedError reports event fired in state of Retry that doesn't declare it
+type RetryEventNotAllowedError struct {
+	State RetryState
+	Event RetryEvent
+}
+
+func (e *RetryEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Retry event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Retry.
+// If current state doesn't declare event, machine remains in it and *RetryEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Retry) Fire(ev RetryEvent) error {
+	switch ev {
+	case RetryEventAbort:
+		switch m.state {
+		case Backoff:
+			m.handleBackoffEvent(BackoffAbort)
+			return nil
+		}
+		return &RetryEventNotAllowedError{State: m.state, Event: ev}
+	case RetryEventFailed:
+		switch m.state {
+		case Attempting:
+			m.handleAttemptingEvent(AttemptingFailed)
+			return nil
+		}
+		return &RetryEventNotAllowedError{State: m.state, Event: ev}
+	case RetryEventSucceeded:
+		switch m.state {
+		case Attempting:
+			m.handleAttemptingEvent(AttemptingSucceeded)
+			return nil
+		}
+		return &RetryEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &RetryEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Retry in Graphviz format
+func (m *Retry) Visualize() string {
+	return `// Definition for Retry in Graphviz format 
+digraph Retry {
+	__initial [shape=point];
+	__initial -> Attempting;
+	Abandoned [shape=Msquare];
+	Attempting -> Abandoned [label=After30s];
+	Attempting -> Backoff [label=Failed];
+	Attempting -> Delivered [label=Succeeded];
+	Backoff -> Abandoned [label=Abort];
+	Backoff -> Attempting [label=After250ms];
+	Delivered [shape=Msquare];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Retry) handleAttemptingEvent(event RetryAttemptingEvent) {
+	switch event {
+	case AttemptingAfter30s:
+		m.state = Abandoned
+		m.attemptingEnteredAt = time.Time{}
+	case AttemptingFailed:
+		m.state = Backoff
+		m.attemptingEnteredAt = time.Time{}
+		m.backoffEnteredAt = m.now()
+	case AttemptingSucceeded:
+		m.state = Delivered
+		m.attemptingEnteredAt = time.Time{}
+	case AttemptingNoop:
+	}
+}
+
+// operateAttempting takes the expired timed transition or executes behaviour of Attempting
+func (m *Retry) operateAttempting(operator RetryBehaviour) RetryAttemptingEvent {
+	now := m.now()
+	if m.attemptingEnteredAt.IsZero() {
+		m.attemptingEnteredAt = now
+	}
+	if now.Sub(m.attemptingEnteredAt) >= 30*time.Second {
+		return AttemptingAfter30s
+	}
+	return operator.OperateAttempting()
+}
+
+func (m *Retry) handleBackoffEvent(event RetryBackoffEvent) {
+	switch event {
+	case BackoffAbort:
+		m.state = Abandoned
+		m.backoffEnteredAt = time.Time{}
+	case BackoffAfter250ms:
+		m.state = Attempting
+		m.backoffEnteredAt = time.Time{}
+		m.attemptingEnteredAt = m.now()
+	case BackoffNoop:
+	}
+}
+
+// operateBackoff takes the expired timed transition or executes behaviour of Backoff
+func (m *Retry) operateBackoff(operator RetryBehaviour) RetryBackoffEvent {
+	now := m.now()
+	if m.backoffEnteredAt.IsZero() {
+		m.backoffEnteredAt = now
+	}
+	if now.Sub(m.backoffEnteredAt) >= 250*time.Millisecond {
+		return BackoffAfter250ms
+	}
+	return operator.OperateBackoff()
+}
+
+//--- Here we will define all events ---
+
+//=== RetryEvent definition ===
+
+// RetryEvent is event of any state of Retry that can be fired
+type RetryEvent int
+
+const (
+	_ RetryEvent = iota
+	RetryEventAbort
+	RetryEventFailed
+	RetryEventSucceeded
+)
+
+var _RetryEventMap = map[RetryEvent]string{
+	RetryEventAbort:     "Abort",
+	RetryEventFailed:    "Failed",
+	RetryEventSucceeded: "Succeeded",
+}
+
+func (m RetryEvent) String() string {
+	return _RetryEventMap[m]
+}
+
+//=== RetryAttemptingEvent definition ===
+
+// RetryAttemptingEvent definition
+type RetryAttemptingEvent int
+
+const (
+	_                   RetryAttemptingEvent = iota
+	AttemptingAfter30s                       // AttemptingAfter30s -> Abandoned
+	AttemptingFailed                         // AttemptingFailed -> Backoff
+	AttemptingSucceeded                      // AttemptingSucceeded -> Delivered
+	AttemptingNoop                           // remain in Attempting
+)
+
+var _RetryAttemptingEventMap = map[RetryAttemptingEvent]string{
+	AttemptingAfter30s:  "AttemptingAfter30s",
+	AttemptingFailed:    "AttemptingFailed",
+	AttemptingSucceeded: "AttemptingSucceeded",
+	AttemptingNoop:      "AttemptingNoop",
+}
+
+func (m RetryAttemptingEvent) String() string {
+	return _RetryAttemptingEventMap[m]
+}
+
+// RetryAttemptingState behaviour
+type RetryAttemptingState interface {
+	OperateAttempting() RetryAttemptingEvent
+}
+
+//=== RetryBackoffEvent definition ===
+
+// RetryBackoffEvent definition
+type RetryBackoffEvent int
+
+const (
+	_                 RetryBackoffEvent = iota
+	BackoffAbort                        // BackoffAbort -> Abandoned
+	BackoffAfter250ms                   // BackoffAfter250ms -> Attempting
+	BackoffNoop                         // remain in Backoff
+)
+
+var _RetryBackoffEventMap = map[RetryBackoffEvent]string{
+	BackoffAbort:      "BackoffAbort",
+	BackoffAfter250ms: "BackoffAfter250ms",
+	BackoffNoop:       "BackoffNoop",
+}
+
+func (m RetryBackoffEvent) String() string {
+	return _RetryBackoffEventMap[m]
+}
+
+// RetryBackoffState behaviour
+type RetryBackoffState interface {
+	OperateBackoff() RetryBackoffEvent
+}
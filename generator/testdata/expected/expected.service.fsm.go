@@ -0,0 +1,601 @@
+package testdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint ServiceDeclaration d04593001f996049
+
+//+++ General machine definition +++
+
+// ServiceState type definition
+type ServiceState int
+
+const (
+	_           ServiceState = iota
+	Down                     // Down state
+	Draining                 // Draining state
+	Heavy                    // Heavy state
+	Light                    // Light state
+	Maintenance              // Maintenance state
+	Operating                // Operating composite state
+	Serving                  // Serving composite state
+	Starting                 // Starting state
+)
+
+var _ServiceStateMap = map[ServiceState]string{
+	Down:        "Down",
+	Draining:    "Draining",
+	Heavy:       "Heavy",
+	Light:       "Light",
+	Maintenance: "Maintenance",
+	Operating:   "Operating",
+	Serving:     "Serving",
+	Starting:    "Starting",
+}
+
+var _ServiceParsingStateMap = map[string]ServiceState{
+	"Down":        Down,
+	"Draining":    Draining,
+	"Heavy":       Heavy,
+	"Light":       Light,
+	"Maintenance": Maintenance,
+	"Starting":    Starting,
+}
+
+func (s ServiceState) String() string {
+	return _ServiceStateMap[s]
+}
+
+var _ServiceParentStateMap = map[ServiceState]ServiceState{
+	Draining: Operating,
+	Heavy:    Serving,
+	Light:    Serving,
+	Serving:  Operating,
+	Starting: Operating,
+}
+
+// Parent returns composite state that contains s
+func (s ServiceState) Parent() ServiceState {
+	return _ServiceParentStateMap[s]
+}
+
+// In reports whether s is the same as state or nested inside of it
+func (s ServiceState) In(state ServiceState) bool {
+	for ; s != 0; s = s.Parent() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceBehaviour definition
+type ServiceBehaviour interface {
+	ServiceDrainingState
+	ServiceHeavyState
+	ServiceHeavyEntry
+	ServiceLightState
+	ServiceMaintenanceState
+	ServiceOperatingExit
+	ServiceStartingState
+}
+
+// Service machine type
+type Service struct {
+	state                ServiceState
+	operatingHistory     ServiceState
+	operatingDeepHistory ServiceState
+}
+
+// NewService creates machine in its initial state Starting
+func NewService() *Service {
+	return &Service{state: Starting}
+}
+
+// NewServiceFromString can be used to deserialize machine state serialized by String method
+func NewServiceFromString(stateStr string) (*Service, error) {
+	parts := strings.Split(stateStr, ";")
+	state, ok := _ServiceParsingStateMap[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Service: %s", parts[0])
+	}
+	m := &Service{state: state}
+	if err := m.restoreHistory(parts[1:]); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// String returns current state of Service with history of composite states
+func (m *Service) String() string {
+	return m.state.String() + m.historyString()
+}
+
+// Current returns current state of Service
+func (m *Service) Current() ServiceState {
+	return m.state
+}
+
+// historyString serializes history of composite states
+func (m *Service) historyString() string {
+	result := ""
+	if m.operatingHistory != 0 {
+		result += ";Operating.H=" + m.operatingHistory.String()
+	}
+	if m.operatingDeepHistory != 0 {
+		result += ";Operating.H*=" + m.operatingDeepHistory.String()
+	}
+	return result
+}
+
+// restoreHistory deserializes history of composite states
+func (m *Service) restoreHistory(entries []string) error {
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("history unknown for Service: %s", entry)
+		}
+		var ok bool
+		switch parts[0] {
+		case "Operating.H":
+			m.operatingHistory, ok = _ServiceOperatingHistoryParsingStateMap[parts[1]]
+		case "Operating.H*":
+			m.operatingDeepHistory, ok = _ServiceOperatingDeepHistoryParsingStateMap[parts[1]]
+		default:
+			return fmt.Errorf("history unknown for Service: %s", entry)
+		}
+		if !ok {
+			return fmt.Errorf("state unknown for Service history %s: %s", parts[0], parts[1])
+		}
+	}
+	return nil
+}
+
+var _ServiceOperatingHistoryParsingStateMap = map[string]ServiceState{
+	"Draining": Draining,
+	"Light":    Light,
+	"Starting": Starting,
+}
+
+// restoreOperatingHistory returns the last active sub-state of Operating or its initial state
+func (m *Service) restoreOperatingHistory() ServiceState {
+	if m.operatingHistory == 0 {
+		return Starting
+	}
+	return m.operatingHistory
+}
+
+var _ServiceOperatingDeepHistoryParsingStateMap = map[string]ServiceState{
+	"Draining": Draining,
+	"Heavy":    Heavy,
+	"Light":    Light,
+	"Starting": Starting,
+}
+
+// restoreOperatingDeepHistory returns the last active leaf state of Operating or its initial state
+func (m *Service) restoreOperatingDeepHistory() ServiceState {
+	if m.operatingDeepHistory == 0 {
+		return Starting
+	}
+	return m.operatingDeepHistory
+}
+
+// enterOperatingDeepHistory executes entry actions of states inside of Operating restored from history
+func (m *Service) enterOperatingDeepHistory(operator ServiceBehaviour) {
+	switch m.state {
+	case Heavy:
+		operator.OnHeavyEnter()
+	}
+}
+
+// Operate executes behaviour for the current state Service
+// On transition exit action of the current state is executed first,
+// then state is changed and entry action of the destination state is executed.
+func (m *Service) Operate(operator ServiceBehaviour) {
+	switch m.state {
+	case Down:
+		return
+	case Draining:
+		m.handleDrainingEvent(operator, operator.OperateDraining())
+	case Heavy:
+		m.handleHeavyEvent(operator, operator.OperateHeavy())
+	case Light:
+		m.handleLightEvent(operator, operator.OperateLight())
+	case Maintenance:
+		m.handleMaintenanceEvent(operator, operator.OperateMaintenance())
+	case Starting:
+		m.handleStartingEvent(operator, operator.OperateStarting())
+	}
+}
+
+// ServiceEventNotAllowedError reports event fired in state of Service that doesn't declare it
+type ServiceEventNotAllowedError struct {
+	State ServiceState
+	Event ServiceEvent
+}
+
+func (e *ServiceEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Service event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Service.
+// If current state doesn't declare event, machine remains in it and *ServiceEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Service) Fire(operator ServiceBehaviour, ev ServiceEvent) error {
+	switch ev {
+	case ServiceEventAbort:
+		switch m.state {
+		case Maintenance:
+			m.handleMaintenanceEvent(operator, MaintenanceAbort)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	case ServiceEventDrain:
+		switch m.state {
+		case Heavy:
+			m.handleHeavyEvent(operator, HeavyDrain)
+			return nil
+		case Light:
+			m.handleLightEvent(operator, LightDrain)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	case ServiceEventDrained:
+		switch m.state {
+		case Draining:
+			m.handleDrainingEvent(operator, DrainingDrained)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	case ServiceEventInterrupt:
+		switch m.state {
+		case Draining:
+			m.handleDrainingEvent(operator, DrainingInterrupt)
+			return nil
+		case Heavy:
+			m.handleHeavyEvent(operator, HeavyInterrupt)
+			return nil
+		case Light:
+			m.handleLightEvent(operator, LightInterrupt)
+			return nil
+		case Starting:
+			m.handleStartingEvent(operator, StartingInterrupt)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	case ServiceEventLoad:
+		switch m.state {
+		case Light:
+			m.handleLightEvent(operator, LightLoad)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	case ServiceEventRelief:
+		switch m.state {
+		case Heavy:
+			m.handleHeavyEvent(operator, HeavyRelief)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	case ServiceEventResume:
+		switch m.state {
+		case Maintenance:
+			m.handleMaintenanceEvent(operator, MaintenanceResume)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	case ServiceEventResumeDeep:
+		switch m.state {
+		case Maintenance:
+			m.handleMaintenanceEvent(operator, MaintenanceResumeDeep)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	case ServiceEventStarted:
+		switch m.state {
+		case Starting:
+			m.handleStartingEvent(operator, StartingStarted)
+			return nil
+		}
+		return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &ServiceEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Service in Graphviz format
+func (m *Service) Visualize() string {
+	return `// Definition for Service in Graphviz format 
+digraph Service {
+	compound=true;
+	__initial [shape=point];
+	__initial -> Starting [lhead=cluster_Operating];
+	Down [shape=Msquare];
+	Maintenance;
+	subgraph cluster_Operating {
+		label=Operating;
+		Operating__H [shape=circle, label="H"];
+		Operating__Hdeep [shape=circle, label="H*"];
+		Draining;
+		subgraph cluster_Serving {
+			label=Serving;
+			Heavy;
+			Light;
+		}
+		Starting;
+	}
+	Draining -> Starting [label=Drained];
+	Heavy -> Light [label=Relief];
+	Light -> Heavy [label=Load];
+	Maintenance -> Down [label=Abort];
+	Maintenance -> Operating__H [label=Resume];
+	Maintenance -> Operating__Hdeep [label=ResumeDeep];
+	Starting -> Maintenance [label=Interrupt, ltail=cluster_Operating];
+	Light -> Draining [label=Drain, ltail=cluster_Serving];
+	Starting -> Light [label=Started, lhead=cluster_Serving];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Service) handleDrainingEvent(operator ServiceBehaviour, event ServiceDrainingEvent) {
+	switch event {
+	case DrainingDrained:
+		m.state = Starting
+	case DrainingInterrupt:
+		operator.OnOperatingExit()
+		m.operatingHistory = Draining
+		m.operatingDeepHistory = Draining
+		m.state = Maintenance
+	case DrainingNoop:
+	}
+}
+
+func (m *Service) handleHeavyEvent(operator ServiceBehaviour, event ServiceHeavyEvent) {
+	switch event {
+	case HeavyDrain:
+		m.state = Draining
+	case HeavyInterrupt:
+		operator.OnOperatingExit()
+		m.operatingHistory = Light
+		m.operatingDeepHistory = Heavy
+		m.state = Maintenance
+	case HeavyRelief:
+		m.state = Light
+	case HeavyNoop:
+	}
+}
+
+func (m *Service) handleLightEvent(operator ServiceBehaviour, event ServiceLightEvent) {
+	switch event {
+	case LightDrain:
+		m.state = Draining
+	case LightInterrupt:
+		operator.OnOperatingExit()
+		m.operatingHistory = Light
+		m.operatingDeepHistory = Light
+		m.state = Maintenance
+	case LightLoad:
+		m.state = Heavy
+		operator.OnHeavyEnter()
+	case LightNoop:
+	}
+}
+
+func (m *Service) handleMaintenanceEvent(operator ServiceBehaviour, event ServiceMaintenanceEvent) {
+	switch event {
+	case MaintenanceAbort:
+		m.state = Down
+	case MaintenanceResume:
+		m.state = m.restoreOperatingHistory()
+	case MaintenanceResumeDeep:
+		m.state = m.restoreOperatingDeepHistory()
+		m.enterOperatingDeepHistory(operator)
+	case MaintenanceNoop:
+	}
+}
+
+func (m *Service) handleStartingEvent(operator ServiceBehaviour, event ServiceStartingEvent) {
+	switch event {
+	case StartingInterrupt:
+		operator.OnOperatingExit()
+		m.operatingHistory = Starting
+		m.operatingDeepHistory = Starting
+		m.state = Maintenance
+	case StartingStarted:
+		m.state = Light
+	case StartingNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== ServiceEvent definition ===
+
+// ServiceEvent is event of any state of Service that can be fired
+type ServiceEvent int
+
+const (
+	_ ServiceEvent = iota
+	ServiceEventAbort
+	ServiceEventDrain
+	ServiceEventDrained
+	ServiceEventInterrupt
+	ServiceEventLoad
+	ServiceEventRelief
+	ServiceEventResume
+	ServiceEventResumeDeep
+	ServiceEventStarted
+)
+
+var _ServiceEventMap = map[ServiceEvent]string{
+	ServiceEventAbort:      "Abort",
+	ServiceEventDrain:      "Drain",
+	ServiceEventDrained:    "Drained",
+	ServiceEventInterrupt:  "Interrupt",
+	ServiceEventLoad:       "Load",
+	ServiceEventRelief:     "Relief",
+	ServiceEventResume:     "Resume",
+	ServiceEventResumeDeep: "ResumeDeep",
+	ServiceEventStarted:    "Started",
+}
+
+func (m ServiceEvent) String() string {
+	return _ServiceEventMap[m]
+}
+
+//=== ServiceDrainingEvent definition ===
+
+// ServiceDrainingEvent definition
+type ServiceDrainingEvent int
+
+const (
+	_                 ServiceDrainingEvent = iota
+	DrainingDrained                        // DrainingDrained -> Starting
+	DrainingInterrupt                      // DrainingInterrupt -> Maintenance
+	DrainingNoop                           // remain in Draining
+)
+
+var _ServiceDrainingEventMap = map[ServiceDrainingEvent]string{
+	DrainingDrained:   "DrainingDrained",
+	DrainingInterrupt: "DrainingInterrupt",
+	DrainingNoop:      "DrainingNoop",
+}
+
+func (m ServiceDrainingEvent) String() string {
+	return _ServiceDrainingEventMap[m]
+}
+
+// ServiceDrainingState behaviour
+type ServiceDrainingState interface {
+	OperateDraining() ServiceDrainingEvent
+}
+
+//=== ServiceHeavyEvent definition ===
+
+// ServiceHeavyEvent definition
+type ServiceHeavyEvent int
+
+const (
+	_              ServiceHeavyEvent = iota
+	HeavyDrain                       // HeavyDrain -> Draining
+	HeavyInterrupt                   // HeavyInterrupt -> Maintenance
+	HeavyRelief                      // HeavyRelief -> Light
+	HeavyNoop                        // remain in Heavy
+)
+
+var _ServiceHeavyEventMap = map[ServiceHeavyEvent]string{
+	HeavyDrain:     "HeavyDrain",
+	HeavyInterrupt: "HeavyInterrupt",
+	HeavyRelief:    "HeavyRelief",
+	HeavyNoop:      "HeavyNoop",
+}
+
+func (m ServiceHeavyEvent) String() string {
+	return _ServiceHeavyEventMap[m]
+}
+
+// ServiceHeavyState behaviour
+type ServiceHeavyState interface {
+	OperateHeavy() ServiceHeavyEvent
+}
+
+//=== ServiceLightEvent definition ===
+
+// ServiceLightEvent definition
+type ServiceLightEvent int
+
+const (
+	_              ServiceLightEvent = iota
+	LightDrain                       // LightDrain -> Draining
+	LightInterrupt                   // LightInterrupt -> Maintenance
+	LightLoad                        // LightLoad -> Heavy
+	LightNoop                        // remain in Light
+)
+
+var _ServiceLightEventMap = map[ServiceLightEvent]string{
+	LightDrain:     "LightDrain",
+	LightInterrupt: "LightInterrupt",
+	LightLoad:      "LightLoad",
+	LightNoop:      "LightNoop",
+}
+
+func (m ServiceLightEvent) String() string {
+	return _ServiceLightEventMap[m]
+}
+
+// ServiceLightState behaviour
+type ServiceLightState interface {
+	OperateLight() ServiceLightEvent
+}
+
+//=== ServiceMaintenanceEvent definition ===
+
+// ServiceMaintenanceEvent definition
+type ServiceMaintenanceEvent int
+
+const (
+	_                     ServiceMaintenanceEvent = iota
+	MaintenanceAbort                              // MaintenanceAbort -> Down
+	MaintenanceResume                             // MaintenanceResume -> Operating
+	MaintenanceResumeDeep                         // MaintenanceResumeDeep -> Operating
+	MaintenanceNoop                               // remain in Maintenance
+)
+
+var _ServiceMaintenanceEventMap = map[ServiceMaintenanceEvent]string{
+	MaintenanceAbort:      "MaintenanceAbort",
+	MaintenanceResume:     "MaintenanceResume",
+	MaintenanceResumeDeep: "MaintenanceResumeDeep",
+	MaintenanceNoop:       "MaintenanceNoop",
+}
+
+func (m ServiceMaintenanceEvent) String() string {
+	return _ServiceMaintenanceEventMap[m]
+}
+
+// ServiceMaintenanceState behaviour
+type ServiceMaintenanceState interface {
+	OperateMaintenance() ServiceMaintenanceEvent
+}
+
+//=== ServiceStartingEvent definition ===
+
+// ServiceStartingEvent definition
+type ServiceStartingEvent int
+
+const (
+	_                 ServiceStartingEvent = iota
+	StartingInterrupt                      // StartingInterrupt -> Maintenance
+	StartingStarted                        // StartingStarted -> Serving
+	StartingNoop                           // remain in Starting
+)
+
+var _ServiceStartingEventMap = map[ServiceStartingEvent]string{
+	StartingInterrupt: "StartingInterrupt",
+	StartingStarted:   "StartingStarted",
+	StartingNoop:      "StartingNoop",
+}
+
+func (m ServiceStartingEvent) String() string {
+	return _ServiceStartingEventMap[m]
+}
+
+// ServiceStartingState behaviour
+type ServiceStartingState interface {
+	OperateStarting() ServiceStartingEvent
+}
+
+//--- Here we will define all state actions ---
+
+// ServiceHeavyEntry action executed when machine enters Heavy
+type ServiceHeavyEntry interface {
+	OnHeavyEnter()
+}
+
+// ServiceOperatingExit action executed when machine leaves Operating
+type ServiceOperatingExit interface {
+	OnOperatingExit()
+}
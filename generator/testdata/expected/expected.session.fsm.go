@@ -0,0 +1,349 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint SessionDeclaration b290ccf2090990f6
+
+//+++ General machine definition +++
+
+// SessionState type definition
+type SessionState int
+
+const (
+	_           SessionState = iota
+	Established              // Established state
+	Finished                 // Finished state
+	Handshaking              // Handshaking state
+	Listening                // Listening state
+)
+
+var _SessionStateMap = map[SessionState]string{
+	Established: "Established",
+	Finished:    "Finished",
+	Handshaking: "Handshaking",
+	Listening:   "Listening",
+}
+
+var _SessionParsingStateMap = map[string]SessionState{
+	"Established": Established,
+	"Finished":    Finished,
+	"Handshaking": Handshaking,
+	"Listening":   Listening,
+}
+
+func (s SessionState) String() string {
+	return _SessionStateMap[s]
+}
+
+// SessionBehaviour definition
+type SessionBehaviour interface {
+	SessionEstablishedState
+	SessionEstablishedGuards
+
+	SessionHandshakingState
+	SessionListeningState
+}
+
+// Session machine type
+type Session struct {
+	state SessionState
+}
+
+// NewSession creates machine in its initial state Listening
+func NewSession() *Session {
+	return &Session{state: Listening}
+}
+
+// NewSessionFromString can be used to deserialize  machine state
+func NewSessionFromString(stateStr string) (*Session, error) {
+	state, ok := _SessionParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Session: %s", stateStr)
+	}
+	return &Session{state: state}, nil
+}
+
+// Current returns current state of Session
+func (m *Session) Current() SessionState {
+	return m.state
+}
+
+// SessionGuardError reports transition of Session refused by guard
+type SessionGuardError struct {
+	State SessionState
+	Event string
+	Guard string
+}
+
+func (e *SessionGuardError) Error() string {
+	return fmt.Sprintf("Session transition %s from %s refused by guard %s", e.Event, e.State, e.Guard)
+}
+
+// Operate executes behaviour for the current state Session.
+// If guard refuses transition, machine remains in current state and *SessionGuardError is returned.
+// Commands produced by transitions are returned in order they were produced.
+func (m *Session) Operate(operator SessionBehaviour) ([]SessionCommand, error) {
+	var commands []SessionCommand
+	command, err := m.operateCurrent(operator)
+	if command != 0 {
+		commands = append(commands, command)
+	}
+	return commands, err
+}
+
+// operateCurrent executes behaviour for the current state Session once
+func (m *Session) operateCurrent(operator SessionBehaviour) (SessionCommand, error) {
+	switch m.state {
+	case Established:
+		return m.handleEstablishedEvent(operator, operator.OperateEstablished())
+	case Finished:
+		return 0, nil
+	case Handshaking:
+		return m.handleHandshakingEvent(operator, operator.OperateHandshaking())
+	case Listening:
+		return m.handleListeningEvent(operator, operator.OperateListening())
+	}
+	return 0, nil
+}
+
+// SessionEventNotAllowedError reports event fired in state of Session that doesn't declare it
+type SessionEventNotAllowedError struct {
+	State SessionState
+	Event SessionEvent
+}
+
+func (e *SessionEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Session event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Session.
+// If current state doesn't declare event, machine remains in it and *SessionEventNotAllowedError is returned.
+// If guard refuses transition, *SessionGuardError is returned.
+// Fire doesn't execute state behaviour.
+// Command produced by transition is returned.
+func (m *Session) Fire(operator SessionBehaviour, ev SessionEvent) (SessionCommand, error) {
+	switch ev {
+	case SessionEventAck:
+		switch m.state {
+		case Handshaking:
+			return m.handleHandshakingEvent(operator, HandshakingAck)
+		}
+		return 0, &SessionEventNotAllowedError{State: m.state, Event: ev}
+	case SessionEventData:
+		switch m.state {
+		case Established:
+			return m.handleEstablishedEvent(operator, EstablishedData)
+		}
+		return 0, &SessionEventNotAllowedError{State: m.state, Event: ev}
+	case SessionEventFin:
+		switch m.state {
+		case Established:
+			return m.handleEstablishedEvent(operator, EstablishedFin)
+		}
+		return 0, &SessionEventNotAllowedError{State: m.state, Event: ev}
+	case SessionEventRst:
+		switch m.state {
+		case Handshaking:
+			return m.handleHandshakingEvent(operator, HandshakingRst)
+		}
+		return 0, &SessionEventNotAllowedError{State: m.state, Event: ev}
+	case SessionEventSyn:
+		switch m.state {
+		case Listening:
+			return m.handleListeningEvent(operator, ListeningSyn)
+		}
+		return 0, &SessionEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return 0, &SessionEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Session in Graphviz format
+func (m *Session) Visualize() string {
+	return `// Definition for Session in Graphviz format 
+digraph Session {
+	__initial [shape=point];
+	__initial -> Listening;
+	Established -> Established [label="Data / SendAck"];
+	Established -> Finished [label="Fin [Drained] / CloseSocket"];
+	Finished [shape=Msquare];
+	Handshaking -> Established [label=Ack];
+	Handshaking -> Listening [label="Rst / CloseSocket"];
+	Listening -> Handshaking [label="Syn / SendSynAck"];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Session) handleEstablishedEvent(operator SessionBehaviour, event SessionEstablishedEvent) (SessionCommand, error) {
+	switch event {
+	case EstablishedData:
+		m.state = Established
+		return SessionCommandSendAck, nil
+	case EstablishedFin:
+		if !operator.Drained() {
+			return 0, &SessionGuardError{State: Established, Event: event.String(), Guard: "Drained"}
+		}
+		m.state = Finished
+		return SessionCommandCloseSocket, nil
+	case EstablishedNoop:
+	}
+	return 0, nil
+}
+
+func (m *Session) handleHandshakingEvent(operator SessionBehaviour, event SessionHandshakingEvent) (SessionCommand, error) {
+	switch event {
+	case HandshakingAck:
+		m.state = Established
+	case HandshakingRst:
+		m.state = Listening
+		return SessionCommandCloseSocket, nil
+	case HandshakingNoop:
+	}
+	return 0, nil
+}
+
+func (m *Session) handleListeningEvent(operator SessionBehaviour, event SessionListeningEvent) (SessionCommand, error) {
+	switch event {
+	case ListeningSyn:
+		m.state = Handshaking
+		return SessionCommandSendSynAck, nil
+	case ListeningNoop:
+	}
+	return 0, nil
+}
+
+//--- Here we will define all events ---
+
+//=== SessionEvent definition ===
+
+// SessionEvent is event of any state of Session that can be fired
+type SessionEvent int
+
+const (
+	_ SessionEvent = iota
+	SessionEventAck
+	SessionEventData
+	SessionEventFin
+	SessionEventRst
+	SessionEventSyn
+)
+
+var _SessionEventMap = map[SessionEvent]string{
+	SessionEventAck:  "Ack",
+	SessionEventData: "Data",
+	SessionEventFin:  "Fin",
+	SessionEventRst:  "Rst",
+	SessionEventSyn:  "Syn",
+}
+
+func (m SessionEvent) String() string {
+	return _SessionEventMap[m]
+}
+
+//=== SessionEstablishedEvent definition ===
+
+// SessionEstablishedEvent definition
+type SessionEstablishedEvent int
+
+const (
+	_               SessionEstablishedEvent = iota
+	EstablishedData                         // EstablishedData -> Established / SendAck
+	EstablishedFin                          // EstablishedFin -> Finished if Drained / CloseSocket
+	EstablishedNoop                         // remain in Established
+)
+
+var _SessionEstablishedEventMap = map[SessionEstablishedEvent]string{
+	EstablishedData: "EstablishedData",
+	EstablishedFin:  "EstablishedFin",
+	EstablishedNoop: "EstablishedNoop",
+}
+
+func (m SessionEstablishedEvent) String() string {
+	return _SessionEstablishedEventMap[m]
+}
+
+// SessionEstablishedState behaviour
+type SessionEstablishedState interface {
+	OperateEstablished() SessionEstablishedEvent
+}
+
+// SessionEstablishedGuards allow or refuse transitions from Established
+type SessionEstablishedGuards interface {
+	Drained() bool
+}
+
+//=== SessionHandshakingEvent definition ===
+
+// SessionHandshakingEvent definition
+type SessionHandshakingEvent int
+
+const (
+	_               SessionHandshakingEvent = iota
+	HandshakingAck                          // HandshakingAck -> Established
+	HandshakingRst                          // HandshakingRst -> Listening / CloseSocket
+	HandshakingNoop                         // remain in Handshaking
+)
+
+var _SessionHandshakingEventMap = map[SessionHandshakingEvent]string{
+	HandshakingAck:  "HandshakingAck",
+	HandshakingRst:  "HandshakingRst",
+	HandshakingNoop: "HandshakingNoop",
+}
+
+func (m SessionHandshakingEvent) String() string {
+	return _SessionHandshakingEventMap[m]
+}
+
+// SessionHandshakingState behaviour
+type SessionHandshakingState interface {
+	OperateHandshaking() SessionHandshakingEvent
+}
+
+//=== SessionListeningEvent definition ===
+
+// SessionListeningEvent definition
+type SessionListeningEvent int
+
+const (
+	_             SessionListeningEvent = iota
+	ListeningSyn                        // ListeningSyn -> Handshaking / SendSynAck
+	ListeningNoop                       // remain in Listening
+)
+
+var _SessionListeningEventMap = map[SessionListeningEvent]string{
+	ListeningSyn:  "ListeningSyn",
+	ListeningNoop: "ListeningNoop",
+}
+
+func (m SessionListeningEvent) String() string {
+	return _SessionListeningEventMap[m]
+}
+
+// SessionListeningState behaviour
+type SessionListeningState interface {
+	OperateListening() SessionListeningEvent
+}
+
+//--- Here we will define all commands ---
+
+// SessionCommand is output of Session transition that caller should execute
+type SessionCommand int
+
+const (
+	_ SessionCommand = iota
+	SessionCommandCloseSocket
+	SessionCommandSendAck
+	SessionCommandSendSynAck
+)
+
+var _SessionCommandMap = map[SessionCommand]string{
+	SessionCommandCloseSocket: "CloseSocket",
+	SessionCommandSendAck:     "SendAck",
+	SessionCommandSendSynAck:  "SendSynAck",
+}
+
+func (m SessionCommand) String() string {
+	return _SessionCommandMap[m]
+}
@@ -3,6 +3,7 @@ package testdata
 import "fmt"
 
 // Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint SomeDeclaration 5b5a4b34f6a92dc6
 
 //+++ General machine definition +++
 
@@ -12,23 +13,23 @@ type SomeState int
 const (
 	_      SomeState = iota
 	First            // First state
-	Fourth           // Fourth state
 	Second           // Second state
 	Third            // Third state
+	Fourth           // Fourth state
 )
 
 var _SomeStateMap = map[SomeState]string{
 	First:  "First",
-	Fourth: "Fourth",
 	Second: "Second",
 	Third:  "Third",
+	Fourth: "Fourth",
 }
 
 var _SomeParsingStateMap = map[string]SomeState{
 	"First":  First,
-	"Fourth": Fourth,
 	"Second": Second,
 	"Third":  Third,
+	"Fourth": Fourth,
 }
 
 func (s SomeState) String() string {
@@ -38,7 +39,6 @@ func (s SomeState) String() string {
 // SomeBehaviour definition
 type SomeBehaviour interface {
 	SomeFirstState
-
 	SomeSecondState
 	SomeThirdState
 }
@@ -48,9 +48,9 @@ type Some struct {
 	state SomeState
 }
 
-// NewSome creates machine with specified initial state
-func NewSome(state SomeState) *Some {
-	return &Some{state: state}
+// NewSome creates machine in its initial state First
+func NewSome() *Some {
+	return &Some{state: First}
 }
 
 // NewSomeFromString can be used to deserialize  machine state
@@ -72,26 +72,85 @@ func (m *Some) Operate(operator SomeBehaviour) {
 	switch m.state {
 	case First:
 		m.handleFirstEvent(operator.OperateFirst())
-	case Fourth:
-		return
 	case Second:
 		m.handleSecondEvent(operator.OperateSecond())
 	case Third:
 		m.handleThirdEvent(operator.OperateThird())
+	case Fourth:
+		return
 	}
 }
 
+// SomeEventNotAllowedError reports event fired in state of Some that doesn't declare it
+type SomeEventNotAllowedError struct {
+	State SomeState
+	Event SomeEvent
+}
+
+func (e *SomeEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Some event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Some.
+// If current state doesn't declare event, machine remains in it and *SomeEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Some) Fire(ev SomeEvent) error {
+	switch ev {
+	case SomeEventAa:
+		switch m.state {
+		case First:
+			m.handleFirstEvent(FirstAa)
+			return nil
+		}
+		return &SomeEventNotAllowedError{State: m.state, Event: ev}
+	case SomeEventBb:
+		switch m.state {
+		case Second:
+			m.handleSecondEvent(SecondBb)
+			return nil
+		}
+		return &SomeEventNotAllowedError{State: m.state, Event: ev}
+	case SomeEventCc:
+		switch m.state {
+		case Second:
+			m.handleSecondEvent(SecondCc)
+			return nil
+		}
+		return &SomeEventNotAllowedError{State: m.state, Event: ev}
+	case SomeEventZz:
+		switch m.state {
+		case Second:
+			m.handleSecondEvent(SecondZz)
+			return nil
+		case Third:
+			m.handleThirdEvent(ThirdZz)
+			return nil
+		}
+		return &SomeEventNotAllowedError{State: m.state, Event: ev}
+	case SomeEventDd:
+		switch m.state {
+		case Third:
+			m.handleThirdEvent(ThirdDd)
+			return nil
+		}
+		return &SomeEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &SomeEventNotAllowedError{State: m.state, Event: ev}
+}
+
 // Visualize states and events for Some in Graphviz format
 func (m *Some) Visualize() string {
 	return `// Definition for Some in Graphviz format 
 digraph Some {
+	__initial [shape=point];
+	__initial -> First;
 	First -> Second [label=Aa];
-	Fourth [shape=Msquare];
 	Second -> Third [label=Bb];
 	Second -> First [label=Cc];
 	Second -> Fourth [label=Zz];
 	Third -> First [label=Dd];
 	Third -> Fourth [label=Zz];
+	Fourth [shape=Msquare];
 }
 `
 }
@@ -130,6 +189,32 @@ func (m *Some) handleThirdEvent(event SomeThirdEvent) {
 
 //--- Here we will define all events ---
 
+//=== SomeEvent definition ===
+
+// SomeEvent is event of any state of Some that can be fired
+type SomeEvent int
+
+const (
+	_ SomeEvent = iota
+	SomeEventAa
+	SomeEventBb
+	SomeEventCc
+	SomeEventZz
+	SomeEventDd
+)
+
+var _SomeEventMap = map[SomeEvent]string{
+	SomeEventAa: "Aa",
+	SomeEventBb: "Bb",
+	SomeEventCc: "Cc",
+	SomeEventZz: "Zz",
+	SomeEventDd: "Dd",
+}
+
+func (m SomeEvent) String() string {
+	return _SomeEventMap[m]
+}
+
 //=== SomeFirstEvent definition ===
 
 // SomeFirstEvent definition
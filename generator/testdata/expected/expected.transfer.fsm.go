@@ -0,0 +1,389 @@
+package testdata
+
+import (
+	"fmt"
+	"time"
+)
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint TransferDeclaration 4995e77464aa8523
+
+//+++ General machine definition +++
+
+// TransferState type definition
+type TransferState int
+
+const (
+	_            TransferState = iota
+	Refused                    // Refused state
+	Requested                  // Requested state
+	Settled                    // Settled state
+	Transferring               // Transferring state
+)
+
+var _TransferStateMap = map[TransferState]string{
+	Refused:      "Refused",
+	Requested:    "Requested",
+	Settled:      "Settled",
+	Transferring: "Transferring",
+}
+
+var _TransferParsingStateMap = map[string]TransferState{
+	"Refused":      Refused,
+	"Requested":    Requested,
+	"Settled":      Settled,
+	"Transferring": Transferring,
+}
+
+func (s TransferState) String() string {
+	return _TransferStateMap[s]
+}
+
+// TransferBehaviour definition
+type TransferBehaviour interface {
+	TransferRefusedState
+	TransferRefusedListener
+	TransferRequestedState
+	TransferRequestedListener
+
+	TransferSettledListener
+	TransferTransferringState
+	TransferTransferringListener
+}
+
+// Transfer machine type
+type Transfer struct {
+	state TransferState
+}
+
+// NewTransfer creates machine in its initial state Requested
+func NewTransfer() *Transfer {
+	return &Transfer{state: Requested}
+}
+
+// NewTransferFromString can be used to deserialize  machine state
+func NewTransferFromString(stateStr string) (*Transfer, error) {
+	state, ok := _TransferParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Transfer: %s", stateStr)
+	}
+	return &Transfer{state: state}, nil
+}
+
+// Current returns current state of Transfer
+func (m *Transfer) Current() TransferState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Transfer
+func (m *Transfer) Operate(operator TransferBehaviour) {
+	switch m.state {
+	case Refused:
+		m.handleRefusedEvent(operator, operator.OperateRefused())
+	case Requested:
+		m.handleRequestedEvent(operator, operator.OperateRequested())
+	case Settled:
+		return
+	case Transferring:
+		m.handleTransferringEvent(operator, operator.OperateTransferring())
+	}
+}
+
+// TransferEventNotAllowedError reports event fired in state of Transfer that doesn't declare it
+type TransferEventNotAllowedError struct {
+	State TransferState
+	Event TransferEvent
+}
+
+func (e *TransferEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Transfer event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Transfer.
+// If current state doesn't declare event, machine remains in it and *TransferEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Transfer) Fire(operator TransferBehaviour, ev TransferEvent) error {
+	switch ev.id {
+	case _TransferEventAppeal:
+		switch m.state {
+		case Refused:
+			m.handleRefusedEvent(operator, RefusedAppeal)
+			return nil
+		}
+		return &TransferEventNotAllowedError{State: m.state, Event: ev}
+	case _TransferEventApprove:
+		switch m.state {
+		case Requested:
+			m.handleRequestedEvent(operator, RequestedApprove(ev.approvePayload))
+			return nil
+		}
+		return &TransferEventNotAllowedError{State: m.state, Event: ev}
+	case _TransferEventBounce:
+		switch m.state {
+		case Transferring:
+			m.handleTransferringEvent(operator, TransferringBounce(ev.bouncePayload))
+			return nil
+		}
+		return &TransferEventNotAllowedError{State: m.state, Event: ev}
+	case _TransferEventReject:
+		switch m.state {
+		case Requested:
+			m.handleRequestedEvent(operator, RequestedReject(ev.rejectPayload))
+			return nil
+		}
+		return &TransferEventNotAllowedError{State: m.state, Event: ev}
+	case _TransferEventSettle:
+		switch m.state {
+		case Transferring:
+			m.handleTransferringEvent(operator, TransferringSettle(ev.settlePayload))
+			return nil
+		}
+		return &TransferEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &TransferEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Transfer in Graphviz format
+func (m *Transfer) Visualize() string {
+	return `// Definition for Transfer in Graphviz format 
+digraph Transfer {
+	__initial [shape=point];
+	__initial -> Requested;
+	Refused -> Requested [label=Appeal];
+	Requested -> Transferring [label=Approve];
+	Requested -> Refused [label=Reject];
+	Settled [shape=Msquare];
+	Transferring -> Requested [label=Bounce];
+	Transferring -> Settled [label=Settle];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Transfer) handleRefusedEvent(operator TransferBehaviour, event TransferRefusedEvent) {
+	switch event {
+	case RefusedAppeal:
+		m.state = Requested
+	case RefusedNoop:
+	}
+}
+
+func (m *Transfer) handleRequestedEvent(operator TransferBehaviour, event TransferRequestedEvent) {
+	switch event.id {
+	case _RequestedApprove:
+		m.state = Transferring
+		operator.OnTransferringApprove(event.approvePayload)
+	case _RequestedReject:
+		m.state = Refused
+		operator.OnRefusedReject(event.rejectPayload)
+	case _RequestedNoop:
+	}
+}
+
+func (m *Transfer) handleTransferringEvent(operator TransferBehaviour, event TransferTransferringEvent) {
+	switch event.id {
+	case _TransferringBounce:
+		m.state = Requested
+		operator.OnRequestedBounce(event.bouncePayload)
+	case _TransferringSettle:
+		m.state = Settled
+		operator.OnSettledSettle(event.settlePayload)
+	case _TransferringNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== TransferEvent definition ===
+
+// TransferEvent is event of any state of Transfer that can be fired, some of events carry payload
+type TransferEvent struct {
+	id             int
+	approvePayload *Approval
+	bouncePayload  error
+	rejectPayload  error
+	settlePayload  time.Duration
+}
+
+const (
+	_ = iota
+	_TransferEventAppeal
+	_TransferEventApprove
+	_TransferEventBounce
+	_TransferEventReject
+	_TransferEventSettle
+)
+
+var (
+	TransferEventAppeal = TransferEvent{id: _TransferEventAppeal}
+)
+
+// TransferEventApprove creates event Approve that carries payload
+func TransferEventApprove(payload *Approval) TransferEvent {
+	return TransferEvent{id: _TransferEventApprove, approvePayload: payload}
+}
+
+// TransferEventBounce creates event Bounce that carries payload
+func TransferEventBounce(payload error) TransferEvent {
+	return TransferEvent{id: _TransferEventBounce, bouncePayload: payload}
+}
+
+// TransferEventReject creates event Reject that carries payload
+func TransferEventReject(payload error) TransferEvent {
+	return TransferEvent{id: _TransferEventReject, rejectPayload: payload}
+}
+
+// TransferEventSettle creates event Settle that carries payload
+func TransferEventSettle(payload time.Duration) TransferEvent {
+	return TransferEvent{id: _TransferEventSettle, settlePayload: payload}
+}
+
+var _TransferEventMap = map[int]string{
+	_TransferEventAppeal:  "Appeal",
+	_TransferEventApprove: "Approve",
+	_TransferEventBounce:  "Bounce",
+	_TransferEventReject:  "Reject",
+	_TransferEventSettle:  "Settle",
+}
+
+func (m TransferEvent) String() string {
+	return _TransferEventMap[m.id]
+}
+
+//=== TransferRefusedEvent definition ===
+
+// TransferRefusedEvent definition
+type TransferRefusedEvent int
+
+const (
+	_             TransferRefusedEvent = iota
+	RefusedAppeal                      // RefusedAppeal -> Requested
+	RefusedNoop                        // remain in Refused
+)
+
+var _TransferRefusedEventMap = map[TransferRefusedEvent]string{
+	RefusedAppeal: "RefusedAppeal",
+	RefusedNoop:   "RefusedNoop",
+}
+
+func (m TransferRefusedEvent) String() string {
+	return _TransferRefusedEventMap[m]
+}
+
+// TransferRefusedState behaviour
+type TransferRefusedState interface {
+	OperateRefused() TransferRefusedEvent
+}
+
+//=== TransferRequestedEvent definition ===
+
+// TransferRequestedEvent definition, some of events carry payload
+type TransferRequestedEvent struct {
+	id             int
+	approvePayload *Approval
+	rejectPayload  error
+}
+
+const (
+	_ = iota
+	_RequestedApprove
+	_RequestedReject
+	_RequestedNoop
+)
+
+var (
+	RequestedNoop = TransferRequestedEvent{id: _RequestedNoop} // remain in Requested
+)
+
+// RequestedApprove creates event RequestedApprove -> Transferring that carries payload
+func RequestedApprove(payload *Approval) TransferRequestedEvent {
+	return TransferRequestedEvent{id: _RequestedApprove, approvePayload: payload}
+}
+
+// RequestedReject creates event RequestedReject -> Refused that carries payload
+func RequestedReject(payload error) TransferRequestedEvent {
+	return TransferRequestedEvent{id: _RequestedReject, rejectPayload: payload}
+}
+
+var _TransferRequestedEventMap = map[int]string{
+	_RequestedApprove: "RequestedApprove",
+	_RequestedReject:  "RequestedReject",
+	_RequestedNoop:    "RequestedNoop",
+}
+
+func (m TransferRequestedEvent) String() string {
+	return _TransferRequestedEventMap[m.id]
+}
+
+// TransferRequestedState behaviour
+type TransferRequestedState interface {
+	OperateRequested() TransferRequestedEvent
+}
+
+//=== TransferTransferringEvent definition ===
+
+// TransferTransferringEvent definition, some of events carry payload
+type TransferTransferringEvent struct {
+	id            int
+	bouncePayload error
+	settlePayload time.Duration
+}
+
+const (
+	_ = iota
+	_TransferringBounce
+	_TransferringSettle
+	_TransferringNoop
+)
+
+var (
+	TransferringNoop = TransferTransferringEvent{id: _TransferringNoop} // remain in Transferring
+)
+
+// TransferringBounce creates event TransferringBounce -> Requested that carries payload
+func TransferringBounce(payload error) TransferTransferringEvent {
+	return TransferTransferringEvent{id: _TransferringBounce, bouncePayload: payload}
+}
+
+// TransferringSettle creates event TransferringSettle -> Settled that carries payload
+func TransferringSettle(payload time.Duration) TransferTransferringEvent {
+	return TransferTransferringEvent{id: _TransferringSettle, settlePayload: payload}
+}
+
+var _TransferTransferringEventMap = map[int]string{
+	_TransferringBounce: "TransferringBounce",
+	_TransferringSettle: "TransferringSettle",
+	_TransferringNoop:   "TransferringNoop",
+}
+
+func (m TransferTransferringEvent) String() string {
+	return _TransferTransferringEventMap[m.id]
+}
+
+// TransferTransferringState behaviour
+type TransferTransferringState interface {
+	OperateTransferring() TransferTransferringEvent
+}
+
+//--- Here we will define all payload listeners ---
+
+// TransferRefusedListener receives payloads of events that lead to Refused
+type TransferRefusedListener interface {
+	OnRefusedReject(payload error)
+}
+
+// TransferRequestedListener receives payloads of events that lead to Requested
+type TransferRequestedListener interface {
+	OnRequestedBounce(payload error)
+}
+
+// TransferSettledListener receives payloads of events that lead to Settled
+type TransferSettledListener interface {
+	OnSettledSettle(payload time.Duration)
+}
+
+// TransferTransferringListener receives payloads of events that lead to Transferring
+type TransferTransferringListener interface {
+	OnTransferringApprove(payload *Approval)
+}
@@ -0,0 +1,318 @@
+package testdata
+
+import (
+	"fmt"
+	"time"
+)
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint UploadDeclaration 5be64a67f68d20c5
+
+//+++ General machine definition +++
+
+// UploadState type definition
+type UploadState int
+
+const (
+	_                UploadState = iota
+	Selected                     // Selected state
+	UploadRetry                  // UploadRetry composite state
+	UploadAttempting             // UploadAttempting state
+	UploadBackoff                // UploadBackoff state
+	Uploaded                     // Uploaded state
+)
+
+var _UploadStateMap = map[UploadState]string{
+	Selected:         "Selected",
+	UploadRetry:      "UploadRetry",
+	UploadAttempting: "UploadAttempting",
+	UploadBackoff:    "UploadBackoff",
+	Uploaded:         "Uploaded",
+}
+
+var _UploadParsingStateMap = map[string]UploadState{
+	"Selected":         Selected,
+	"UploadAttempting": UploadAttempting,
+	"UploadBackoff":    UploadBackoff,
+	"Uploaded":         Uploaded,
+}
+
+func (s UploadState) String() string {
+	return _UploadStateMap[s]
+}
+
+var _UploadParentStateMap = map[UploadState]UploadState{
+	UploadAttempting: UploadRetry,
+	UploadBackoff:    UploadRetry,
+}
+
+// Parent returns composite state that contains s
+func (s UploadState) Parent() UploadState {
+	return _UploadParentStateMap[s]
+}
+
+// In reports whether s is the same as state or nested inside of it
+func (s UploadState) In(state UploadState) bool {
+	for ; s != 0; s = s.Parent() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadBehaviour definition
+type UploadBehaviour interface {
+	UploadSelectedState
+	RetryAttemptingState
+	RetryBackoffState
+}
+
+// Upload machine type
+type Upload struct {
+	state                     UploadState
+	clock                     UploadClock
+	uploadAttemptingEnteredAt time.Time
+	uploadBackoffEnteredAt    time.Time
+}
+
+// NewUpload creates machine in its initial state Selected
+func NewUpload() *Upload {
+	return &Upload{state: Selected}
+}
+
+// NewUploadFromString can be used to deserialize  machine state
+func NewUploadFromString(stateStr string) (*Upload, error) {
+	state, ok := _UploadParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Upload: %s", stateStr)
+	}
+	return &Upload{state: state}, nil
+}
+
+// Current returns current state of Upload
+func (m *Upload) Current() UploadState {
+	return m.state
+}
+
+// UploadClock provides current time for timed transitions of Upload
+type UploadClock interface {
+	Now() time.Time
+}
+
+// WithClock replaces clock used by timed transitions of Upload, so tests can control time.
+// Timers of the current states restart from the next Operate call.
+func (m *Upload) WithClock(clock UploadClock) *Upload {
+	m.clock = clock
+	m.uploadAttemptingEnteredAt = time.Time{}
+	m.uploadBackoffEnteredAt = time.Time{}
+	return m
+}
+
+// now returns current time of Upload clock
+func (m *Upload) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
+
+// Operate executes behaviour for the current state Upload
+func (m *Upload) Operate(operator UploadBehaviour) {
+	switch m.state {
+	case Selected:
+		m.handleSelectedEvent(operator.OperateSelected())
+	case UploadAttempting:
+		m.handleUploadAttemptingEvent(m.operateUploadAttempting(operator))
+	case UploadBackoff:
+		m.handleUploadBackoffEvent(m.operateUploadBackoff(operator))
+	case Uploaded:
+		return
+	}
+}
+
+// UploadEventNotAllowedError reports event fired in state of Upload that doesn't declare it
+type UploadEventNotAllowedError struct {
+	State UploadState
+	Event UploadEvent
+}
+
+func (e *UploadEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Upload event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Upload.
+// If current state doesn't declare event, machine remains in it and *UploadEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Upload) Fire(ev UploadEvent) error {
+	switch ev {
+	case UploadEventSend:
+		switch m.state {
+		case Selected:
+			m.handleSelectedEvent(SelectedSend)
+			return nil
+		}
+		return &UploadEventNotAllowedError{State: m.state, Event: ev}
+	case UploadEventFailed:
+		switch m.state {
+		case UploadAttempting:
+			m.handleUploadAttemptingEvent(AttemptingFailed)
+			return nil
+		}
+		return &UploadEventNotAllowedError{State: m.state, Event: ev}
+	case UploadEventSucceeded:
+		switch m.state {
+		case UploadAttempting:
+			m.handleUploadAttemptingEvent(AttemptingSucceeded)
+			return nil
+		}
+		return &UploadEventNotAllowedError{State: m.state, Event: ev}
+	case UploadEventAbort:
+		switch m.state {
+		case UploadBackoff:
+			m.handleUploadBackoffEvent(BackoffAbort)
+			return nil
+		}
+		return &UploadEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &UploadEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Upload in Graphviz format
+func (m *Upload) Visualize() string {
+	return `// Definition for Upload in Graphviz format 
+digraph Upload {
+	compound=true;
+	__initial [shape=point];
+	__initial -> Selected;
+	Selected;
+	subgraph cluster_UploadRetry {
+		label=UploadRetry;
+		UploadAttempting;
+		UploadBackoff;
+	}
+	Uploaded [shape=Msquare];
+	Selected -> UploadAttempting [label=Send, lhead=cluster_UploadRetry];
+	UploadAttempting -> UploadBackoff [label=Failed];
+	UploadAttempting -> Uploaded [label=Succeeded];
+	UploadAttempting -> Selected [label=After30s];
+	UploadBackoff -> UploadAttempting [label=After250ms];
+	UploadBackoff -> Selected [label=Abort];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Upload) handleSelectedEvent(event UploadSelectedEvent) {
+	switch event {
+	case SelectedSend:
+		m.state = UploadAttempting
+		m.uploadAttemptingEnteredAt = m.now()
+	case SelectedNoop:
+	}
+}
+
+func (m *Upload) handleUploadAttemptingEvent(event RetryAttemptingEvent) {
+	switch event {
+	case AttemptingFailed:
+		m.state = UploadBackoff
+		m.uploadAttemptingEnteredAt = time.Time{}
+		m.uploadBackoffEnteredAt = m.now()
+	case AttemptingSucceeded:
+		m.state = Uploaded
+		m.uploadAttemptingEnteredAt = time.Time{}
+	case AttemptingAfter30s:
+		m.state = Selected
+		m.uploadAttemptingEnteredAt = time.Time{}
+	case AttemptingNoop:
+	}
+}
+
+// operateUploadAttempting takes the expired timed transition or executes behaviour of UploadAttempting
+func (m *Upload) operateUploadAttempting(operator UploadBehaviour) RetryAttemptingEvent {
+	now := m.now()
+	if m.uploadAttemptingEnteredAt.IsZero() {
+		m.uploadAttemptingEnteredAt = now
+	}
+	if now.Sub(m.uploadAttemptingEnteredAt) >= 30*time.Second {
+		return AttemptingAfter30s
+	}
+	return operator.OperateAttempting()
+}
+
+func (m *Upload) handleUploadBackoffEvent(event RetryBackoffEvent) {
+	switch event {
+	case BackoffAfter250ms:
+		m.state = UploadAttempting
+		m.uploadBackoffEnteredAt = time.Time{}
+		m.uploadAttemptingEnteredAt = m.now()
+	case BackoffAbort:
+		m.state = Selected
+		m.uploadBackoffEnteredAt = time.Time{}
+	case BackoffNoop:
+	}
+}
+
+// operateUploadBackoff takes the expired timed transition or executes behaviour of UploadBackoff
+func (m *Upload) operateUploadBackoff(operator UploadBehaviour) RetryBackoffEvent {
+	now := m.now()
+	if m.uploadBackoffEnteredAt.IsZero() {
+		m.uploadBackoffEnteredAt = now
+	}
+	if now.Sub(m.uploadBackoffEnteredAt) >= 250*time.Millisecond {
+		return BackoffAfter250ms
+	}
+	return operator.OperateBackoff()
+}
+
+//--- Here we will define all events ---
+
+//=== UploadEvent definition ===
+
+// UploadEvent is event of any state of Upload that can be fired
+type UploadEvent int
+
+const (
+	_ UploadEvent = iota
+	UploadEventSend
+	UploadEventFailed
+	UploadEventSucceeded
+	UploadEventAbort
+)
+
+var _UploadEventMap = map[UploadEvent]string{
+	UploadEventSend:      "Send",
+	UploadEventFailed:    "Failed",
+	UploadEventSucceeded: "Succeeded",
+	UploadEventAbort:     "Abort",
+}
+
+func (m UploadEvent) String() string {
+	return _UploadEventMap[m]
+}
+
+//=== UploadSelectedEvent definition ===
+
+// UploadSelectedEvent definition
+type UploadSelectedEvent int
+
+const (
+	_            UploadSelectedEvent = iota
+	SelectedSend                     // SelectedSend -> UploadRetry
+	SelectedNoop                     // remain in Selected
+)
+
+var _UploadSelectedEventMap = map[UploadSelectedEvent]string{
+	SelectedSend: "SelectedSend",
+	SelectedNoop: "SelectedNoop",
+}
+
+func (m UploadSelectedEvent) String() string {
+	return _UploadSelectedEventMap[m]
+}
+
+// UploadSelectedState behaviour
+type UploadSelectedState interface {
+	OperateSelected() UploadSelectedEvent
+}
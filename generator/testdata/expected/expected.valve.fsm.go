@@ -0,0 +1,206 @@
+package testdata
+
+import "fmt"
+
+// Generated by go-fsm-generator. DO NOT EDIT.
+//fsm:fingerprint ValveStates efb4a1d8e61b66a9
+
+//+++ General machine definition +++
+
+// ValveState type definition
+type ValveState int
+
+const (
+	_       ValveState = iota
+	Sealed             // Sealed state
+	Venting            // Venting state
+)
+
+var _ValveStateMap = map[ValveState]string{
+	Sealed:  "Sealed",
+	Venting: "Venting",
+}
+
+var _ValveParsingStateMap = map[string]ValveState{
+	"Sealed":  Sealed,
+	"Venting": Venting,
+}
+
+func (s ValveState) String() string {
+	return _ValveStateMap[s]
+}
+
+// ValveBehaviour definition
+type ValveBehaviour interface {
+	ValveSealedState
+	ValveVentingState
+}
+
+// Valve machine type
+type Valve struct {
+	state ValveState
+}
+
+// NewValve creates machine in its initial state Sealed
+func NewValve() *Valve {
+	return &Valve{state: Sealed}
+}
+
+// NewValveFromString can be used to deserialize  machine state
+func NewValveFromString(stateStr string) (*Valve, error) {
+	state, ok := _ValveParsingStateMap[stateStr]
+	if !ok {
+		return nil, fmt.Errorf("state unknown for Valve: %s", stateStr)
+	}
+	return &Valve{state: state}, nil
+}
+
+// Current returns current state of Valve
+func (m *Valve) Current() ValveState {
+	return m.state
+}
+
+// Operate executes behaviour for the current state Valve
+func (m *Valve) Operate(operator ValveBehaviour) {
+	switch m.state {
+	case Sealed:
+		m.handleSealedEvent(operator.OperateSealed())
+	case Venting:
+		m.handleVentingEvent(operator.OperateVenting())
+	}
+}
+
+// ValveEventNotAllowedError reports event fired in state of Valve that doesn't declare it
+type ValveEventNotAllowedError struct {
+	State ValveState
+	Event ValveEvent
+}
+
+func (e *ValveEventNotAllowedError) Error() string {
+	return fmt.Sprintf("Valve event %s not allowed in state %s", e.Event, e.State)
+}
+
+// Fire executes transition declared for event in the current state Valve.
+// If current state doesn't declare event, machine remains in it and *ValveEventNotAllowedError is returned.
+// Fire doesn't execute state behaviour.
+func (m *Valve) Fire(ev ValveEvent) error {
+	switch ev {
+	case ValveEventVent:
+		switch m.state {
+		case Sealed:
+			m.handleSealedEvent(SealedVent)
+			return nil
+		}
+		return &ValveEventNotAllowedError{State: m.state, Event: ev}
+	case ValveEventSeal:
+		switch m.state {
+		case Venting:
+			m.handleVentingEvent(VentingSeal)
+			return nil
+		}
+		return &ValveEventNotAllowedError{State: m.state, Event: ev}
+	}
+	return &ValveEventNotAllowedError{State: m.state, Event: ev}
+}
+
+// Visualize states and events for Valve in Graphviz format
+func (m *Valve) Visualize() string {
+	return `// Definition for Valve in Graphviz format 
+digraph Valve {
+	__initial [shape=point];
+	__initial -> Sealed;
+	Sealed -> Venting [label=Vent];
+	Venting -> Sealed [label=Seal];
+}
+`
+}
+
+// Handlers for state transitions
+
+func (m *Valve) handleSealedEvent(event ValveSealedEvent) {
+	switch event {
+	case SealedVent:
+		m.state = Venting
+	case SealedNoop:
+	}
+}
+
+func (m *Valve) handleVentingEvent(event ValveVentingEvent) {
+	switch event {
+	case VentingSeal:
+		m.state = Sealed
+	case VentingNoop:
+	}
+}
+
+//--- Here we will define all events ---
+
+//=== ValveEvent definition ===
+
+// ValveEvent is event of any state of Valve that can be fired
+type ValveEvent int
+
+const (
+	_ ValveEvent = iota
+	ValveEventVent
+	ValveEventSeal
+)
+
+var _ValveEventMap = map[ValveEvent]string{
+	ValveEventVent: "Vent",
+	ValveEventSeal: "Seal",
+}
+
+func (m ValveEvent) String() string {
+	return _ValveEventMap[m]
+}
+
+//=== ValveSealedEvent definition ===
+
+// ValveSealedEvent definition
+type ValveSealedEvent int
+
+const (
+	_          ValveSealedEvent = iota
+	SealedVent                  // SealedVent -> Venting
+	SealedNoop                  // remain in Sealed
+)
+
+var _ValveSealedEventMap = map[ValveSealedEvent]string{
+	SealedVent: "SealedVent",
+	SealedNoop: "SealedNoop",
+}
+
+func (m ValveSealedEvent) String() string {
+	return _ValveSealedEventMap[m]
+}
+
+// ValveSealedState behaviour
+type ValveSealedState interface {
+	OperateSealed() ValveSealedEvent
+}
+
+//=== ValveVentingEvent definition ===
+
+// ValveVentingEvent definition
+type ValveVentingEvent int
+
+const (
+	_           ValveVentingEvent = iota
+	VentingSeal                   // VentingSeal -> Sealed
+	VentingNoop                   // remain in Venting
+)
+
+var _ValveVentingEventMap = map[ValveVentingEvent]string{
+	VentingSeal: "VentingSeal",
+	VentingNoop: "VentingNoop",
+}
+
+func (m ValveVentingEvent) String() string {
+	return _ValveVentingEventMap[m]
+}
+
+// ValveVentingState behaviour
+type ValveVentingState interface {
+	OperateVenting() ValveVentingEvent
+}
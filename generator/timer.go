@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timerPrefix starts names of timed events like `After100ms`
+const timerPrefix = "After"
+
+// timer is the timed transition of the state
+type timer struct {
+	Event event
+	// Duration is Go expression of timer duration like `100 * time.Millisecond`
+	Duration string
+	// Field is the name of machine field that holds time when the state declaring timer was entered
+	Field string
+}
+
+// parseTimer returns delay of timed event or zero if event isn't timed
+func parseTimer(ev event) time.Duration {
+	if !strings.HasPrefix(string(ev), timerPrefix) {
+		return 0
+	}
+	d, err := time.ParseDuration(strings.TrimPrefix(string(ev), timerPrefix))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// collectTimers returns timers of leaf state ordered by duration, so the shortest one is checked first.
+// Timer counts time since the state that declares it was entered, so timer of composite state
+// keeps running while machine moves between its sub-states.
+func collectTimers(definition machineDefinition, leaf state) []timer {
+	type timed struct {
+		after time.Duration
+		timer timer
+	}
+	var result []timed
+	seen := map[event]bool{}
+	for s := leaf; s != ""; s = definition.States[s].Parent {
+		for _, ev := range sortedEvents(definition.States[s].Events) {
+			tr := definition.States[s].Events[ev]
+			if seen[ev] {
+				continue
+			}
+			seen[ev] = true
+			if tr.After > 0 {
+				result = append(result, timed{after: tr.After, timer: timer{Event: ev, Duration: durationExpression(tr.After), Field: enteredAtField(s)}})
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].after < result[j].after
+	})
+	var timers []timer
+	for _, t := range result {
+		timers = append(timers, t.timer)
+	}
+	return timers
+}
+
+// hasTimers reports whether state declares timed events, so machine keeps time it was entered at
+func hasTimers(st stateDefinition) bool {
+	for _, tr := range st.Events {
+		if tr.After > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// enteredAtField returns name of machine field that holds time when state with timers was entered
+func enteredAtField(s state) string {
+	return strings.ToLower(string(s[:1])) + string(s[1:]) + "EnteredAt"
+}
+
+// TimerFields returns fields that hold entry time of states with timers, in generation order of states
+func (m machineDefinition) TimerFields() []string {
+	var result []string
+	for _, st := range m.OrderedStates() {
+		if hasTimers(st) {
+			result = append(result, enteredAtField(st.Name))
+		}
+	}
+	return result
+}
+
+// TimerFields returns distinct fields with entry time timers of the state count from
+func (s stateDefinition) TimerFields() []string {
+	var result []string
+	for _, t := range s.Timers {
+		if !contains(result, t.Field) {
+			result = append(result, t.Field)
+		}
+	}
+	return result
+}
+
+// durationExpression formats duration in the largest unit it can be expressed with
+func durationExpression(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("%d * time.Nanosecond", d)
+}
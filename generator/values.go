@@ -0,0 +1,358 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// valueOption pins numeric value of state constant like `fsm:"value=3"`
+const valueOption = "value="
+
+// eventValueOption pins numeric value of event constant of the state like `fsm:"value.Pay=2"`
+const eventValueOption = "value."
+
+const lockFileSuffix = ".fsm.lock"
+
+// noopEvent is the event every non-terminal state has to remain in it
+const noopEvent = "Noop"
+
+const (
+	stateEnum        = "state"
+	eventEnum        = "event"
+	machineEventEnum = "machine-event"
+	reservedMark     = "reserved"
+)
+
+// enum identifies group of constants numbered together.
+// Owner is the state that declares events of event enum.
+type enum struct {
+	Kind  string
+	Owner state
+}
+
+// lockFile records numeric values once assigned to states and events of machine.
+// Values of names removed from declaration stay in lock file, so they are never reused.
+type lockFile map[enum]map[string]int
+
+func lockFilePath(definition machineDefinition) string {
+	return filepath.Join(definition.DirName, strings.ToLower(definition.MachineName+lockFileSuffix))
+}
+
+// readLockFile reads lock file of machine. Lock file is created only on demand,
+// but once it exists, generator always keeps values recorded in it.
+func readLockFile(path string, create bool) (lockFile, bool) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lockFile{}, create
+	}
+	if err != nil {
+		failf(token.Position{Filename: path}, "can't read lock file: %v", err)
+	}
+	lock, err := parseLockFile(content)
+	if err != nil {
+		failf(token.Position{Filename: path}, "unsupported lock file format: %v", err)
+	}
+	return lock, true
+}
+
+func parseLockFile(content []byte) (lockFile, error) {
+	lock := lockFile{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 4 && fields[3] == reservedMark {
+			fields = fields[:3]
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected `<kind> <name> <value>`, got `%s`", line, text)
+		}
+		e := enum{Kind: fields[0]}
+		name := fields[1]
+		switch e.Kind {
+		case stateEnum, machineEventEnum:
+		case eventEnum:
+			dot := strings.Index(name, ".")
+			if dot < 0 {
+				return nil, fmt.Errorf("line %d: event `%s` should be qualified with its state like `State.Event`", line, name)
+			}
+			e.Owner, name = state(name[:dot]), name[dot+1:]
+		default:
+			return nil, fmt.Errorf("line %d: unknown kind `%s`", line, e.Kind)
+		}
+		value, err := strconv.Atoi(fields[2])
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("line %d: value `%s` of `%s` should be positive integer", line, fields[2], name)
+		}
+		if lock[e] == nil {
+			lock[e] = map[string]int{}
+		}
+		if _, ok := lock[e][name]; ok {
+			return nil, fmt.Errorf("line %d: `%s` is recorded twice", line, name)
+		}
+		for other, otherValue := range lock[e] {
+			if otherValue == value {
+				return nil, fmt.Errorf("line %d: value %d of `%s` is already recorded for `%s`", line, value, name, other)
+			}
+		}
+		lock[e][name] = value
+	}
+	return lock, scanner.Err()
+}
+
+func writeLockFile(path string, definition machineDefinition, lock lockFile) {
+	builder := &strings.Builder{}
+	builder.WriteString("# Numeric values of ")
+	builder.WriteString(definition.MachineName)
+	builder.WriteString(" states and events generated by go-fsm-generator.\n")
+	builder.WriteString("# Values are never reassigned, values of removed states and events stay reserved.\n")
+	enums := make([]enum, 0, len(lock))
+	for e := range lock {
+		enums = append(enums, e)
+	}
+	kindOrder := map[string]int{stateEnum: 0, eventEnum: 1, machineEventEnum: 2}
+	sort.Slice(enums, func(i, j int) bool {
+		if enums[i].Kind != enums[j].Kind {
+			return kindOrder[enums[i].Kind] < kindOrder[enums[j].Kind]
+		}
+		return enums[i].Owner < enums[j].Owner
+	})
+	for _, e := range enums {
+		values := lock[e]
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return values[names[i]] < values[names[j]]
+		})
+		for _, name := range names {
+			builder.WriteString(e.Kind)
+			builder.WriteString(" ")
+			if e.Owner != "" {
+				builder.WriteString(string(e.Owner))
+				builder.WriteString(".")
+			}
+			builder.WriteString(name)
+			builder.WriteString(" ")
+			builder.WriteString(strconv.Itoa(values[name]))
+			if !lockedNameExists(definition, e, name) {
+				builder.WriteString(" ")
+				builder.WriteString(reservedMark)
+			}
+			builder.WriteString("\n")
+		}
+	}
+	err := ioutil.WriteFile(path, []byte(builder.String()), 0664)
+	if err != nil {
+		log.Fatal("can't write lock file to disk. ", err)
+	}
+}
+
+func lockedNameExists(definition machineDefinition, e enum, name string) bool {
+	switch e.Kind {
+	case stateEnum:
+		_, ok := definition.States[state(name)]
+		return ok
+	case machineEventEnum:
+		for _, ev := range definition.Events {
+			if string(ev.Name) == name {
+				return true
+			}
+		}
+		return false
+	}
+	st, ok := definition.Leaves()[e.Owner]
+	if !ok || st.IsTerminal {
+		return false
+	}
+	_, ok = st.Transitions[event(name)]
+	return ok || name == noopEvent
+}
+
+// valueError reports pinned value that can't be assigned to Name
+type valueError struct {
+	Name    string
+	Message string
+}
+
+func (e valueError) Error() string {
+	return e.Message
+}
+
+// assignValues numbers constants of one enum. Values pinned in declaration and recorded in lock file are kept,
+// other names get values following the largest value ever used, so removed names leave gaps.
+// Lock is updated with assigned values.
+func assignValues(names []string, pinned map[string]int, locked map[string]int) (map[string]int, error) {
+	owners := map[int]string{}
+	for name, value := range locked {
+		owners[value] = name
+	}
+	values := map[string]int{}
+	for _, name := range names {
+		value, ok := pinned[name]
+		if !ok {
+			continue
+		}
+		if lockedValue, ok := locked[name]; ok && lockedValue != value {
+			return nil, valueError{name, fmt.Sprintf("`%s` is pinned to %d, but lock file records %d. Values can't be renumbered", name, value, lockedValue)}
+		}
+		if owner, ok := owners[value]; ok && owner != name {
+			if !contains(names, owner) {
+				return nil, valueError{name, fmt.Sprintf("value %d of `%s` is reserved for removed `%s`", value, name, owner)}
+			}
+			return nil, valueError{name, fmt.Sprintf("value %d of `%s` is already used by `%s`", value, name, owner)}
+		}
+		owners[value] = name
+		values[name] = value
+	}
+	next := 1
+	for value := range owners {
+		if value >= next {
+			next = value + 1
+		}
+	}
+	for _, name := range names {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		if value, ok := locked[name]; ok {
+			values[name] = value
+			continue
+		}
+		values[name] = next
+		next++
+	}
+	for name, value := range values {
+		locked[name] = value
+	}
+	return values, nil
+}
+
+// assignStateAndEventValues replaces implicit iota numbering of states and events with explicit values,
+// when some states or events have pinned values or machine has lock file
+func assignStateAndEventValues(fset *token.FileSet, definition *machineDefinition, lock lockFile, locked bool) {
+	pinned := map[string]int{}
+	pinnedEvents := false
+	var names []string
+	for _, name := range definition.StateOrder {
+		st := definition.States[name]
+		names = append(names, string(name))
+		if st.Value != 0 {
+			pinned[string(name)] = st.Value
+		}
+		pinnedEvents = pinnedEvents || len(st.EventValues) > 0
+	}
+	if len(pinned) == 0 && !pinnedEvents && !locked {
+		return
+	}
+	definition.HasValues = true
+	stateValues, err := assignValues(names, pinned, lockEnum(lock, enum{Kind: stateEnum}))
+	if err != nil {
+		st := definition.States[state(err.(valueError).Name)]
+		failf(fset.Position(st.Field.Pos()), "can't assign value of state: %v", err)
+	}
+	for name, value := range stateValues {
+		st := definition.States[state(name)]
+		st.Value = value
+		definition.States[state(name)] = st
+	}
+
+	for name, st := range definition.Leaves() {
+		if st.IsTerminal || st.Embedded != "" {
+			continue
+		}
+		var events []string
+		for _, ev := range st.TransitionOrder {
+			events = append(events, string(ev))
+		}
+		events = append(events, noopEvent)
+		values, err := assignValues(events, pinnedEventValues(*definition, name), lockEnum(lock, enum{Kind: eventEnum, Owner: name}))
+		if err != nil {
+			failf(fset.Position(st.Field.Pos()), "can't assign value of event: %v", err)
+		}
+		for ev, tr := range st.Transitions {
+			tr.Value = values[string(ev)]
+			st.Transitions[ev] = tr
+		}
+		st.NoopValue = values[noopEvent]
+		definition.States[name] = st
+	}
+
+	var events []string
+	for _, ev := range definition.Events {
+		events = append(events, string(ev.Name))
+	}
+	values, err := assignValues(events, nil, lockEnum(lock, enum{Kind: machineEventEnum}))
+	if err != nil {
+		failf(token.Position{}, "can't assign value of machine event: %v", err)
+	}
+	for i, ev := range definition.Events {
+		definition.Events[i].Value = values[string(ev.Name)]
+	}
+}
+
+// verifyEventValues checks that states pin values of events they declare or inherit, or of their Noop event
+func verifyEventValues(fset *token.FileSet, definition machineDefinition) {
+	for _, name := range sortedStates(definition.States) {
+		st := definition.States[name]
+		if len(st.EventValues) > 0 && st.IsTerminal && !st.IsComposite {
+			failf(fset.Position(st.Field.Tag.Pos()), "can't pin values of events on terminal state `%s`", name)
+		}
+		var events []string
+		for ev := range st.EventValues {
+			events = append(events, string(ev))
+		}
+		sort.Strings(events)
+		for _, ev := range events {
+			if ev != noopEvent && !hasEvent(definition, name, event(ev)) {
+				failf(fset.Position(st.Field.Tag.Pos()), "can't pin value of event `%s`, state `%s` doesn't have it", ev, name)
+			}
+		}
+	}
+}
+
+// hasEvent reports whether state declares event or inherits it from parents
+func hasEvent(definition machineDefinition, name state, ev event) bool {
+	if _, ok := definition.States[name].Transitions[ev]; ok {
+		return true
+	}
+	for s := name; s != ""; s = definition.States[s].Parent {
+		if _, ok := definition.States[s].Events[ev]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pinnedEventValues returns values of events pinned on leaf state and its parents, values pinned on inner state take precedence
+func pinnedEventValues(definition machineDefinition, leaf state) map[string]int {
+	result := map[string]int{}
+	for s := leaf; s != ""; s = definition.States[s].Parent {
+		for ev, value := range definition.States[s].EventValues {
+			if _, ok := result[string(ev)]; !ok {
+				result[string(ev)] = value
+			}
+		}
+	}
+	return result
+}
+
+func lockEnum(lock lockFile, e enum) map[string]int {
+	if lock[e] == nil {
+		lock[e] = map[string]int{}
+	}
+	return lock[e]
+}
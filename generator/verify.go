@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around changes in unified diff
+const diffContext = 3
+
+// VerifyGenerated regenerates machines in memory and compares them with generated files on disk.
+// It writes the reason and unified diff of every stale file to w and reports whether all files are up to date.
+func VerifyGenerated(dirName string, types []string, options Options, w io.Writer) bool {
+	fset, pkgs := parseDir(dirName)
+	upToDate := true
+	err := catchDeclarationError(func() {
+		forEachMachine(fset, pkgs, types, func(ns *namespace, machineName string, pkg *ast.Package, obj *ast.Object) {
+			definition := parseDefinition(options, machineName, dirName, pkg, fset, obj)
+			reportDiagnostics(definition, analyzeDefinition(fset, definition, options.Strict))
+			reportDiagnostics(definition, checkProperties(fset, definition))
+			lock, locked := readLockFile(lockFilePath(definition), options.Lock)
+			src := generateSource(options, ns, pkg, fset, &definition, lock, locked)
+
+			path := filepath.Join(definition.DirName, outputFileName(definition.MachineName))
+			content, err := ioutil.ReadFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				log.Fatal("can't read generated file. ", err)
+			}
+			if err == nil && bytes.Equal(content, src) {
+				return
+			}
+			upToDate = false
+			fmt.Fprintf(w, "%s: %s\n", path, staleReason(definition, content, err != nil))
+			io.WriteString(w, unifiedDiff(path, path+" (regenerated)", content, src))
+		})
+	})
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	return upToDate
+}
+
+// staleReason explains why generated file differs from regenerated one using its fingerprint
+func staleReason(definition machineDefinition, content []byte, missing bool) string {
+	if missing {
+		return "generated file is missing, run go generate"
+	}
+	declaration, fingerprint, ok := readFingerprint(content)
+	switch {
+	case !ok:
+		return "generated file has no fingerprint of declaration, run go generate"
+	case declaration != definition.Declaration:
+		return fmt.Sprintf("file was generated from `%s`, but machine is declared by `%s`, run go generate", declaration, definition.Declaration)
+	case fingerprint != definition.Fingerprint:
+		return fmt.Sprintf("declaration `%s` changed since file was generated, run go generate", declaration)
+	}
+	return fmt.Sprintf(
+		"declaration `%s` didn't change, but generated file differs, it was edited or generated with other options or version of generator",
+		declaration,
+	)
+}
+
+// diffLine is a line of unified diff. From and To are indexes of the line, or of the next line, in compared texts.
+type diffLine struct {
+	Kind byte
+	Text string
+	From int
+	To   int
+}
+
+// unifiedDiff returns difference between texts in unified format
+func unifiedDiff(fromName string, toName string, from []byte, to []byte) string {
+	a, b := splitLines(from), splitLines(to)
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				common[i][j] = common[i+1][j+1] + 1
+			case common[i+1][j] >= common[i][j+1]:
+				common[i][j] = common[i+1][j]
+			default:
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+	var lines []diffLine
+	var changes []int
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{Kind: ' ', Text: a[i], From: i, To: j})
+			i, j = i+1, j+1
+			continue
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, diffLine{Kind: '-', Text: a[i], From: i, To: j})
+			i++
+		default:
+			lines = append(lines, diffLine{Kind: '+', Text: b[j], From: i, To: j})
+			j++
+		}
+		changes = append(changes, len(lines)-1)
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "--- %s\n+++ %s\n", fromName, toName)
+	for first := 0; first < len(changes); {
+		last := first
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*diffContext {
+			last++
+		}
+		start, end := changes[first]-diffContext, changes[last]+diffContext+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		fromCount, toCount := 0, 0
+		for _, line := range lines[start:end] {
+			if line.Kind != '+' {
+				fromCount++
+			}
+			if line.Kind != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(
+			builder, "@@ -%s +%s @@\n",
+			hunkRange(lines[start].From, fromCount), hunkRange(lines[start].To, toCount),
+		)
+		for _, line := range lines[start:end] {
+			builder.WriteByte(line.Kind)
+			builder.WriteString(line.Text)
+			if !strings.HasSuffix(line.Text, "\n") {
+				builder.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		first = last + 1
+	}
+	return builder.String()
+}
+
+// hunkRange formats range of hunk that starts at line index, empty range refers to the line before it
+func hunkRange(index int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", index)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", index+1)
+	}
+	return fmt.Sprintf("%d,%d", index+1, count)
+}
+
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
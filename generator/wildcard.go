@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// wildcardState is the name of special field which events are added to every non-terminal state
+const wildcardState = "Any"
+
+func verifyWildcard(fset *token.FileSet, pkg *ast.Package, wildcard stateDefinition) {
+	if len(wildcard.Events) == 0 {
+		failf(fset.Position(wildcard.Field.Pos()), "`%s` should declare events for every non-terminal state", wildcardState)
+	}
+	if wildcard.IsInitial || wildcard.HasEntry || wildcard.HasExit || wildcard.IsRegion || wildcard.IsAuto || len(wildcard.Attributes) > 0 || wildcard.Value != 0 {
+		failf(fset.Position(wildcard.Field.Pos()), "`%s` can't have options or attributes", wildcardState)
+	}
+	if nestedDeclaration(pkg, wildcard.Field) != nil {
+		failf(fset.Position(wildcard.Field.Pos()), "`%s` can't have sub-states", wildcardState)
+	}
+}
+
+// expandWildcards adds events of `Any` field to every non-terminal leaf state declared next to it.
+// Events declared on the state itself or inherited from its parents take precedence.
+// States spliced from embedded machine get only events of `Any` fields of that machine.
+// Transitions have to be resolved before expansion, so terminal states are known.
+func expandWildcards(fset *token.FileSet, definition machineDefinition, wildcards map[state]stateDefinition) {
+	for _, scope := range sortedStates(wildcards) {
+		wildcard := wildcards[scope]
+		if scope == "" && len(definition.Regions) > 0 {
+			failf(fset.Position(wildcard.Field.Pos()), "`%s` should be declared inside of region, because machine has regions", wildcardState)
+		}
+		for _, ev := range sortedEvents(wildcard.Events) {
+			tr := wildcard.Events[ev]
+			dst, ok := definition.States[tr.Destination]
+			if !ok {
+				failf(
+					fset.Position(wildcard.Field.Pos()),
+					"You've defined (%v) -%v-> (%v). But there is no such destination state as `%v`",
+					wildcardState, ev, tr.Destination, tr.Destination,
+				)
+			}
+			if definition.isRegion(scope) && dst.Region != string(scope) {
+				failf(
+					fset.Position(wildcard.Field.Pos()),
+					"You've defined (%v) -%v-> (%v). But `%v` doesn't belong to region `%v`",
+					wildcardState, ev, tr.Destination, tr.Destination, scope,
+				)
+			}
+		}
+		for _, name := range definition.wildcardScope(scope) {
+			st := definition.States[name]
+			if st.IsTerminal || st.Embedded != wildcard.Embedded {
+				continue
+			}
+			if st.Events == nil {
+				st.Events, st.Destinations = map[event]transition{}, map[state][]event{}
+			}
+			for _, ev := range sortedEvents(wildcard.Events) {
+				if _, ok := st.Transitions[ev]; ok {
+					continue
+				}
+				tr := wildcard.Events[ev]
+				st.Events[ev] = tr
+				st.Destinations[tr.Destination] = append(st.Destinations[tr.Destination], ev)
+			}
+			definition.States[name] = st
+		}
+	}
+	if len(definition.Regions) > 0 {
+		verifyRegions(fset, definition)
+	}
+}
+
+// wildcardScope returns leaf states affected by `Any` field declared inside of scope
+func (m machineDefinition) wildcardScope(scope state) []state {
+	if m.isRegion(scope) {
+		var result []state
+		for _, name := range sortedStates(m.States) {
+			if m.States[name].Region == string(scope) && !m.States[name].IsComposite {
+				result = append(result, name)
+			}
+		}
+		return result
+	}
+	if scope != "" {
+		return m.leavesOf(scope)
+	}
+	return sortedStates(m.Leaves())
+}
+
+func (m machineDefinition) isRegion(name state) bool {
+	for _, r := range m.Regions {
+		if r.Name == string(name) {
+			return true
+		}
+	}
+	return false
+}
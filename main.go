@@ -1,26 +1,104 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"github.com/storozhukBM/go-fsm-generator/generator"
 )
 
+const (
+	// checkCommand verifies declarations without writing generated files
+	checkCommand = "check"
+	// verifyCommand fails if generated files differ from the ones generated from current declarations
+	verifyCommand = "verify"
+	// diffCommand classifies changes between two versions of declarations as additive or breaking
+	diffCommand = "diff"
+)
+
 func main() {
+	args := os.Args[1:]
+	command := ""
+	if len(args) > 0 && (args[0] == checkCommand || args[0] == verifyCommand || args[0] == diffCommand) {
+		command, args = args[0], args[1:]
+	}
 	verbose := flag.Bool("v", false, "verbose output from generator")
-	typeNames := flag.String("type", "", "comma-separated list of type names; must be set")
+	alphabetical := flag.Bool("alphabetical", false, "order states and events in generated code by name instead of declaration order")
+	prefix := flag.String("prefix", "", "prefix of generated state and event constants, like {{.MachineName}}")
+	lock := flag.Bool("lock", false, "record numeric values of states and events in lock file next to generated one")
+	strict := flag.Bool("strict", false, "fail generation when analysis finds unreachable states or states that can't reach terminal state")
+	typeNames := flag.String("type", "", "comma-separated list of type names; if not set, types marked with //fsm:machine comment are used")
+	jsonOutput := flag.Bool("json", false, "print problems found by check command as JSON")
 	var dirName string
 	flag.StringVar(&dirName, "dir", ".", "working directory; must be set")
 
-	flag.Parse()
-	if len(*typeNames) == 0 {
-		log.Fatalf("the flag -type must be set")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [%s|%s|%s] [flags]\n", os.Args[0], checkCommand, verifyCommand, diffCommand)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s %s [flags] <old> <new>\n", os.Args[0], diffCommand)
+		fmt.Fprintf(flag.CommandLine.Output(), "where <old> and <new> are directories, files or <revision>:<path> of git repository\n")
+		flag.PrintDefaults()
 	}
+	flag.CommandLine.Parse(args)
 	if len(dirName) == 0 {
 		log.Fatalf("the flag -dir must be set")
 	}
-	types := strings.Split(*typeNames, ",")
-	generator.RunGeneratorForTypes(dirName, types, *verbose)
+	var types []string
+	if len(*typeNames) > 0 {
+		types = strings.Split(*typeNames, ",")
+	}
+	options := generator.Options{Verbose: *verbose, Alphabetical: *alphabetical, Prefix: *prefix, Lock: *lock, Strict: *strict}
+	if command == checkCommand {
+		os.Exit(check(dirName, types, options, *jsonOutput))
+	}
+	if command == diffCommand {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		breaking, err := generator.DiffDeclarations(flag.Arg(0), flag.Arg(1), types, options, os.Stdout)
+		if err != nil {
+			log.Print(err)
+			os.Exit(2)
+		}
+		if breaking {
+			os.Exit(1)
+		}
+		return
+	}
+	if command == verifyCommand {
+		if !generator.VerifyGenerated(dirName, types, options, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+	generator.RunGenerator(dirName, types, options)
+}
+
+// check prints problems of declarations and returns exit code, which is non-zero if some of them are errors
+func check(dirName string, types []string, options generator.Options, jsonOutput bool) int {
+	diagnostics := generator.CheckDeclarations(dirName, types, options)
+	if jsonOutput {
+		if diagnostics == nil {
+			diagnostics = []generator.Diagnostic{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diagnostics); err != nil {
+			log.Fatal("can't encode diagnostics ", err)
+		}
+	} else {
+		for _, d := range diagnostics {
+			fmt.Println(d)
+		}
+	}
+	for _, d := range diagnostics {
+		if d.Severity == generator.SeverityError {
+			return 1
+		}
+	}
+	return 0
 }